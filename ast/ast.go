@@ -19,6 +19,7 @@ type Expression interface {
 
 type Program struct {
 	Statements []Statement
+	Comments   []*Comment // in source order
 }
 
 func (p *Program) TokenLiteral() string {
@@ -34,3 +34,19 @@ func TestProgram_String(t *testing.T) {
 	}
 	assert.Equal(t, exp, program.String())
 }
+
+func TestStartToken(t *testing.T) {
+	a := &Identifier{Token: token.Token{Type: token.IDENT, Literal: "a", Line: 2, Column: 5}, Value: "a"}
+	call := &CallExpression{
+		Token:    token.Token{Type: token.LPAREN, Literal: "(", Line: 2, Column: 8},
+		Function: &IndexExpression{Token: token.Token{Type: token.LBRACKET, Literal: "[", Line: 2, Column: 6}, Left: a},
+	}
+	infix := &InfixExpression{Token: token.Token{Type: token.PLUS, Literal: "+", Line: 2, Column: 11}, LeftValue: call}
+	stmt := &ExpressionStatement{Token: a.Token, Expression: infix}
+
+	assert.Equal(t, a.Token, StartToken(infix))
+	assert.Equal(t, a.Token, StartToken(stmt))
+	imp := &ImportStatement{Token: token.Token{Type: token.IMPORT, Literal: "import", Line: 1, Column: 1}}
+	assert.Equal(t, 1, StartToken(imp).Line)
+	assert.Equal(t, token.Token{}, StartToken(&Program{}))
+}
@@ -8,6 +8,7 @@ import (
 type BlockStatement struct {
 	Token      token.Token // the '{' token
 	Statements []Statement
+	EndToken   token.Token // the '}' token
 }
 
 func (bs *BlockStatement) TokenLiteral() string {
@@ -24,7 +24,7 @@ func (ce *CallExpression) String() string {
 	for _, arg := range ce.Arguments {
 		args = append(args, arg.String())
 	}
-	out.WriteString(ce.Function.TokenLiteral())
+	out.WriteString(ce.Function.String())
 	out.WriteByte('(')
 	out.WriteString(strings.Join(args, ", "))
 	out.WriteByte(')')
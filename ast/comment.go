@@ -0,0 +1,17 @@
+package ast
+
+import "monkey_interpreter/token"
+
+// Comment is a line comment. Comments are not part of the statement tree - the parser collects
+// them on the Program so tools such as the formatter can put them back
+type Comment struct {
+	Token token.Token // token.COMMENT, the literal includes the leading //
+}
+
+func (c *Comment) TokenLiteral() string {
+	return c.Token.Literal
+}
+
+func (c *Comment) String() string {
+	return c.Token.Literal
+}
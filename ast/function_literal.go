@@ -9,7 +9,12 @@ import (
 type FunctionLiteral struct {
 	Token      token.Token // The FN token
 	Parameters []*Identifier
+	ReturnType TypeExpression // nil unless annotated with ->
 	Body       *BlockStatement
+
+	// Locals names the slots of the frame a call allocates - the parameters first, then the let
+	// bindings of the body. It is nil until the resolver runs
+	Locals []string
 }
 
 func (fl *FunctionLiteral) TokenLiteral() string {
@@ -25,10 +30,17 @@ func (fl *FunctionLiteral) String() string {
 
 	var params []string
 	for _, param := range fl.Parameters {
-		params = append(params, param.Value)
+		if param.Annotation != nil {
+			params = append(params, param.Value+": "+param.Annotation.String())
+		} else {
+			params = append(params, param.Value)
+		}
 	}
 	out.WriteString(strings.Join(params, ","))
 	out.WriteByte(')')
+	if fl.ReturnType != nil {
+		out.WriteString(" -> " + fl.ReturnType.String() + " ")
+	}
 	out.WriteByte('{')
 	out.WriteString(fl.Body.String())
 	out.WriteByte('}')
@@ -9,7 +9,12 @@ import (
 
 type HashLiteral struct {
 	Token token.Token
-	Pairs map[Expression]Expression
+	Pairs []HashLiteralPair // in source order
+}
+
+type HashLiteralPair struct {
+	Key   Expression
+	Value Expression
 }
 
 func (hl *HashLiteral) TokenLiteral() string {
@@ -23,8 +28,8 @@ func (hl *HashLiteral) String() string {
 	out.WriteByte('{')
 
 	var pairs []string
-	for key, val := range hl.Pairs {
-		pairs = append(pairs, fmt.Sprintf("%s : %s", key.String(), val.String()))
+	for _, pair := range hl.Pairs {
+		pairs = append(pairs, fmt.Sprintf("%s : %s", pair.Key.String(), pair.Value.String()))
 	}
 
 	out.WriteString(strings.Join(pairs, ", "))
@@ -5,6 +5,12 @@ import "monkey_interpreter/token"
 type Identifier struct {
 	Token token.Token
 	Value string
+
+	// Annotation is the declared type of a let binding or a parameter, nil if there is none
+	Annotation TypeExpression
+
+	// Resolution is filled in by the resolver, the zero value means the name is looked up at runtime
+	Resolution Resolution
 }
 
 func (i *Identifier) expressionNode() {}
@@ -16,3 +22,18 @@ func (i *Identifier) TokenLiteral() string {
 func (i *Identifier) String() string {
 	return i.Value
 }
+
+type ResolutionKind int
+
+const (
+	Unresolved ResolutionKind = iota
+	Local                     // Slot of the function frame Depth frames up
+	Global                    // a name in the top level environment Depth frames up, or a builtin
+)
+
+// Resolution tells the evaluator where the variable an identifier refers to is stored
+type Resolution struct {
+	Kind  ResolutionKind
+	Depth int // number of function frames between the reference and the declaring scope
+	Slot  int
+}
@@ -0,0 +1,27 @@
+package ast
+
+import (
+	"bytes"
+	"monkey_interpreter/token"
+)
+
+type ImportStatement struct {
+	Token token.Token // Token.IMPORT
+	Path  *StringLiteral
+	Name  *Identifier
+}
+
+func (is *ImportStatement) statementNode() {}
+func (is *ImportStatement) TokenLiteral() string {
+	return is.Token.Literal
+}
+
+func (is *ImportStatement) String() string {
+	var out bytes.Buffer
+	out.WriteString(is.Token.Literal + " ")
+	out.WriteString(`"` + is.Path.Value + `"`)
+	out.WriteString(" as ")
+	out.WriteString(is.Name.Value)
+	out.WriteByte(';')
+	return out.String()
+}
@@ -20,6 +20,9 @@ func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 	out.WriteString(ls.Token.Literal + " ")
 	out.WriteString(ls.Name.TokenLiteral())
+	if ls.Name.Annotation != nil {
+		out.WriteString(": " + ls.Name.Annotation.String())
+	}
 	out.WriteString(" = ")
 	if ls.Value != nil {
 		out.WriteString(ls.Value.String())
@@ -0,0 +1,36 @@
+package ast
+
+import (
+	"bytes"
+	"monkey_interpreter/token"
+	"strings"
+)
+
+type MacroLiteral struct {
+	Token      token.Token // The MACRO token
+	Parameters []*Identifier
+	Body       *BlockStatement
+}
+
+func (ml *MacroLiteral) TokenLiteral() string {
+	return ml.Token.Literal
+}
+
+func (ml *MacroLiteral) expressionNode() {}
+
+func (ml *MacroLiteral) String() string {
+	var out bytes.Buffer
+	out.WriteString(ml.TokenLiteral())
+	out.WriteByte('(')
+
+	var params []string
+	for _, param := range ml.Parameters {
+		params = append(params, param.Value)
+	}
+	out.WriteString(strings.Join(params, ","))
+	out.WriteByte(')')
+	out.WriteByte('{')
+	out.WriteString(ml.Body.String())
+	out.WriteByte('}')
+	return out.String()
+}
@@ -0,0 +1,29 @@
+package ast
+
+import (
+	"bytes"
+	"monkey_interpreter/token"
+)
+
+// MemberExpression accesses a top level binding of a module, e.g. lib.add
+type MemberExpression struct {
+	Token  token.Token // The '.' token
+	Object Expression
+	Member *Identifier
+}
+
+func (me *MemberExpression) TokenLiteral() string {
+	return me.Token.Literal
+}
+
+func (me *MemberExpression) expressionNode() {}
+
+func (me *MemberExpression) String() string {
+	var out bytes.Buffer
+	out.WriteByte('(')
+	out.WriteString(me.Object.String())
+	out.WriteByte('.')
+	out.WriteString(me.Member.Value)
+	out.WriteByte(')')
+	return out.String()
+}
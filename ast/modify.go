@@ -0,0 +1,270 @@
+package ast
+
+import "fmt"
+
+// ModifierFunc is called by Modify with a node whose children were already modified
+type ModifierFunc func(Node) Node
+
+// Modify rewrites the tree bottom-up, replacing every node with the result of modifier.
+// The input tree is left untouched - a parent is copied when one of its children changes,
+// otherwise the original node is passed to modifier. Returning nil for a statement removes it
+// from its program or block. Type annotations are not modified
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch n := node.(type) {
+	case *Program:
+		if stmts, changed := modifyStatements(n.Statements, modifier); changed {
+			cp := *n
+			cp.Statements = stmts
+			node = &cp
+		}
+	case *LetStatement:
+		name := modifyIdentifier(n.Name, modifier)
+		value := modifyExpression(n.Value, modifier)
+		if name != n.Name || value != n.Value {
+			cp := *n
+			cp.Name, cp.Value = name, value
+			node = &cp
+		}
+	case *ReturnStatement:
+		if value := modifyExpression(n.ReturnValue, modifier); value != n.ReturnValue {
+			cp := *n
+			cp.ReturnValue = value
+			node = &cp
+		}
+	case *ImportStatement:
+		path, ok := Modify(n.Path, modifier).(*StringLiteral)
+		if !ok {
+			panic(fmt.Sprintf("ast.Modify: import path %s must be replaced with a *StringLiteral", n.Path.Value))
+		}
+		name := modifyIdentifier(n.Name, modifier)
+		if path != n.Path || name != n.Name {
+			cp := *n
+			cp.Path, cp.Name = path, name
+			node = &cp
+		}
+	case *ExpressionStatement:
+		if exp := modifyExpression(n.Expression, modifier); exp != n.Expression {
+			cp := *n
+			cp.Expression = exp
+			node = &cp
+		}
+	case *BlockStatement:
+		if stmts, changed := modifyStatements(n.Statements, modifier); changed {
+			cp := *n
+			cp.Statements = stmts
+			node = &cp
+		}
+	case *PrefixExpression:
+		if right := modifyExpression(n.Right, modifier); right != n.Right {
+			cp := *n
+			cp.Right = right
+			node = &cp
+		}
+	case *InfixExpression:
+		left := modifyExpression(n.LeftValue, modifier)
+		right := modifyExpression(n.RightValue, modifier)
+		if left != n.LeftValue || right != n.RightValue {
+			cp := *n
+			cp.LeftValue, cp.RightValue = left, right
+			node = &cp
+		}
+	case *IfExpression:
+		condition := modifyExpression(n.Condition, modifier)
+		consequence := modifyBlock(n.Consequence, modifier)
+		alternative := modifyBlock(n.Alternative, modifier)
+		if condition != n.Condition || consequence != n.Consequence || alternative != n.Alternative {
+			cp := *n
+			cp.Condition, cp.Consequence, cp.Alternative = condition, consequence, alternative
+			node = &cp
+		}
+	case *FunctionLiteral:
+		params, changed := modifyParameters(n.Parameters, modifier)
+		body := modifyBlock(n.Body, modifier)
+		if changed || body != n.Body {
+			cp := *n
+			cp.Parameters, cp.Body = params, body
+			node = &cp
+		}
+	case *MacroLiteral:
+		params, changed := modifyParameters(n.Parameters, modifier)
+		body := modifyBlock(n.Body, modifier)
+		if changed || body != n.Body {
+			cp := *n
+			cp.Parameters, cp.Body = params, body
+			node = &cp
+		}
+	case *CallExpression:
+		function := modifyExpression(n.Function, modifier)
+		args, changed := modifyExpressions(n.Arguments, modifier)
+		if changed || function != n.Function {
+			cp := *n
+			cp.Function, cp.Arguments = function, args
+			node = &cp
+		}
+	case *ArrayLiteral:
+		if elems, changed := modifyExpressions(n.Elements, modifier); changed {
+			cp := *n
+			cp.Elements = elems
+			node = &cp
+		}
+	case *IndexExpression:
+		left := modifyExpression(n.Left, modifier)
+		index := modifyExpression(n.Index, modifier)
+		if left != n.Left || index != n.Index {
+			cp := *n
+			cp.Left, cp.Index = left, index
+			node = &cp
+		}
+	case *MemberExpression:
+		object := modifyExpression(n.Object, modifier)
+		member := modifyIdentifier(n.Member, modifier)
+		if object != n.Object || member != n.Member {
+			cp := *n
+			cp.Object, cp.Member = object, member
+			node = &cp
+		}
+	case *SliceExpression:
+		left := modifyExpression(n.Left, modifier)
+		start := modifyExpression(n.Start, modifier)
+		end := modifyExpression(n.End, modifier)
+		step := modifyExpression(n.Step, modifier)
+		if left != n.Left || start != n.Start || end != n.End || step != n.Step {
+			cp := *n
+			cp.Left, cp.Start, cp.End, cp.Step = left, start, end, step
+			node = &cp
+		}
+	case *HashLiteral:
+		changed := false
+		pairs := make([]HashLiteralPair, len(n.Pairs))
+		for i, pair := range n.Pairs {
+			pairs[i] = HashLiteralPair{
+				Key:   modifyExpression(pair.Key, modifier),
+				Value: modifyExpression(pair.Value, modifier),
+			}
+			changed = changed || pairs[i] != pair
+		}
+		if changed {
+			cp := *n
+			cp.Pairs = pairs
+			node = &cp
+		}
+	}
+
+	return modifier(node)
+}
+
+func modifyExpression(exp Expression, modifier ModifierFunc) Expression {
+	if exp == nil {
+		return nil
+	}
+	modified := Modify(exp, modifier)
+	if modified == nil {
+		return nil
+	}
+	res, ok := modified.(Expression)
+	if !ok {
+		panic(fmt.Sprintf("ast.Modify: %T replaced with %T which is not an Expression", exp, modified))
+	}
+	return res
+}
+
+func modifyExpressions(exps []Expression, modifier ModifierFunc) ([]Expression, bool) {
+	if exps == nil {
+		return nil, false
+	}
+	changed := false
+	modified := make([]Expression, len(exps))
+	for i, exp := range exps {
+		modified[i] = modifyExpression(exp, modifier)
+		changed = changed || modified[i] != exp
+	}
+	return modified, changed
+}
+
+func modifyStatements(stmts []Statement, modifier ModifierFunc) ([]Statement, bool) {
+	changed := false
+	modified := make([]Statement, 0, len(stmts))
+	for _, stmt := range stmts {
+		res := Modify(stmt, modifier)
+		if res == nil {
+			changed = true
+			continue
+		}
+		newStmt, ok := res.(Statement)
+		if !ok {
+			panic(fmt.Sprintf("ast.Modify: %T replaced with %T which is not a Statement", stmt, res))
+		}
+		changed = changed || newStmt != stmt
+		modified = append(modified, newStmt)
+	}
+	return modified, changed
+}
+
+func modifyIdentifier(ident *Identifier, modifier ModifierFunc) *Identifier {
+	if ident == nil {
+		return nil
+	}
+	res, ok := Modify(ident, modifier).(*Identifier)
+	if !ok {
+		panic(fmt.Sprintf("ast.Modify: identifier %s must be replaced with an *Identifier", ident.Value))
+	}
+	return res
+}
+
+func modifyBlock(block *BlockStatement, modifier ModifierFunc) *BlockStatement {
+	if block == nil {
+		return nil
+	}
+	res, ok := Modify(block, modifier).(*BlockStatement)
+	if !ok {
+		panic("ast.Modify: a block statement must be replaced with a *BlockStatement")
+	}
+	return res
+}
+
+// modifyParameters returns the original slice unless one of the parameters was replaced
+func modifyParameters(params []*Identifier, modifier ModifierFunc) ([]*Identifier, bool) {
+	changed := false
+	modified := make([]*Identifier, len(params))
+	for i, param := range params {
+		modified[i] = modifyIdentifier(param, modifier)
+		changed = changed || modified[i] != param
+	}
+	if !changed {
+		return params, false
+	}
+	return modified, true
+}
+
+// Copy returns a deep copy of the tree. Passes annotating nodes in place, like the resolver, need
+// their own copy of a subtree that also appears elsewhere, such as the nodes a macro returns
+func Copy(node Node) Node {
+	return Modify(node, func(node Node) Node {
+		// Modify copies the parents of the nodes copied here
+		switch n := node.(type) {
+		case *Identifier:
+			cp := *n
+			return &cp
+		case *IntegerLiteral:
+			cp := *n
+			return &cp
+		case *StringLiteral:
+			cp := *n
+			return &cp
+		case *Boolean:
+			cp := *n
+			return &cp
+		case *BlockStatement:
+			cp := *n
+			return &cp
+		case *ArrayLiteral:
+			cp := *n
+			return &cp
+		case *HashLiteral:
+			cp := *n
+			return &cp
+		default:
+			return node
+		}
+	})
+}
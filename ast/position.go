@@ -0,0 +1,62 @@
+package ast
+
+import "monkey_interpreter/token"
+
+// StartToken returns the first token of the source of a node, where tools report its position. It
+// is the zero token for a Program
+func StartToken(node Node) token.Token {
+	switch node := node.(type) {
+	case *InfixExpression:
+		return StartToken(node.LeftValue)
+	case *CallExpression:
+		return StartToken(node.Function)
+	case *IndexExpression:
+		return StartToken(node.Left)
+	case *SliceExpression:
+		return StartToken(node.Left)
+	case *MemberExpression:
+		return StartToken(node.Object)
+	case *LetStatement:
+		return node.Token
+	case *ReturnStatement:
+		return node.Token
+	case *ImportStatement:
+		return node.Token
+	case *ExpressionStatement:
+		return node.Token
+	case *BlockStatement:
+		return node.Token
+	case *Comment:
+		return node.Token
+	case *Identifier:
+		return node.Token
+	case *IntegerLiteral:
+		return node.Token
+	case *StringLiteral:
+		return node.Token
+	case *Boolean:
+		return node.Token
+	case *PrefixExpression:
+		return node.Token
+	case *IfExpression:
+		return node.Token
+	case *FunctionLiteral:
+		return node.Token
+	case *MacroLiteral:
+		return node.Token
+	case *ArrayLiteral:
+		return node.Token
+	case *HashLiteral:
+		return node.Token
+	case *NamedType:
+		return node.Token
+	case *ArrayType:
+		return node.Token
+	case *HashType:
+		return node.Token
+	case *FunctionType:
+		return node.Token
+	default:
+		return token.Token{}
+	}
+}
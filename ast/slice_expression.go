@@ -0,0 +1,41 @@
+package ast
+
+import (
+	"bytes"
+	"monkey_interpreter/token"
+)
+
+type SliceExpression struct {
+	Token token.Token // The '[' token
+	Left  Expression
+	Start Expression // optional, nil when omitted
+	End   Expression // optional, nil when omitted
+	Step  Expression // optional, nil when omitted
+}
+
+func (se *SliceExpression) TokenLiteral() string {
+	return se.Token.Literal
+}
+
+func (se *SliceExpression) expressionNode() {}
+
+func (se *SliceExpression) String() string {
+	var out bytes.Buffer
+	out.WriteByte('(')
+	out.WriteString(se.Left.String())
+	out.WriteByte('[')
+	if se.Start != nil {
+		out.WriteString(se.Start.String())
+	}
+	out.WriteByte(':')
+	if se.End != nil {
+		out.WriteString(se.End.String())
+	}
+	if se.Step != nil {
+		out.WriteByte(':')
+		out.WriteString(se.Step.String())
+	}
+	out.WriteByte(']')
+	out.WriteByte(')')
+	return out.String()
+}
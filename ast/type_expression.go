@@ -0,0 +1,82 @@
+package ast
+
+import (
+	"monkey_interpreter/token"
+	"strings"
+)
+
+// TypeExpression is a type annotation, checked by the type checker and ignored at runtime
+type TypeExpression interface {
+	Node
+	typeNode()
+}
+
+// NamedType is a basic type such as int, string, bool or any
+type NamedType struct {
+	Token token.Token
+	Name  string
+}
+
+func (nt *NamedType) typeNode() {}
+
+func (nt *NamedType) TokenLiteral() string {
+	return nt.Token.Literal
+}
+
+func (nt *NamedType) String() string {
+	return nt.Name
+}
+
+// ArrayType is written [int]
+type ArrayType struct {
+	Token   token.Token // The '[' token
+	Element TypeExpression
+}
+
+func (at *ArrayType) typeNode() {}
+
+func (at *ArrayType) TokenLiteral() string {
+	return at.Token.Literal
+}
+
+func (at *ArrayType) String() string {
+	return "[" + at.Element.String() + "]"
+}
+
+// HashType is written {string: int}
+type HashType struct {
+	Token token.Token // The '{' token
+	Key   TypeExpression
+	Value TypeExpression
+}
+
+func (ht *HashType) typeNode() {}
+
+func (ht *HashType) TokenLiteral() string {
+	return ht.Token.Literal
+}
+
+func (ht *HashType) String() string {
+	return "{" + ht.Key.String() + ": " + ht.Value.String() + "}"
+}
+
+// FunctionType is written fn(int, string) -> bool
+type FunctionType struct {
+	Token      token.Token // The FN token
+	Parameters []TypeExpression
+	Return     TypeExpression
+}
+
+func (ft *FunctionType) typeNode() {}
+
+func (ft *FunctionType) TokenLiteral() string {
+	return ft.Token.Literal
+}
+
+func (ft *FunctionType) String() string {
+	params := make([]string, 0, len(ft.Parameters))
+	for _, param := range ft.Parameters {
+		params = append(params, param.String())
+	}
+	return "fn(" + strings.Join(params, ", ") + ") -> " + ft.Return.String()
+}
@@ -0,0 +1,222 @@
+package ast
+
+import (
+	"github.com/stretchr/testify/assert"
+	"monkey_interpreter/token"
+	"testing"
+)
+
+func ident(name string) *Identifier {
+	return &Identifier{Token: token.Token{Type: token.IDENT, Literal: name}, Value: name}
+}
+
+func integer(val int64) *IntegerLiteral {
+	return &IntegerLiteral{Token: token.Token{Type: token.INT, Literal: "x"}, Value: val}
+}
+
+func testProgram() *Program {
+	// let f = fn(a) { if (a > 1) { [a, 2][0] } else { {"k": a}["k"] } }; f(1);
+	return &Program{
+		Statements: []Statement{
+			&LetStatement{
+				Name: ident("f"),
+				Value: &FunctionLiteral{
+					Parameters: []*Identifier{ident("a")},
+					Body: &BlockStatement{Statements: []Statement{
+						&ExpressionStatement{Expression: &IfExpression{
+							Condition: &InfixExpression{LeftValue: ident("a"), Operator: ">", RightValue: integer(1)},
+							Consequence: &BlockStatement{Statements: []Statement{
+								&ExpressionStatement{Expression: &IndexExpression{
+									Left:  &ArrayLiteral{Elements: []Expression{ident("a"), integer(2)}},
+									Index: integer(0),
+								}},
+							}},
+							Alternative: &BlockStatement{Statements: []Statement{
+								&ExpressionStatement{Expression: &IndexExpression{
+									Left:  &HashLiteral{Pairs: []HashLiteralPair{{Key: &StringLiteral{Value: "k"}, Value: ident("a")}}},
+									Index: &StringLiteral{Value: "k"},
+								}},
+							}},
+						}},
+					}},
+				},
+			},
+			&ExpressionStatement{Expression: &CallExpression{Function: ident("f"), Arguments: []Expression{integer(1)}}},
+		},
+	}
+}
+
+func TestInspect(t *testing.T) {
+	var idents []string
+	var ints []int64
+	Inspect(testProgram(), func(node Node) bool {
+		switch node := node.(type) {
+		case *Identifier:
+			idents = append(idents, node.Value)
+		case *IntegerLiteral:
+			ints = append(ints, node.Value)
+		}
+		return true
+	})
+	assert.Equal(t, []string{"f", "a", "a", "a", "a", "f"}, idents)
+	assert.Equal(t, []int64{1, 2, 0, 1}, ints)
+}
+
+func TestInspectSkipsChildren(t *testing.T) {
+	count := 0
+	Inspect(testProgram(), func(node Node) bool {
+		if node != nil {
+			count++
+		}
+		_, isFn := node.(*FunctionLiteral)
+		return !isFn
+	})
+	// Program, LetStatement, f, FunctionLiteral, ExpressionStatement, CallExpression, f, 1
+	assert.Equal(t, 8, count)
+}
+
+type depthVisitor struct {
+	depth    int
+	maxDepth *int
+}
+
+func (v depthVisitor) Visit(node Node) Visitor {
+	if node == nil {
+		return nil
+	}
+	if v.depth > *v.maxDepth {
+		*v.maxDepth = v.depth
+	}
+	return depthVisitor{depth: v.depth + 1, maxDepth: v.maxDepth}
+}
+
+func TestWalk(t *testing.T) {
+	maxDepth := 0
+	Walk(depthVisitor{maxDepth: &maxDepth}, testProgram())
+	// Program > Let > Fn > Block > ExpStmt > If > Block > ExpStmt > Index > Hash/Array > elements
+	assert.Equal(t, 10, maxDepth)
+}
+
+func TestModify(t *testing.T) {
+	one := func() Expression { return integer(1) }
+	two := func() Expression { return integer(2) }
+	turnOneIntoTwo := func(node Node) Node {
+		integer, ok := node.(*IntegerLiteral)
+		if !ok || integer.Value != 1 {
+			return node
+		}
+		return two()
+	}
+
+	tests := []struct {
+		input    Node
+		expected Node
+	}{
+		{one(), two()},
+		{
+			&Program{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			&Program{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+		},
+		{
+			&InfixExpression{LeftValue: one(), Operator: "+", RightValue: two()},
+			&InfixExpression{LeftValue: two(), Operator: "+", RightValue: two()},
+		},
+		{
+			&PrefixExpression{Operator: "-", Right: one()},
+			&PrefixExpression{Operator: "-", Right: two()},
+		},
+		{
+			&IndexExpression{Left: one(), Index: one()},
+			&IndexExpression{Left: two(), Index: two()},
+		},
+		{
+			&SliceExpression{Left: one(), Start: one(), Step: one()},
+			&SliceExpression{Left: two(), Start: two(), Step: two()},
+		},
+		{
+			&IfExpression{
+				Condition:   one(),
+				Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+				Alternative: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}},
+			},
+			&IfExpression{
+				Condition:   two(),
+				Consequence: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+				Alternative: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}},
+			},
+		},
+		{&ReturnStatement{ReturnValue: one()}, &ReturnStatement{ReturnValue: two()}},
+		{&LetStatement{Name: ident("x"), Value: one()}, &LetStatement{Name: ident("x"), Value: two()}},
+		{
+			&FunctionLiteral{Body: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: one()}}}},
+			&FunctionLiteral{Body: &BlockStatement{Statements: []Statement{&ExpressionStatement{Expression: two()}}}},
+		},
+		{
+			&CallExpression{Function: ident("f"), Arguments: []Expression{one(), two()}},
+			&CallExpression{Function: ident("f"), Arguments: []Expression{two(), two()}},
+		},
+		{&ArrayLiteral{Elements: []Expression{one(), one()}}, &ArrayLiteral{Elements: []Expression{two(), two()}}},
+		{
+			&HashLiteral{Pairs: []HashLiteralPair{{Key: one(), Value: one()}}},
+			&HashLiteral{Pairs: []HashLiteralPair{{Key: two(), Value: two()}}},
+		},
+	}
+	for _, test := range tests {
+		modified := Modify(test.input, turnOneIntoTwo)
+		assert.Equal(t, test.expected, modified)
+	}
+}
+
+func TestModifyCopiesChangedParents(t *testing.T) {
+	program := testProgram()
+	before := program.String()
+
+	modified := Modify(program, func(node Node) Node {
+		if id, ok := node.(*Identifier); ok && id.Value == "a" {
+			return ident("b")
+		}
+		return node
+	})
+
+	assert.Equal(t, before, program.String(), "the input tree must not change")
+	assert.NotEqual(t, before, modified.String())
+	assert.NotContains(t, modified.String(), "a")
+
+	// The untouched call statement is shared between both trees
+	assert.Same(t, program.Statements[1], modified.(*Program).Statements[1])
+}
+
+func TestCopy(t *testing.T) {
+	program := testProgram()
+	copied := Copy(program)
+	assert.Equal(t, program.String(), copied.String())
+
+	// No node of the original tree is reachable from the copy
+	original := make(map[Node]bool)
+	Inspect(program, func(node Node) bool {
+		original[node] = true
+		return true
+	})
+	delete(original, nil)
+	Inspect(copied, func(node Node) bool {
+		assert.False(t, original[node], "%T %v is shared", node, node)
+		return true
+	})
+}
+
+func TestModifyRemovesStatements(t *testing.T) {
+	program := &Program{Statements: []Statement{
+		&ExpressionStatement{Expression: integer(1)},
+		&ReturnStatement{ReturnValue: integer(2)},
+		&ExpressionStatement{Expression: integer(3)},
+	}}
+	modified := Modify(program, func(node Node) Node {
+		if _, ok := node.(*ReturnStatement); ok {
+			return nil
+		}
+		return node
+	}).(*Program)
+
+	assert.Equal(t, 2, len(modified.Statements))
+	assert.Equal(t, 3, len(program.Statements))
+}
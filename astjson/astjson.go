@@ -0,0 +1,248 @@
+// Package astjson converts an ast.Program to and from a stable JSON document so tools written in
+// other languages can consume the parse tree.
+//
+// The document is {"version": Version, "program": <node>}. Every node is an object with a "kind"
+// holding the name of its ast type (e.g. "InfixExpression") and, except for Program, a "token"
+// object {"type", "literal", "line", "column"}. The remaining keys hold the children of the node
+// and are listed in the encode functions below. Optional children are null when absent and lists
+// are always present, possibly empty. Fields are only ever added within a version.
+package astjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"monkey_interpreter/ast"
+	"monkey_interpreter/token"
+)
+
+// Version of the JSON schema, bumped on any incompatible change
+const Version = 1
+
+type document struct {
+	Version int             `json:"version"`
+	Program json.RawMessage `json:"program"`
+}
+
+type jsonToken struct {
+	Type    token.Type `json:"type"`
+	Literal string     `json:"literal"`
+	Line    int        `json:"line"`
+	Column  int        `json:"column"`
+}
+
+type jsonObject map[string]interface{}
+
+// Marshal encodes the program as a versioned JSON document
+func Marshal(program *ast.Program) ([]byte, error) {
+	encoded, err := json.Marshal(encodeNode(program))
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(document{Version: Version, Program: encoded})
+}
+
+func encodeNode(node ast.Node) interface{} {
+	switch node := node.(type) {
+	case *ast.Program:
+		comments := make([]interface{}, 0, len(node.Comments))
+		for _, comment := range node.Comments {
+			comments = append(comments, encodeNode(comment))
+		}
+		return jsonObject{
+			"kind":       "Program",
+			"statements": encodeStatements(node.Statements),
+			"comments":   comments,
+		}
+	case *ast.Comment:
+		return newObject("Comment", node.Token)
+	case *ast.LetStatement:
+		obj := newObject("LetStatement", node.Token)
+		obj["name"] = encodeNode(node.Name)
+		obj["value"] = encodeExpression(node.Value)
+		return obj
+	case *ast.ReturnStatement:
+		obj := newObject("ReturnStatement", node.Token)
+		obj["value"] = encodeExpression(node.ReturnValue)
+		return obj
+	case *ast.ImportStatement:
+		obj := newObject("ImportStatement", node.Token)
+		obj["path"] = encodeNode(node.Path)
+		obj["name"] = encodeNode(node.Name)
+		return obj
+	case *ast.ExpressionStatement:
+		obj := newObject("ExpressionStatement", node.Token)
+		obj["expression"] = encodeExpression(node.Expression)
+		return obj
+	case *ast.BlockStatement:
+		if node == nil {
+			return nil
+		}
+		obj := newObject("BlockStatement", node.Token)
+		obj["statements"] = encodeStatements(node.Statements)
+		obj["endToken"] = encodeToken(node.EndToken)
+		return obj
+	case *ast.Identifier:
+		if node == nil {
+			return nil
+		}
+		obj := newObject("Identifier", node.Token)
+		obj["value"] = node.Value
+		obj["annotation"] = encodeType(node.Annotation)
+		return obj
+	case *ast.IntegerLiteral:
+		obj := newObject("IntegerLiteral", node.Token)
+		obj["value"] = node.Value
+		return obj
+	case *ast.StringLiteral:
+		obj := newObject("StringLiteral", node.Token)
+		obj["value"] = node.Value
+		return obj
+	case *ast.Boolean:
+		obj := newObject("Boolean", node.Token)
+		obj["value"] = node.Value
+		return obj
+	case *ast.PrefixExpression:
+		obj := newObject("PrefixExpression", node.Token)
+		obj["operator"] = node.Operator
+		obj["right"] = encodeExpression(node.Right)
+		return obj
+	case *ast.InfixExpression:
+		obj := newObject("InfixExpression", node.Token)
+		obj["left"] = encodeExpression(node.LeftValue)
+		obj["operator"] = node.Operator
+		obj["right"] = encodeExpression(node.RightValue)
+		return obj
+	case *ast.IfExpression:
+		obj := newObject("IfExpression", node.Token)
+		obj["condition"] = encodeExpression(node.Condition)
+		obj["consequence"] = encodeNode(node.Consequence)
+		obj["alternative"] = encodeNode(node.Alternative)
+		return obj
+	case *ast.FunctionLiteral:
+		params := make([]interface{}, 0, len(node.Parameters))
+		for _, param := range node.Parameters {
+			params = append(params, encodeNode(param))
+		}
+		obj := newObject("FunctionLiteral", node.Token)
+		obj["parameters"] = params
+		obj["returnType"] = encodeType(node.ReturnType)
+		obj["body"] = encodeNode(node.Body)
+		return obj
+	case *ast.MacroLiteral:
+		params := make([]interface{}, 0, len(node.Parameters))
+		for _, param := range node.Parameters {
+			params = append(params, encodeNode(param))
+		}
+		obj := newObject("MacroLiteral", node.Token)
+		obj["parameters"] = params
+		obj["body"] = encodeNode(node.Body)
+		return obj
+	case *ast.CallExpression:
+		obj := newObject("CallExpression", node.Token)
+		obj["function"] = encodeExpression(node.Function)
+		obj["arguments"] = encodeExpressions(node.Arguments)
+		return obj
+	case *ast.ArrayLiteral:
+		obj := newObject("ArrayLiteral", node.Token)
+		obj["elements"] = encodeExpressions(node.Elements)
+		return obj
+	case *ast.IndexExpression:
+		obj := newObject("IndexExpression", node.Token)
+		obj["left"] = encodeExpression(node.Left)
+		obj["index"] = encodeExpression(node.Index)
+		return obj
+	case *ast.MemberExpression:
+		obj := newObject("MemberExpression", node.Token)
+		obj["object"] = encodeExpression(node.Object)
+		obj["member"] = encodeNode(node.Member)
+		return obj
+	case *ast.SliceExpression:
+		obj := newObject("SliceExpression", node.Token)
+		obj["left"] = encodeExpression(node.Left)
+		obj["start"] = encodeExpression(node.Start)
+		obj["end"] = encodeExpression(node.End)
+		obj["step"] = encodeExpression(node.Step)
+		return obj
+	case *ast.HashLiteral:
+		pairs := make([]interface{}, 0, len(node.Pairs))
+		for _, pair := range node.Pairs {
+			pairs = append(pairs, jsonObject{
+				"key":   encodeExpression(pair.Key),
+				"value": encodeExpression(pair.Value),
+			})
+		}
+		obj := newObject("HashLiteral", node.Token)
+		obj["pairs"] = pairs
+		return obj
+	case *ast.NamedType:
+		obj := newObject("NamedType", node.Token)
+		obj["name"] = node.Name
+		return obj
+	case *ast.ArrayType:
+		obj := newObject("ArrayType", node.Token)
+		obj["element"] = encodeType(node.Element)
+		return obj
+	case *ast.HashType:
+		obj := newObject("HashType", node.Token)
+		obj["key"] = encodeType(node.Key)
+		obj["value"] = encodeType(node.Value)
+		return obj
+	case *ast.FunctionType:
+		params := make([]interface{}, 0, len(node.Parameters))
+		for _, param := range node.Parameters {
+			params = append(params, encodeType(param))
+		}
+		obj := newObject("FunctionType", node.Token)
+		obj["parameters"] = params
+		obj["return"] = encodeType(node.Return)
+		return obj
+	default:
+		panic(fmt.Sprintf("astjson: unsupported node %T", node))
+	}
+}
+
+func newObject(kind string, tk token.Token) jsonObject {
+	return jsonObject{
+		"kind":  kind,
+		"token": encodeToken(tk),
+	}
+}
+
+func encodeToken(tk token.Token) jsonToken {
+	return jsonToken{
+		Type:    tk.Type,
+		Literal: tk.Literal,
+		Line:    tk.Line,
+		Column:  tk.Column,
+	}
+}
+
+func encodeExpression(exp ast.Expression) interface{} {
+	if exp == nil {
+		return nil
+	}
+	return encodeNode(exp)
+}
+
+func encodeExpressions(exps []ast.Expression) []interface{} {
+	encoded := make([]interface{}, 0, len(exps))
+	for _, exp := range exps {
+		encoded = append(encoded, encodeExpression(exp))
+	}
+	return encoded
+}
+
+func encodeStatements(stmts []ast.Statement) []interface{} {
+	encoded := make([]interface{}, 0, len(stmts))
+	for _, stmt := range stmts {
+		encoded = append(encoded, encodeNode(stmt))
+	}
+	return encoded
+}
+
+func encodeType(typ ast.TypeExpression) interface{} {
+	if typ == nil {
+		return nil
+	}
+	return encodeNode(typ)
+}
@@ -0,0 +1,84 @@
+package astjson
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"monkey_interpreter/lexer"
+	"monkey_interpreter/parser"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	input := `// comment
+	import "lib/math.mk" as math;
+	math.add(1, 2);
+	let add = fn(x: int, y) -> int { return x + y; };
+	let typed: fn([string], {int: bool}) -> any = fn(a, b) { a };
+	let arr = [1, "two", true, -3];
+	let h = {"a": arr[0], "b": arr[1:], "c": arr[::-1]};
+	if (add(1, 2) > 2) { puts(h["a"]) } else { fn() {}() }
+	!false;
+	let unless = macro(cond, body) { quote(if (!(unquote(cond))) { unquote(body) }) };
+	[]`
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	assert.Empty(t, p.Error())
+
+	data, err := Marshal(program)
+	assert.NoError(t, err)
+
+	decoded, err := Unmarshal(data)
+	assert.NoError(t, err)
+	assert.Equal(t, program, decoded)
+}
+
+func TestMarshalSchema(t *testing.T) {
+	p := parser.New(lexer.New("x + 1"))
+	program := p.ParseProgram()
+
+	data, err := Marshal(program)
+	assert.NoError(t, err)
+
+	var doc map[string]interface{}
+	assert.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, float64(Version), doc["version"])
+
+	prog := doc["program"].(map[string]interface{})
+	assert.Equal(t, "Program", prog["kind"])
+	stmt := prog["statements"].([]interface{})[0].(map[string]interface{})
+	assert.Equal(t, "ExpressionStatement", stmt["kind"])
+	infix := stmt["expression"].(map[string]interface{})
+	assert.Equal(t, "InfixExpression", infix["kind"])
+	assert.Equal(t, "+", infix["operator"])
+	assert.Equal(t, map[string]interface{}{
+		"type":    "+",
+		"literal": "+",
+		"line":    float64(1),
+		"column":  float64(3),
+	}, infix["token"])
+	right := infix["right"].(map[string]interface{})
+	assert.Equal(t, "IntegerLiteral", right["kind"])
+	assert.Equal(t, float64(1), right["value"])
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		expErr string
+	}{
+		{`{"version": 99, "program": null}`, "unsupported AST schema version 99, want 1"},
+		{`{"version": 1, "program": {"kind": "Bogus"}}`, `unknown node kind "Bogus"`},
+		{`{"version": 1, "program": {"kind": "Identifier", "token": {}, "value": "x"}}`, "expected a Program node, got *ast.Identifier"},
+		{`{"version": 1, "program": {"kind": "Program", "statements": [{"kind": "Identifier", "token": {}, "value": "x"}]}}`, "expected a statement, got *ast.Identifier"},
+		{`{"version": 1, "program": {"kind": "Program", "statements": [{"kind": "LetStatement", "token": {}, "name": {"kind": "Identifier", "token": {}, "value": "x"}, "value": null}]}}`, "LetStatement is missing its value"},
+		{`{"version": 1, "program": {"kind": "Program", "statements": [{"kind": "ExpressionStatement", "token": {}, "expression": {"kind": "FunctionLiteral", "token": {}, "parameters": [], "body": null}}]}}`, "FunctionLiteral is missing its body"},
+		{`{"version": 1, "program": {"kind": "Program", "statements": [{"kind": "ExpressionStatement", "token": {}, "expression": {"kind": "IfExpression", "token": {}, "condition": {"kind": "Boolean", "token": {}, "value": true}}}]}}`, "IfExpression is missing its consequence"},
+		{`{"version": 1, "program": {"kind": "Program", "statements": [{"kind": "ExpressionStatement", "token": {}, "expression": {"kind": "CallExpression", "token": {}, "function": null, "arguments": []}}]}}`, "CallExpression is missing its function"},
+		{`{"version": 1, "program": {"kind": "Program", "statements": [{"kind": "ExpressionStatement", "token": {}, "expression": {"kind": "InfixExpression", "token": {}, "operator": "+", "left": null, "right": null}}]}}`, "InfixExpression is missing its left"},
+		{`{"version": 1, "program": {"kind": "Program", "statements": [{"kind": "ExpressionStatement", "token": {}, "expression": {"kind": "HashLiteral", "token": {}, "pairs": [{"key": null}]}}]}}`, "HashLiteral pair is missing its key"},
+	}
+	for _, test := range tests {
+		_, err := Unmarshal([]byte(test.input))
+		assert.EqualError(t, err, test.expErr)
+	}
+}
@@ -0,0 +1,370 @@
+package astjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"monkey_interpreter/ast"
+	"monkey_interpreter/token"
+)
+
+// Unmarshal decodes a document produced by Marshal back into an ast.Program
+func Unmarshal(data []byte) (*ast.Program, error) {
+	var doc document
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Version != Version {
+		return nil, fmt.Errorf("unsupported AST schema version %d, want %d", doc.Version, Version)
+	}
+
+	d := &decoder{}
+	node := d.node(doc.Program)
+	if d.err != nil {
+		return nil, d.err
+	}
+	program, ok := node.(*ast.Program)
+	if !ok {
+		return nil, fmt.Errorf("expected a Program node, got %T", node)
+	}
+	return program, nil
+}
+
+// decoder keeps the first error so the node constructors can be written without error checks
+type decoder struct {
+	err error
+}
+
+func (d *decoder) fail(format string, a ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, a...)
+	}
+}
+
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || bytes.Equal(raw, []byte("null"))
+}
+
+func (d *decoder) fields(raw json.RawMessage) map[string]json.RawMessage {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		d.fail("invalid node: %s", err)
+	}
+	return fields
+}
+
+func (d *decoder) value(raw json.RawMessage, v interface{}) {
+	if err := json.Unmarshal(raw, v); err != nil {
+		d.fail("invalid value %s: %s", raw, err)
+	}
+}
+
+func (d *decoder) token(raw json.RawMessage) token.Token {
+	var tk jsonToken
+	d.value(raw, &tk)
+	return token.Token{
+		Type:    tk.Type,
+		Literal: tk.Literal,
+		Line:    tk.Line,
+		Column:  tk.Column,
+	}
+}
+
+func (d *decoder) node(raw json.RawMessage) ast.Node {
+	if d.err != nil || isNull(raw) {
+		return nil
+	}
+	f := d.fields(raw)
+	var kind string
+	d.value(f["kind"], &kind)
+
+	switch kind {
+	case "Program":
+		program := &ast.Program{Statements: d.statements(f["statements"])}
+		for _, node := range d.nodes(f["comments"]) {
+			comment, ok := node.(*ast.Comment)
+			if !ok {
+				d.fail("expected a Comment node, got %T", node)
+				return nil
+			}
+			program.Comments = append(program.Comments, comment)
+		}
+		return program
+	case "Comment":
+		return &ast.Comment{Token: d.token(f["token"])}
+	case "LetStatement":
+		return &ast.LetStatement{
+			Token: d.token(f["token"]),
+			Name:  d.identifier(d.required(f, "LetStatement", "name")),
+			Value: d.expression(d.required(f, "LetStatement", "value")),
+		}
+	case "ReturnStatement":
+		return &ast.ReturnStatement{
+			Token:       d.token(f["token"]),
+			ReturnValue: d.expression(d.required(f, "ReturnStatement", "value")),
+		}
+	case "ImportStatement":
+		stmt := &ast.ImportStatement{
+			Token: d.token(f["token"]),
+			Name:  d.identifier(d.required(f, "ImportStatement", "name")),
+		}
+		if path, ok := d.node(f["path"]).(*ast.StringLiteral); ok {
+			stmt.Path = path
+		} else {
+			d.fail("expected a StringLiteral import path")
+		}
+		return stmt
+	case "ExpressionStatement":
+		return &ast.ExpressionStatement{
+			Token:      d.token(f["token"]),
+			Expression: d.expression(d.required(f, "ExpressionStatement", "expression")),
+		}
+	case "BlockStatement":
+		return &ast.BlockStatement{
+			Token:      d.token(f["token"]),
+			Statements: d.statements(f["statements"]),
+			EndToken:   d.token(f["endToken"]),
+		}
+	case "Identifier":
+		ident := &ast.Identifier{
+			Token:      d.token(f["token"]),
+			Annotation: d.typeExpression(f["annotation"]),
+		}
+		d.value(f["value"], &ident.Value)
+		return ident
+	case "IntegerLiteral":
+		lit := &ast.IntegerLiteral{Token: d.token(f["token"])}
+		d.value(f["value"], &lit.Value)
+		return lit
+	case "StringLiteral":
+		lit := &ast.StringLiteral{Token: d.token(f["token"])}
+		d.value(f["value"], &lit.Value)
+		return lit
+	case "Boolean":
+		lit := &ast.Boolean{Token: d.token(f["token"])}
+		d.value(f["value"], &lit.Value)
+		return lit
+	case "PrefixExpression":
+		exp := &ast.PrefixExpression{
+			Token: d.token(f["token"]),
+			Right: d.expression(d.required(f, "PrefixExpression", "right")),
+		}
+		d.value(f["operator"], &exp.Operator)
+		return exp
+	case "InfixExpression":
+		exp := &ast.InfixExpression{
+			Token:      d.token(f["token"]),
+			LeftValue:  d.expression(d.required(f, "InfixExpression", "left")),
+			RightValue: d.expression(d.required(f, "InfixExpression", "right")),
+		}
+		d.value(f["operator"], &exp.Operator)
+		return exp
+	case "IfExpression":
+		return &ast.IfExpression{
+			Token:       d.token(f["token"]),
+			Condition:   d.expression(d.required(f, "IfExpression", "condition")),
+			Consequence: d.block(d.required(f, "IfExpression", "consequence")),
+			Alternative: d.block(f["alternative"]),
+		}
+	case "FunctionLiteral":
+		return &ast.FunctionLiteral{
+			Token:      d.token(f["token"]),
+			Parameters: d.parameters(f["parameters"]),
+			ReturnType: d.typeExpression(f["returnType"]),
+			Body:       d.block(d.required(f, "FunctionLiteral", "body")),
+		}
+	case "MacroLiteral":
+		return &ast.MacroLiteral{
+			Token:      d.token(f["token"]),
+			Parameters: d.parameters(f["parameters"]),
+			Body:       d.block(d.required(f, "MacroLiteral", "body")),
+		}
+	case "CallExpression":
+		return &ast.CallExpression{
+			Token:     d.token(f["token"]),
+			Function:  d.expression(d.required(f, "CallExpression", "function")),
+			Arguments: d.expressions(f["arguments"]),
+		}
+	case "ArrayLiteral":
+		return &ast.ArrayLiteral{
+			Token:    d.token(f["token"]),
+			Elements: d.expressions(f["elements"]),
+		}
+	case "IndexExpression":
+		return &ast.IndexExpression{
+			Token: d.token(f["token"]),
+			Left:  d.expression(d.required(f, "IndexExpression", "left")),
+			Index: d.expression(d.required(f, "IndexExpression", "index")),
+		}
+	case "MemberExpression":
+		return &ast.MemberExpression{
+			Token:  d.token(f["token"]),
+			Object: d.expression(d.required(f, "MemberExpression", "object")),
+			Member: d.identifier(d.required(f, "MemberExpression", "member")),
+		}
+	case "SliceExpression":
+		return &ast.SliceExpression{
+			Token: d.token(f["token"]),
+			Left:  d.expression(d.required(f, "SliceExpression", "left")),
+			Start: d.expression(f["start"]),
+			End:   d.expression(f["end"]),
+			Step:  d.expression(f["step"]),
+		}
+	case "HashLiteral":
+		hash := &ast.HashLiteral{
+			Token: d.token(f["token"]),
+			Pairs: []ast.HashLiteralPair{},
+		}
+		var pairs []map[string]json.RawMessage
+		d.value(f["pairs"], &pairs)
+		for _, pair := range pairs {
+			hash.Pairs = append(hash.Pairs, ast.HashLiteralPair{
+				Key:   d.expression(d.required(pair, "HashLiteral pair", "key")),
+				Value: d.expression(d.required(pair, "HashLiteral pair", "value")),
+			})
+		}
+		return hash
+	case "NamedType":
+		typ := &ast.NamedType{Token: d.token(f["token"])}
+		d.value(f["name"], &typ.Name)
+		return typ
+	case "ArrayType":
+		return &ast.ArrayType{
+			Token:   d.token(f["token"]),
+			Element: d.typeExpression(d.required(f, "ArrayType", "element")),
+		}
+	case "HashType":
+		return &ast.HashType{
+			Token: d.token(f["token"]),
+			Key:   d.typeExpression(d.required(f, "HashType", "key")),
+			Value: d.typeExpression(d.required(f, "HashType", "value")),
+		}
+	case "FunctionType":
+		fnType := &ast.FunctionType{
+			Token:  d.token(f["token"]),
+			Return: d.typeExpression(d.required(f, "FunctionType", "return")),
+		}
+		for _, node := range d.nodes(f["parameters"]) {
+			param, ok := node.(ast.TypeExpression)
+			if !ok {
+				d.fail("expected a type, got %T", node)
+				return nil
+			}
+			fnType.Parameters = append(fnType.Parameters, param)
+		}
+		return fnType
+	default:
+		d.fail("unknown node kind %q", kind)
+		return nil
+	}
+}
+
+// required returns the child field of a node, failing if it is missing or null
+func (d *decoder) required(f map[string]json.RawMessage, kind, field string) json.RawMessage {
+	raw := f[field]
+	if isNull(raw) {
+		d.fail("%s is missing its %s", kind, field)
+	}
+	return raw
+}
+
+func (d *decoder) nodes(raw json.RawMessage) []ast.Node {
+	if isNull(raw) {
+		return nil
+	}
+	var items []json.RawMessage
+	d.value(raw, &items)
+	var nodes []ast.Node
+	for _, item := range items {
+		nodes = append(nodes, d.node(item))
+	}
+	return nodes
+}
+
+func (d *decoder) expression(raw json.RawMessage) ast.Expression {
+	node := d.node(raw)
+	if node == nil {
+		return nil
+	}
+	exp, ok := node.(ast.Expression)
+	if !ok {
+		d.fail("expected an expression, got %T", node)
+	}
+	return exp
+}
+
+// expressions decodes a list of expressions. Like the parser it returns nil for an empty list
+func (d *decoder) expressions(raw json.RawMessage) []ast.Expression {
+	var exps []ast.Expression
+	for _, node := range d.nodes(raw) {
+		exp, ok := node.(ast.Expression)
+		if !ok {
+			d.fail("expected an expression, got %T", node)
+			return nil
+		}
+		exps = append(exps, exp)
+	}
+	return exps
+}
+
+func (d *decoder) statements(raw json.RawMessage) []ast.Statement {
+	stmts := []ast.Statement{}
+	for _, node := range d.nodes(raw) {
+		stmt, ok := node.(ast.Statement)
+		if !ok {
+			d.fail("expected a statement, got %T", node)
+			return nil
+		}
+		stmts = append(stmts, stmt)
+	}
+	return stmts
+}
+
+func (d *decoder) block(raw json.RawMessage) *ast.BlockStatement {
+	node := d.node(raw)
+	if node == nil {
+		return nil
+	}
+	block, ok := node.(*ast.BlockStatement)
+	if !ok {
+		d.fail("expected a BlockStatement, got %T", node)
+	}
+	return block
+}
+
+func (d *decoder) identifier(raw json.RawMessage) *ast.Identifier {
+	node := d.node(raw)
+	if node == nil {
+		return nil
+	}
+	ident, ok := node.(*ast.Identifier)
+	if !ok {
+		d.fail("expected an Identifier, got %T", node)
+	}
+	return ident
+}
+
+func (d *decoder) parameters(raw json.RawMessage) []*ast.Identifier {
+	var params []*ast.Identifier
+	for _, node := range d.nodes(raw) {
+		param, ok := node.(*ast.Identifier)
+		if !ok {
+			d.fail("expected an Identifier parameter, got %T", node)
+			return nil
+		}
+		params = append(params, param)
+	}
+	return params
+}
+
+func (d *decoder) typeExpression(raw json.RawMessage) ast.TypeExpression {
+	node := d.node(raw)
+	if node == nil {
+		return nil
+	}
+	typ, ok := node.(ast.TypeExpression)
+	if !ok {
+		d.fail("expected a type, got %T", node)
+	}
+	return typ
+}
@@ -0,0 +1,56 @@
+package checker
+
+// builtinSchemes types the builtins with a single signature. The others, like puts or reduce
+// which take a varying number of arguments, are not listed and have the type any
+func builtinSchemes() map[string]*scheme {
+	// The quantified variables are replaced at every use, so all schemes can share them
+	a, b := &variable{id: -1}, &variable{id: -2}
+	fn := func(ret Type, params ...Type) Type {
+		return &function{params: params, ret: ret}
+	}
+	mono := func(t Type) *scheme {
+		return &scheme{typ: t}
+	}
+	poly := func(t Type, vars ...*variable) *scheme {
+		return &scheme{vars: vars, typ: t}
+	}
+	arrayOf := func(elem Type) Type {
+		return &array{elem: elem}
+	}
+	hashOf := &hash{key: a, value: b}
+
+	return map[string]*scheme{
+		"len":      mono(fn(intType, anyType)),
+		"first":    poly(fn(a, arrayOf(a)), a),
+		"last":     poly(fn(a, arrayOf(a)), a),
+		"rest":     poly(fn(arrayOf(a), arrayOf(a)), a),
+		"push":     poly(fn(arrayOf(a), arrayOf(a), a), a),
+		"keys":     poly(fn(arrayOf(a), hashOf), a, b),
+		"values":   poly(fn(arrayOf(b), hashOf), a, b),
+		"has":      poly(fn(boolType, hashOf, a), a, b),
+		"delete":   poly(fn(hashOf, hashOf, a), a, b),
+		"put":      poly(fn(hashOf, hashOf, a, b), a, b),
+		"merge":    poly(fn(hashOf, hashOf, hashOf), a, b),
+		"map":      poly(fn(arrayOf(b), arrayOf(a), fn(b, a)), a, b),
+		"filter":   poly(fn(arrayOf(a), arrayOf(a), fn(b, a)), a, b),
+		"any":      poly(fn(boolType, arrayOf(a), fn(b, a)), a, b),
+		"all":      poly(fn(boolType, arrayOf(a), fn(b, a)), a, b),
+		"find":     poly(fn(a, arrayOf(a), fn(b, a)), a, b),
+		"readline": mono(fn(stringType)),
+
+		"split":       mono(fn(arrayOf(stringType), stringType, stringType)),
+		"join":        mono(fn(stringType, arrayOf(stringType), stringType)),
+		"trim":        mono(fn(stringType, stringType)),
+		"trim_left":   mono(fn(stringType, stringType)),
+		"trim_right":  mono(fn(stringType, stringType)),
+		"upper":       mono(fn(stringType, stringType)),
+		"lower":       mono(fn(stringType, stringType)),
+		"contains":    mono(fn(boolType, stringType, stringType)),
+		"starts_with": mono(fn(boolType, stringType, stringType)),
+		"ends_with":   mono(fn(boolType, stringType, stringType)),
+		"index_of":    mono(fn(intType, stringType, stringType)),
+		"replace":     mono(fn(stringType, stringType, stringType, stringType)),
+		"repeat":      mono(fn(stringType, stringType, intType)),
+		"chars":       mono(fn(arrayOf(stringType), stringType)),
+	}
+}
@@ -0,0 +1,478 @@
+// Package checker infers the types of a Monkey program without running it, in the style of
+// Hindley-Milner: unannotated parameters get type variables that are solved by unification, and
+// functions bound by let are generalized so they can be used at several types.
+//
+// Monkey is dynamically typed, so the checker only reports what would fail at runtime. Values it
+// cannot type, such as null, the elements of mixed arrays or the result of builtins with several
+// signatures, get the type any, which is compatible with every type.
+package checker
+
+import (
+	"fmt"
+	"monkey_interpreter/ast"
+	"monkey_interpreter/resolver"
+	"monkey_interpreter/token"
+	"sort"
+)
+
+// Error is a type error at a position of the source
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// binding is a variable in scope. A variable used before its let has been checked gets a
+// forward type variable that the let unifies with its type
+type binding struct {
+	scheme  *scheme
+	forward *variable
+}
+
+type scope struct {
+	vars  map[string]*binding
+	outer *scope
+}
+
+func (s *scope) lookup(name string) *binding {
+	for ; s != nil; s = s.outer {
+		if b, ok := s.vars[name]; ok {
+			return b
+		}
+	}
+	return nil
+}
+
+// returnContext is the function whose body is being checked
+type returnContext struct {
+	typ       Type
+	annotated bool
+}
+
+// Info holds the types inferred for the bindings of a program
+type Info struct {
+	// Types maps the names bound by let statements and function parameters to their types. The
+	// type of a let is the one before generalization, so polymorphic functions show variables
+	Types map[*ast.Identifier]Type
+}
+
+type checker struct {
+	info   *Info
+	errors []*Error
+	trail  []change
+	level  int
+	nextID int
+	scope  *scope
+	fn     *returnContext // nil at the top level
+}
+
+// Check infers the types of program and returns the type errors sorted by position
+func Check(program *ast.Program) []*Error {
+	_, errs := Infer(program)
+	return errs
+}
+
+// Infer is like Check but also returns the inferred types
+func Infer(program *ast.Program) (*Info, []*Error) {
+	c := &checker{
+		info:  &Info{Types: make(map[*ast.Identifier]Type)},
+		scope: &scope{vars: make(map[string]*binding)},
+	}
+	for name, s := range builtinSchemes() {
+		c.scope.vars[name] = &binding{scheme: s}
+	}
+
+	c.enterScope(program.Statements)
+	c.statements(program.Statements)
+
+	sort.SliceStable(c.errors, func(i, j int) bool {
+		a, b := c.errors[i], c.errors[j]
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+	return c.info, c.errors
+}
+
+func (c *checker) errorf(tk token.Token, format string, a ...interface{}) {
+	c.errors = append(c.errors, &Error{
+		Line:    tk.Line,
+		Column:  tk.Column,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+// enterScope opens the scope of a program or function body and declares its let bindings
+func (c *checker) enterScope(statements []ast.Statement) {
+	c.scope = &scope{vars: make(map[string]*binding), outer: c.scope}
+	for _, stmt := range statements {
+		for _, decl := range resolver.Declarations(stmt) {
+			if _, ok := c.scope.vars[decl.Value]; !ok {
+				c.scope.vars[decl.Value] = &binding{}
+			}
+		}
+	}
+}
+
+// statements checks a program or block and returns the type of the value it evaluates to
+func (c *checker) statements(statements []ast.Statement) Type {
+	var last Type = anyType
+	for _, stmt := range statements {
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			c.let(stmt)
+			last = anyType
+		case *ast.ImportStatement:
+			c.define(stmt.Name.Value, &scheme{typ: anyType}, stmt.Name.Token)
+			last = anyType
+		case *ast.ReturnStatement:
+			t := c.infer(stmt.ReturnValue)
+			if c.fn != nil {
+				c.returns(t, stmt.ReturnValue)
+			}
+			// Nothing after a return runs, so the block can be given any type
+			last = c.fresh()
+		case *ast.ExpressionStatement:
+			last = c.infer(stmt.Expression)
+		}
+	}
+	return last
+}
+
+func (c *checker) let(stmt *ast.LetStatement) {
+	c.level++
+	t := c.infer(stmt.Value)
+	c.level--
+
+	if stmt.Name.Annotation != nil {
+		declared := c.annotation(stmt.Name.Annotation)
+		if !c.unify(declared, t) {
+			c.errorf(ast.StartToken(stmt.Value), "cannot use %s as %s in let %s", t, declared, stmt.Name.Value)
+		}
+		t = declared
+	}
+	c.info.Types[stmt.Name] = t
+	c.define(stmt.Name.Value, c.generalize(t), stmt.Name.Token)
+}
+
+// define binds name in the current scope, checking the uses that came before the definition
+func (c *checker) define(name string, s *scheme, tk token.Token) {
+	b, ok := c.scope.vars[name]
+	if !ok {
+		b = &binding{}
+		c.scope.vars[name] = b
+	}
+	if b.forward != nil {
+		if t := c.instantiate(s); !c.unify(b.forward, t) {
+			c.errorf(tk, "%s is used as %s before its definition as %s", name, b.forward, t)
+		}
+		b.forward = nil
+	}
+	b.scheme = s
+}
+
+// returns checks a value returned from the current function
+func (c *checker) returns(t Type, value ast.Node) {
+	if c.unify(c.fn.typ, t) || !c.fn.annotated {
+		// Without an annotation a function may return values of different types, the first one wins
+		return
+	}
+	c.errorf(ast.StartToken(value), "cannot use %s as %s in return", t, c.fn.typ)
+}
+
+func (c *checker) infer(exp ast.Expression) Type {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral:
+		return intType
+	case *ast.StringLiteral:
+		return stringType
+	case *ast.Boolean:
+		return boolType
+	case *ast.Identifier:
+		b := c.scope.lookup(exp.Value)
+		if b == nil {
+			// Undefined variables are reported by the resolver
+			return anyType
+		}
+		if b.scheme == nil {
+			if b.forward == nil {
+				b.forward = c.fresh()
+			}
+			return b.forward
+		}
+		return c.instantiate(b.scheme)
+	case *ast.PrefixExpression:
+		return c.prefix(exp)
+	case *ast.InfixExpression:
+		return c.infix(exp)
+	case *ast.IfExpression:
+		c.infer(exp.Condition)
+		consequence := c.statements(exp.Consequence.Statements)
+		if exp.Alternative == nil {
+			return anyType
+		}
+		alternative := c.statements(exp.Alternative.Statements)
+		return c.join(consequence, alternative)
+	case *ast.FunctionLiteral:
+		return c.function(exp)
+	case *ast.CallExpression:
+		return c.call(exp)
+	case *ast.ArrayLiteral:
+		var elem Type = c.fresh()
+		for _, e := range exp.Elements {
+			elem = c.join(elem, c.infer(e))
+		}
+		return &array{elem: elem}
+	case *ast.HashLiteral:
+		var key, value Type = c.fresh(), c.fresh()
+		for _, pair := range exp.Pairs {
+			key = c.join(key, c.infer(pair.Key))
+			value = c.join(value, c.infer(pair.Value))
+		}
+		return &hash{key: key, value: value}
+	case *ast.IndexExpression:
+		return c.index(exp)
+	case *ast.SliceExpression:
+		return c.slice(exp)
+	case *ast.MemberExpression:
+		c.infer(exp.Object)
+		return anyType
+	default:
+		return anyType
+	}
+}
+
+// join is the type of a value that is either a or b, any if they are incompatible
+func (c *checker) join(a, b Type) Type {
+	if c.unify(a, b) {
+		return a
+	}
+	return anyType
+}
+
+func (c *checker) prefix(exp *ast.PrefixExpression) Type {
+	right := c.infer(exp.Right)
+	if exp.Operator == "!" {
+		return boolType
+	}
+	if !c.unify(intType, right) {
+		c.errorf(exp.Token, "unknown operator: %s%s", exp.Operator, right)
+		return anyType
+	}
+	return intType
+}
+
+func (c *checker) infix(exp *ast.InfixExpression) Type {
+	left, right := c.infer(exp.LeftValue), c.infer(exp.RightValue)
+
+	var operand, result Type
+	switch exp.Operator {
+	case "==", "!=":
+		// Values of different types are never equal but can be compared
+		return boolType
+	case "<", ">":
+		if operand = c.intOrStringOperand(left, right); operand == anyType {
+			return boolType
+		}
+		result = boolType
+	case "+":
+		if operand = c.intOrStringOperand(left, right); operand == anyType {
+			return anyType
+		}
+		result = operand
+	default:
+		operand, result = intType, intType
+	}
+
+	if c.unify(operand, left) && c.unify(operand, right) {
+		return result
+	}
+	if c.unify(left, right) {
+		c.errorf(exp.Token, "unknown operator: %s %s %s", prune(left), exp.Operator, prune(right))
+	} else {
+		c.errorf(exp.Token, "type mismatch: %s %s %s", prune(left), exp.Operator, prune(right))
+	}
+	return anyType
+}
+
+// intOrStringOperand picks the type of the operands of an operator taking two ints or two
+// strings, any if either of them is
+func (c *checker) intOrStringOperand(left, right Type) Type {
+	l, r := prune(left), prune(right)
+	switch {
+	case l == anyType || r == anyType:
+		return anyType
+	case l == stringType || r == stringType:
+		return stringType
+	case l == intType || r == intType:
+		return intType
+	}
+	_, lv := l.(*variable)
+	_, rv := r.(*variable)
+	if lv && rv {
+		// Either two ints or two strings, which is left to the other uses of the operands
+		c.unify(l, r)
+		return l
+	}
+	return intType
+}
+
+func (c *checker) function(fn *ast.FunctionLiteral) Type {
+	outerScope, outerFn := c.scope, c.fn
+	c.enterScope(fn.Body.Statements)
+
+	params := make([]Type, 0, len(fn.Parameters))
+	for _, param := range fn.Parameters {
+		var t Type
+		if param.Annotation != nil {
+			t = c.annotation(param.Annotation)
+		} else {
+			t = c.fresh()
+		}
+		c.scope.vars[param.Value] = &binding{scheme: &scheme{typ: t}}
+		c.info.Types[param] = t
+		params = append(params, t)
+	}
+
+	c.fn = &returnContext{typ: c.fresh()}
+	if fn.ReturnType != nil {
+		c.fn = &returnContext{typ: c.annotation(fn.ReturnType), annotated: true}
+	}
+	ret := c.fn.typ
+	last := c.statements(fn.Body.Statements)
+	if len(fn.Body.Statements) > 0 {
+		c.returns(last, lastValue(fn.Body))
+	}
+
+	c.scope, c.fn = outerScope, outerFn
+	return &function{params: params, ret: ret}
+}
+
+// lastValue returns the node whose value a block evaluates to, used to position errors
+func lastValue(block *ast.BlockStatement) ast.Node {
+	stmt := block.Statements[len(block.Statements)-1]
+	if exp, ok := stmt.(*ast.ExpressionStatement); ok {
+		return exp.Expression
+	}
+	return stmt
+}
+
+func (c *checker) call(exp *ast.CallExpression) Type {
+	if ident, ok := exp.Function.(*ast.Identifier); ok && ident.Value == "quote" {
+		return anyType
+	}
+
+	fnType := c.infer(exp.Function)
+	args := make([]Type, 0, len(exp.Arguments))
+	for _, arg := range exp.Arguments {
+		args = append(args, c.infer(arg))
+	}
+
+	switch fn := prune(fnType).(type) {
+	case *function:
+		// Extra arguments are ignored at runtime, only missing ones fail
+		if len(args) < len(fn.params) {
+			c.errorf(exp.Token, "wrong number of arguments. got=%d, want=%d", len(args), len(fn.params))
+			return fn.ret
+		}
+		for i, arg := range args[:len(fn.params)] {
+			if !c.unify(fn.params[i], arg) {
+				c.errorf(ast.StartToken(exp.Arguments[i]), "cannot use %s as %s in argument %d", prune(arg), prune(fn.params[i]), i+1)
+			}
+		}
+		return fn.ret
+	case *variable:
+		ret := c.fresh()
+		c.unify(fn, &function{params: args, ret: ret})
+		return ret
+	default:
+		if fn != anyType {
+			c.errorf(ast.StartToken(exp.Function), "not a function: %s", fn)
+		}
+		return anyType
+	}
+}
+
+func (c *checker) index(exp *ast.IndexExpression) Type {
+	left, idx := c.infer(exp.Left), c.infer(exp.Index)
+	switch l := prune(left).(type) {
+	case *array:
+		c.integerIndex(idx, exp.Index)
+		return l.elem
+	case *hash:
+		// A key of another type is just missing
+		c.unify(l.key, idx)
+		return l.value
+	case *variable:
+		return anyType
+	default:
+		if l == stringType {
+			c.integerIndex(idx, exp.Index)
+			return stringType
+		}
+		if l != anyType {
+			c.errorf(exp.Token, "index operator not supported: %s", l)
+		}
+		return anyType
+	}
+}
+
+func (c *checker) integerIndex(idx Type, node ast.Node) {
+	if !c.unify(intType, idx) {
+		c.errorf(ast.StartToken(node), "index must be int, got %s", prune(idx))
+	}
+}
+
+func (c *checker) slice(exp *ast.SliceExpression) Type {
+	left := c.infer(exp.Left)
+	for _, bound := range []ast.Expression{exp.Start, exp.End, exp.Step} {
+		if bound != nil {
+			if t := c.infer(bound); !c.unify(intType, t) {
+				c.errorf(ast.StartToken(bound), "slice index must be int, got %s", prune(t))
+			}
+		}
+	}
+	switch l := prune(left).(type) {
+	case *array, *variable:
+		return l
+	default:
+		if l != stringType && l != anyType {
+			c.errorf(exp.Token, "slice operator not supported: %s", l)
+			return anyType
+		}
+		return l
+	}
+}
+
+// annotation converts a type annotation to a type
+func (c *checker) annotation(typ ast.TypeExpression) Type {
+	switch typ := typ.(type) {
+	case *ast.NamedType:
+		switch typ.Name {
+		case "int":
+			return intType
+		case "string":
+			return stringType
+		case "bool":
+			return boolType
+		case "any":
+			return anyType
+		}
+		c.errorf(typ.Token, "unknown type %s", typ.Name)
+		return anyType
+	case *ast.ArrayType:
+		return &array{elem: c.annotation(typ.Element)}
+	case *ast.HashType:
+		return &hash{key: c.annotation(typ.Key), value: c.annotation(typ.Value)}
+	case *ast.FunctionType:
+		params := make([]Type, 0, len(typ.Parameters))
+		for _, param := range typ.Parameters {
+			params = append(params, c.annotation(param))
+		}
+		return &function{params: params, ret: c.annotation(typ.Return)}
+	default:
+		return anyType
+	}
+}
@@ -0,0 +1,115 @@
+package checker
+
+import (
+	"github.com/stretchr/testify/assert"
+	"monkey_interpreter/lexer"
+	"monkey_interpreter/parser"
+	"testing"
+)
+
+func check(t *testing.T, input string) []string {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	assert.Empty(t, p.Error(), input)
+
+	msgs := make([]string, 0)
+	for _, err := range Check(program) {
+		msgs = append(msgs, err.Error())
+	}
+	return msgs
+}
+
+func TestCheckValidPrograms(t *testing.T) {
+	tests := []string{
+		`let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) }; fib(10) + 1`,
+		`let id = fn(x) { x }; id(1) + 2; id("a") + "b"`,
+		`let add = fn(a, b) { a + b }; add(1, 2); add("a", "b")`,
+		`let f = fn() { g() + 1 }; let g = fn() { 2 }`,
+		`let x: int = 5; let s: string = "s"; let b: bool = !x`,
+		`let f = fn(a: string, b: [int]) -> bool { len(a) > first(b) }; f("s", [1])`,
+		`let h: {string: int} = {"a": 1}; h["a"] + 1; h[1]`,
+		`let counter = fn() { let count = 0; fn() { count + 1 } }; counter()() * 2`,
+		`let mixed = [1, "two", true]; mixed[0] - 1; mixed[1] + "x"`,
+		`let record = {"name": "a", "age": 1}; record["age"] + 1; record["name"] + "b"`,
+		`let people = [{"age": 1}, {"age": 2}]; reduce(map(people, fn(p) { p["age"] }), fn(a, b) { a + b }, 0)`,
+		`let maybe = fn(x) { if (x) { 1 } }; maybe(true) + "a"`,
+		`puts(1, "a"); len("abc") + len([1])`,
+		`let compose = fn(f: fn(int) -> int, g) { fn(x) { f(g(x)) } }; compose(fn(x) { x + 1 }, fn(x) { x * 2 })(3)`,
+		`"abc"[1:] + "d"; [1, 2, 3][::-1][0] + 1`,
+		`import "lib.mk" as lib; lib.add(1) + "a"`,
+		`let unknown = fn(f) { f(1) + f(2) }; unknown(fn(x) { x })`,
+		`if (true) { let y = 1 }; y + 1`,
+		`let x = 1; let x = "a"; x + "b"`,
+		`quote(1 + "a")`,
+		`let less = fn(a, b) { a < b }; less("a", "b"); if ("x" > "y") { 1 }`,
+		`join(map(split("a,b", ","), fn(s) { upper(s) }), "") + trim(" c ")`,
+		`let first = fn(x) { x }; first(5, "extra") + 1`,
+	}
+
+	for _, input := range tests {
+		assert.Empty(t, check(t, input), input)
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   []string
+	}{
+		{`"a" - 1`, []string{"1:5: type mismatch: string - int"}},
+		{`true + false`, []string{"1:6: unknown operator: bool + bool"}},
+		{`-"a"`, []string{"1:1: unknown operator: -string"}},
+		{`1 < "a"`, []string{"1:3: type mismatch: int < string"}},
+		{`true > false`, []string{"1:6: unknown operator: bool > bool"}},
+		{`split("a,b", ",")[0] - 1`, []string{"1:22: type mismatch: string - int"}},
+		{`repeat("a", "b")`, []string{"1:13: cannot use string as int in argument 2"}},
+		{`let x: int = "s"`, []string{"1:14: cannot use string as int in let x"}},
+		{`let y: foo = 1`, []string{"1:8: unknown type foo"}},
+		{`let f = fn(a: string) -> bool { a + 1 }`, []string{"1:35: type mismatch: string + int"}},
+		{`let f = fn() -> int { if (true) { return "a" } 1 }`, []string{"1:42: cannot use string as int in return"}},
+		{`let f = fn() -> int { "a" }`, []string{"1:23: cannot use string as int in return"}},
+		{`let f = fn(a, b) { a }; f(1)`, []string{"1:26: wrong number of arguments. got=1, want=2"}},
+		{`let f = fn(a: [int]) { a }; f(["x"])`, []string{"1:31: cannot use [string] as [int] in argument 1"}},
+		{`5(1)`, []string{"1:1: not a function: int"}},
+		{`let double = fn(x) { x * 2 }; double("s")`, []string{"1:38: cannot use string as int in argument 1"}},
+		{`let id = fn(x) { x }; id(1) + id("a")`, []string{"1:29: type mismatch: int + string"}},
+		{`[1]["a"]`, []string{"1:5: index must be int, got string"}},
+		{`5[0]`, []string{"1:2: index operator not supported: int"}},
+		{`true[1:]`, []string{"1:5: slice operator not supported: bool"}},
+		{`push([1], "a")`, []string{"1:11: cannot use string as int in argument 2"}},
+		{`map([1, 2], fn(x) { x + "a" })`, []string{"1:13: cannot use fn(string) -> string as fn(int) -> t2 in argument 2"}},
+		{
+			"let early = fn() { later(\"x\") };\nlet later = fn(n) { n * 2 };",
+			[]string{"2:5: later is used as fn(string) -> t3 before its definition as fn(int) -> int"},
+		},
+		{
+			"let a = \"a\" - 1;\nlet b = -true;",
+			[]string{"1:13: type mismatch: string - int", "2:9: unknown operator: -bool"},
+		},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.exp, check(t, test.input), test.input)
+	}
+}
+
+func TestInferTypes(t *testing.T) {
+	input := `let x = 1; let add = fn(a, b: string) { a + b }; let names = ["a"];`
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+
+	info, errs := Infer(program)
+	assert.Empty(t, errs)
+
+	types := make(map[string]string)
+	for ident, typ := range info.Types {
+		types[ident.Value] = typ.String()
+	}
+	assert.Equal(t, map[string]string{
+		"x":     "int",
+		"a":     "string",
+		"b":     "string",
+		"add":   "fn(string, string) -> string",
+		"names": "[string]",
+	}, types)
+}
@@ -0,0 +1,273 @@
+package checker
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Type is the static type of an expression
+type Type interface {
+	String() string
+}
+
+// basic types are compared by identity
+type basic struct {
+	name string
+}
+
+func (b *basic) String() string {
+	return b.name
+}
+
+var (
+	intType    = &basic{name: "int"}
+	stringType = &basic{name: "string"}
+	boolType   = &basic{name: "bool"}
+	// anyType is the type of values the checker knows nothing about, such as null or the result of
+	// a builtin with several signatures. It is compatible with every type
+	anyType = &basic{name: "any"}
+)
+
+type array struct {
+	elem Type
+}
+
+func (a *array) String() string {
+	return "[" + a.elem.String() + "]"
+}
+
+type hash struct {
+	key   Type
+	value Type
+}
+
+func (h *hash) String() string {
+	return "{" + h.key.String() + ": " + h.value.String() + "}"
+}
+
+type function struct {
+	params []Type
+	ret    Type
+}
+
+func (f *function) String() string {
+	params := make([]string, 0, len(f.params))
+	for _, param := range f.params {
+		params = append(params, param.String())
+	}
+	return "fn(" + strings.Join(params, ", ") + ") -> " + f.ret.String()
+}
+
+// variable is a type still to be inferred. Once bound, instance holds the type it stands for.
+// level is the let nesting depth the variable was created at, variables deeper than the let
+// being generalized are the ones it can be polymorphic in
+type variable struct {
+	id       int
+	level    int
+	instance Type
+}
+
+func (v *variable) String() string {
+	if v.instance != nil {
+		return v.instance.String()
+	}
+	return fmt.Sprintf("t%d", v.id)
+}
+
+// scheme is a type generalized over vars, instantiated with fresh variables at every use
+type scheme struct {
+	vars []*variable
+	typ  Type
+}
+
+// prune returns the type a chain of bound variables stands for
+func prune(t Type) Type {
+	if v, ok := t.(*variable); ok && v.instance != nil {
+		v.instance = prune(v.instance)
+		return v.instance
+	}
+	return t
+}
+
+func occurs(v *variable, t Type) bool {
+	switch t := prune(t).(type) {
+	case *variable:
+		return t == v
+	case *array:
+		return occurs(v, t.elem)
+	case *hash:
+		return occurs(v, t.key) || occurs(v, t.value)
+	case *function:
+		for _, param := range t.params {
+			if occurs(v, param) {
+				return true
+			}
+		}
+		return occurs(v, t.ret)
+	default:
+		return false
+	}
+}
+
+// change is an entry of the trail used to undo a failed unification
+type change struct {
+	v     *variable
+	level int
+	bound bool
+}
+
+// unify makes a and b the same type by binding variables. When it fails every binding it made is
+// undone, so the caller can report the error, or fall back to any, with the types as they were
+func (c *checker) unify(a, b Type) bool {
+	mark := len(c.trail)
+	if c.unifyTypes(a, b) {
+		return true
+	}
+	for i := len(c.trail) - 1; i >= mark; i-- {
+		ch := c.trail[i]
+		ch.v.level = ch.level
+		if ch.bound {
+			ch.v.instance = nil
+		}
+	}
+	c.trail = c.trail[:mark]
+	return false
+}
+
+func (c *checker) unifyTypes(a, b Type) bool {
+	a, b = prune(a), prune(b)
+	if a == b {
+		return true
+	}
+	if v, ok := a.(*variable); ok {
+		return c.bind(v, b)
+	}
+	if v, ok := b.(*variable); ok {
+		return c.bind(v, a)
+	}
+	if a == anyType || b == anyType {
+		return true
+	}
+
+	switch a := a.(type) {
+	case *array:
+		if b, ok := b.(*array); ok {
+			return c.unifyTypes(a.elem, b.elem)
+		}
+	case *hash:
+		if b, ok := b.(*hash); ok {
+			return c.unifyTypes(a.key, b.key) && c.unifyTypes(a.value, b.value)
+		}
+	case *function:
+		if b, ok := b.(*function); ok && len(a.params) == len(b.params) {
+			for i := range a.params {
+				if !c.unifyTypes(a.params[i], b.params[i]) {
+					return false
+				}
+			}
+			return c.unifyTypes(a.ret, b.ret)
+		}
+	}
+	return false
+}
+
+func (c *checker) bind(v *variable, t Type) bool {
+	if occurs(v, t) {
+		return false
+	}
+	c.adjustLevels(t, v.level)
+	c.trail = append(c.trail, change{v: v, level: v.level, bound: true})
+	v.instance = t
+	return true
+}
+
+// adjustLevels lowers the level of the variables in t, which become reachable from a variable
+// of the given level and must not be generalized before it is
+func (c *checker) adjustLevels(t Type, level int) {
+	switch t := prune(t).(type) {
+	case *variable:
+		if t.level > level {
+			c.trail = append(c.trail, change{v: t, level: t.level})
+			t.level = level
+		}
+	case *array:
+		c.adjustLevels(t.elem, level)
+	case *hash:
+		c.adjustLevels(t.key, level)
+		c.adjustLevels(t.value, level)
+	case *function:
+		for _, param := range t.params {
+			c.adjustLevels(param, level)
+		}
+		c.adjustLevels(t.ret, level)
+	}
+}
+
+func (c *checker) fresh() *variable {
+	c.nextID++
+	return &variable{id: c.nextID, level: c.level}
+}
+
+// generalize quantifies t over the variables created inside the let being checked
+func (c *checker) generalize(t Type) *scheme {
+	s := &scheme{typ: t}
+	var collect func(t Type)
+	collect = func(t Type) {
+		switch t := prune(t).(type) {
+		case *variable:
+			if t.level > c.level {
+				for _, v := range s.vars {
+					if v == t {
+						return
+					}
+				}
+				s.vars = append(s.vars, t)
+			}
+		case *array:
+			collect(t.elem)
+		case *hash:
+			collect(t.key)
+			collect(t.value)
+		case *function:
+			for _, param := range t.params {
+				collect(param)
+			}
+			collect(t.ret)
+		}
+	}
+	collect(t)
+	return s
+}
+
+func (c *checker) instantiate(s *scheme) Type {
+	if len(s.vars) == 0 {
+		return s.typ
+	}
+	fresh := make(map[*variable]Type, len(s.vars))
+	for _, v := range s.vars {
+		fresh[v] = c.fresh()
+	}
+	var copyType func(t Type) Type
+	copyType = func(t Type) Type {
+		switch t := prune(t).(type) {
+		case *variable:
+			if f, ok := fresh[t]; ok {
+				return f
+			}
+			return t
+		case *array:
+			return &array{elem: copyType(t.elem)}
+		case *hash:
+			return &hash{key: copyType(t.key), value: copyType(t.value)}
+		case *function:
+			params := make([]Type, 0, len(t.params))
+			for _, param := range t.params {
+				params = append(params, copyType(param))
+			}
+			return &function{params: params, ret: copyType(t.ret)}
+		default:
+			return t
+		}
+	}
+	return copyType(s.typ)
+}
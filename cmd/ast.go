@@ -0,0 +1,53 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"monkey_interpreter/astjson"
+	"monkey_interpreter/lexer"
+	"monkey_interpreter/parser"
+	"os"
+)
+
+func runAst(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) > 1 {
+		_, _ = fmt.Fprintln(stderr, "usage: monkey ast [file]")
+		return 2
+	}
+
+	name := "<standard input>"
+	var src []byte
+	var err error
+	if len(args) == 1 {
+		name = args[0]
+		src, err = os.ReadFile(name)
+	} else {
+		src, err = io.ReadAll(stdin)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 1
+	}
+
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Error()) > 0 {
+		for _, msg := range p.Error() {
+			_, _ = fmt.Fprintf(stderr, "%s: %s\n", name, msg)
+		}
+		return 1
+	}
+
+	data, err := astjson.Marshal(program)
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 1
+	}
+	var out bytes.Buffer
+	_ = json.Indent(&out, data, "", "  ")
+	out.WriteByte('\n')
+	_, _ = out.WriteTo(stdout)
+	return 0
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"monkey_interpreter/ast"
+	"monkey_interpreter/checker"
+	"monkey_interpreter/evaluator"
+	"monkey_interpreter/lexer"
+	"monkey_interpreter/object"
+	"monkey_interpreter/parser"
+	"os"
+	"sort"
+)
+
+type positionedError struct {
+	line    int
+	column  int
+	message string
+}
+
+func runCheck(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		src, err := io.ReadAll(stdin)
+		if err != nil {
+			_, _ = fmt.Fprintln(stderr, err)
+			return 1
+		}
+		return checkSource("<standard input>", string(src), stdin, stdout, stderr)
+	}
+
+	status := 0
+	for _, path := range args {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			_, _ = fmt.Fprintln(stderr, err)
+			status = 1
+			continue
+		}
+		if code := checkSource(path, string(src), stdin, stdout, stderr); code != 0 {
+			status = code
+		}
+	}
+	return status
+}
+
+// checkSource reports the parse, resolve and type errors of a file without running it
+func checkSource(name, src string, stdin io.Reader, stdout, stderr io.Writer) int {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Error()) > 0 {
+		for _, msg := range p.Error() {
+			_, _ = fmt.Fprintf(stdout, "%s: %s\n", name, msg)
+		}
+		return 1
+	}
+
+	// Macros have to be expanded to know what will run
+	ctx := evaluator.NewContext(stdin, stdout, stderr)
+	macroEnv := object.NewEnvironment()
+	evaluator.DefineMacros(program, macroEnv)
+	expanded, err := ctx.ExpandMacros(program, macroEnv)
+	if err != nil {
+		_, _ = fmt.Fprintf(stdout, "%s: %s\n", name, err)
+		return 1
+	}
+	program = expanded.(*ast.Program)
+
+	var errs []positionedError
+	for _, err := range ctx.Resolve(program, object.NewEnvironment()) {
+		errs = append(errs, positionedError{err.Line, err.Column, err.Message})
+	}
+	for _, err := range checker.Check(program) {
+		errs = append(errs, positionedError{err.Line, err.Column, err.Message})
+	}
+	sort.SliceStable(errs, func(i, j int) bool {
+		return errs[i].line < errs[j].line || (errs[i].line == errs[j].line && errs[i].column < errs[j].column)
+	})
+	for _, err := range errs {
+		_, _ = fmt.Fprintf(stdout, "%s:%d:%d: %s\n", name, err.line, err.column, err.message)
+	}
+	if len(errs) > 0 {
+		return 1
+	}
+	return 0
+}
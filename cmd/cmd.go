@@ -0,0 +1,88 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+type command struct {
+	usage string
+	run   func(args []string, stdin io.Reader, stdout, stderr io.Writer) int
+}
+
+var commands = map[string]command{
+	"ast": {
+		usage: "ast [file]               print the syntax tree of a Monkey file as JSON",
+		run:   runAst,
+	},
+	"check": {
+		usage: "check [files...]         report type errors without running the program",
+		run:   runCheck,
+	},
+	"cover": {
+		usage: "cover [-html file] file  report the statements and branches a Monkey program runs",
+		run:   runCover,
+	},
+	"dap": {
+		usage: "dap [-path dirs]         serve the Debug Adapter Protocol on standard input and output",
+		run:   runDap,
+	},
+	"debug": {
+		usage: "debug [-path dirs] file  run a Monkey program under an interactive debugger",
+		run:   runDebug,
+	},
+	"fmt": {
+		usage: "fmt [-w] [files...]      format Monkey source files",
+		run:   runFmt,
+	},
+	"lint": {
+		usage: "lint [files...]          report likely mistakes such as unused lets or unreachable code",
+		run:   runLint,
+	},
+	"lsp": {
+		usage: "lsp                      serve the Language Server Protocol on standard input and output",
+		run:   runLsp,
+	},
+	"profile": {
+		usage: "profile [-o file] file   report the functions and lines a Monkey program spends its time in",
+		run:   runProfile,
+	},
+	"run": {
+		usage: "run [-path dirs] file    run a Monkey program",
+		run:   runRun,
+	},
+	"test": {
+		usage: "test [-v] [patterns...]  run the test_ functions of the *_test.mk files, ./... for all of them",
+		run:   runTest,
+	},
+}
+
+// Run executes the monkey command named by args[0] and returns the exit code of the process
+func Run(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		printUsage(stderr)
+		return 2
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		_, _ = fmt.Fprintf(stderr, "unknown command %q\n", args[0])
+		printUsage(stderr)
+		return 2
+	}
+	return cmd.run(args[1:], stdin, stdout, stderr)
+}
+
+func printUsage(out io.Writer) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	_, _ = fmt.Fprintln(out, "usage: monkey <command> [arguments]")
+	_, _ = fmt.Fprintln(out, "commands:")
+	for _, name := range names {
+		_, _ = fmt.Fprintf(out, "    %s\n", commands[name].usage)
+	}
+}
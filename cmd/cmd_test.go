@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFmt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.mk")
+	assert.NoError(t, os.WriteFile(path, []byte("let x=1"), 0644))
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"fmt", path}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "let x = 1;\n", stdout.String())
+
+	stdout.Reset()
+	code = Run([]string{"fmt", "-w", path}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "", stdout.String())
+	src, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, "let x = 1;\n", string(src))
+}
+
+func TestFmtStdin(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"fmt"}, strings.NewReader("puts( 1 )"), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "puts(1);\n", stdout.String())
+
+	stdout.Reset()
+	code = Run([]string{"fmt"}, strings.NewReader("let = 1"), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "<standard input>")
+}
+
+func TestUnknownCommand(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"bogus"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 2, code)
+	assert.Contains(t, stderr.String(), `unknown command "bogus"`)
+}
+
+func TestAst(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "main.mk")
+	assert.NoError(t, os.WriteFile(path, []byte("let x = 1;"), 0644))
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"ast", path}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), `"version": 1`)
+	assert.Contains(t, stdout.String(), `"kind": "LetStatement"`)
+
+	stdout.Reset()
+	code = Run([]string{"ast"}, strings.NewReader("let = 1"), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), "<standard input>: ")
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	lib := filepath.Join(dir, "lib")
+	assert.NoError(t, os.Mkdir(lib, 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(lib, "greet.mk"), []byte(`let hello = fn(name) { "hello " + name };`), 0644))
+	main := filepath.Join(dir, "main.mk")
+	assert.NoError(t, os.WriteFile(main, []byte(`import "greet.mk" as greet; puts(greet.hello("you"));`), 0644))
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"run", "-path", lib, main}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Equal(t, "hello you\n", stdout.String())
+
+	stdout.Reset()
+	code = Run([]string{"run", main}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stderr.String(), `Error: cannot find module "greet.mk"`)
+
+	code = Run([]string{"run"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 2, code)
+}
+
+func TestRunTrace(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.mk")
+	assert.NoError(t, os.WriteFile(main, []byte(`let f = fn(x) { len(x) }; f("ab");`), 0644))
+	traceFile := filepath.Join(dir, "trace.json")
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"run", "-trace", traceFile, main}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+
+	var trace struct {
+		TraceEvents []struct {
+			Name  string `json:"name"`
+			Phase string `json:"ph"`
+		} `json:"traceEvents"`
+	}
+	data, err := os.ReadFile(traceFile)
+	assert.NoError(t, err)
+	assert.NoError(t, json.Unmarshal(data, &trace))
+	var events []string
+	for _, event := range trace.TraceEvents {
+		events = append(events, event.Phase+" "+event.Name)
+	}
+	assert.Equal(t, []string{"B f", "B len", "E len", "E f"}, events)
+
+	code = Run([]string{"run", "-trace", filepath.Join(dir, "missing", "trace.json"), main}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+}
+
+func TestCheck(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.mk")
+	assert.NoError(t, os.WriteFile(good, []byte(`let add = fn(a: int, b: int) -> int { a + b }; add(1, 2);`), 0644))
+	bad := filepath.Join(dir, "bad.mk")
+	assert.NoError(t, os.WriteFile(bad, []byte("let x = \"a\" - 1;\nputs(y);"), 0644))
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"check", good}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "", stdout.String())
+
+	code = Run([]string{"check", good, bad}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, bad+":1:13: type mismatch: string - int\n"+bad+":2:6: identifier not found: y\n", stdout.String())
+
+	stdout.Reset()
+	code = Run([]string{"check"}, strings.NewReader("let = 1"), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout.String(), "<standard input>: ")
+}
+
+func TestLint(t *testing.T) {
+	dir := t.TempDir()
+	good := filepath.Join(dir, "good.mk")
+	assert.NoError(t, os.WriteFile(good, []byte(`let add = fn(a, b) { a + b }; add(1, 2);`), 0644))
+	bad := filepath.Join(dir, "bad.mk")
+	assert.NoError(t, os.WriteFile(bad, []byte("let len = 1;\nlet f = fn() { return 1; 2 }; // lint:ignore unreachable-code\nf(1)"), 0644))
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"lint", good}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "", stdout.String())
+
+	code = Run([]string{"lint", good, bad}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Equal(t, bad+":1:5: len shadows the builtin len (shadowed-builtin)\n"+
+		bad+":3:1: wrong number of arguments to f. got=1, want=0 (argument-count)\n", stdout.String())
+
+	stdout.Reset()
+	code = Run([]string{"lint"}, strings.NewReader("let = 1"), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout.String(), "<standard input>: ")
+}
+
+func TestDebug(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "main.mk")
+	assert.NoError(t, os.WriteFile(file, []byte("let x = 1;\nputs(x + 1);\nx + true;\n"), 0644))
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"debug", file}, strings.NewReader("b 3\nc\np x\nc\n"), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout.String(), "2\nStopped in main.mk at main.mk:3\n")
+	assert.Contains(t, stdout.String(), "(monkey) 1\n(monkey) Program finished\n")
+	assert.Equal(t, file+": Error: type mismatch: INTEGER + BOOLEAN\n", stderr.String())
+
+	code = Run([]string{"debug"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 2, code)
+}
+
+func TestProfile(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.mk")
+	assert.NoError(t, os.WriteFile(main, []byte("let f = fn(x) { len(x) };\nputs(f(\"ab\"));\n"), 0644))
+	output := filepath.Join(dir, "cpu.pprof")
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"profile", "-o", output, main}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.True(t, strings.HasPrefix(stdout.String(), "2\nDuration: "))
+	assert.Contains(t, stdout.String(), "  f main.mk:1\n")
+	assert.Contains(t, stdout.String(), "  main.mk:2\n")
+	info, err := os.Stat(output)
+	assert.NoError(t, err)
+	assert.Greater(t, info.Size(), int64(0))
+
+	assert.NoError(t, os.WriteFile(main, []byte("1 + true"), 0644))
+	stdout.Reset()
+	code = Run([]string{"profile", main}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout.String(), "Duration: ")
+
+	code = Run([]string{"profile"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 2, code)
+}
+
+func TestCover(t *testing.T) {
+	dir := t.TempDir()
+	main := filepath.Join(dir, "main.mk")
+	assert.NoError(t, os.WriteFile(main, []byte("let f = fn(x) { if (x) { 1 } else { 2 } };\nputs(f(true));\n"), 0644))
+	html := filepath.Join(dir, "coverage.html")
+	lcov := filepath.Join(dir, "coverage.lcov")
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"cover", "-html", html, "-lcov", lcov, main}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.True(t, strings.HasPrefix(stdout.String(), "1\nfile "))
+	assert.Contains(t, stdout.String(), "80.0% (4/5)         50.0% (1/2)        100.0% (1/1)\n")
+	data, err := os.ReadFile(html)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), `<span class="line partial"><span class="number">1</span>`)
+	data, err = os.ReadFile(lcov)
+	assert.NoError(t, err)
+	assert.Contains(t, string(data), "SF:"+main+"\n")
+	assert.Contains(t, string(data), "BRDA:1,0,1,0\n")
+
+	assert.NoError(t, os.WriteFile(main, []byte("1 + true"), 0644))
+	stdout.Reset()
+	code = Run([]string{"cover", main}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout.String(), "total ")
+
+	code = Run([]string{"cover"}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 2, code)
+}
+
+func TestTest(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "sub"), 0755))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "a_test.mk"), []byte("let test_a = fn() { assert_eq(1 + 1, 2) };\n"), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "sub", "b_test.mk"), []byte("let test_b = fn() { assert(false, \"b\") };\n"), 0644))
+
+	var stdout, stderr bytes.Buffer
+	code := Run([]string{"test", "-v", dir}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code, stderr.String())
+	assert.Contains(t, stdout.String(), "--- PASS: test_a (")
+	assert.Contains(t, stdout.String(), "PASS: 1 tests in 1 files\n")
+
+	stdout.Reset()
+	code = Run([]string{"test", dir + "/..."}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.Contains(t, stdout.String(), "    assertion failed: b: expected a truthy value, got false\n")
+	assert.Contains(t, stdout.String(), "FAIL: 1 of 2 tests failed in 2 files\n")
+
+	stdout.Reset()
+	code = Run([]string{"test", "-format", "tap", "-run", "_b", dir + "/..."}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 1, code)
+	assert.True(t, strings.HasPrefix(stdout.String(), "TAP version 13\n1..1\nnot ok 1 - "))
+
+	stdout.Reset()
+	code = Run([]string{"test", "-format", "junit", dir}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 0, code)
+	assert.Contains(t, stdout.String(), `<testsuites tests="1" failures="0" errors="0"`)
+
+	code = Run([]string{"test", "-format", "xml", dir}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 2, code)
+	code = Run([]string{"test", filepath.Join(dir, "missing")}, strings.NewReader(""), &stdout, &stderr)
+	assert.Equal(t, 2, code)
+}
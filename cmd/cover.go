@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"monkey_interpreter/coverage"
+	"monkey_interpreter/evaluator"
+	"monkey_interpreter/object"
+	"os"
+	"path/filepath"
+)
+
+func runCover(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("cover", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("path", os.Getenv("MONKEYPATH"), "list of directories searched for imports, separated by "+string(filepath.ListSeparator))
+	html := flags.String("html", "", "write the sources annotated with their coverage to `file`")
+	lcov := flags.String("lcov", "", "write an LCOV tracefile to `file`")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		_, _ = fmt.Fprintln(stderr, "usage: monkey cover [-path dirs] [-html file] [-lcov file] file")
+		return 2
+	}
+
+	ctx := evaluator.NewContext(stdin, stdout, stderr)
+	if *path != "" {
+		ctx.SearchPath = filepath.SplitList(*path)
+	}
+	collector := coverage.Start(ctx)
+	res := ctx.EvalFile(flags.Arg(0), object.NewEnvironment())
+	cov := collector.Stop()
+
+	code := 0
+	if err, ok := res.(*object.Error); ok {
+		_, _ = fmt.Fprintf(stderr, "%s: %s\n", flags.Arg(0), err.Inspect())
+		code = 1
+	}
+	dir, _ := os.Getwd()
+	if err := cov.WriteSummary(stdout, dir); err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if *html != "" {
+		if err := writeCoverage(*html, func(w io.Writer) error { return cov.WriteHTML(w, dir) }); err != nil {
+			_, _ = fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+	if *lcov != "" {
+		if err := writeCoverage(*lcov, cov.WriteLCOV); err != nil {
+			_, _ = fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+	return code
+}
+
+func writeCoverage(path string, write func(io.Writer) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
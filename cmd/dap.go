@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"monkey_interpreter/dap"
+	"os"
+	"path/filepath"
+)
+
+func runDap(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("dap", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("path", os.Getenv("MONKEYPATH"), "list of directories searched for imports, separated by "+string(filepath.ListSeparator))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 0 {
+		_, _ = fmt.Fprintln(stderr, "usage: monkey dap [-path dirs]")
+		return 2
+	}
+
+	server := dap.NewServer(stdin, stdout)
+	if *path != "" {
+		server.SearchPath = filepath.SplitList(*path)
+	}
+	if err := server.Run(); err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"monkey_interpreter/debugger"
+	"monkey_interpreter/evaluator"
+	"monkey_interpreter/object"
+	"os"
+	"path/filepath"
+)
+
+func runDebug(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("debug", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("path", os.Getenv("MONKEYPATH"), "list of directories searched for imports, separated by "+string(filepath.ListSeparator))
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		_, _ = fmt.Fprintln(stderr, "usage: monkey debug [-path dirs] file")
+		return 2
+	}
+
+	// The program and the debugger share the input, so they must share its buffer too
+	ctx := evaluator.NewContext(stdin, stdout, stderr)
+	if *path != "" {
+		ctx.SearchPath = filepath.SplitList(*path)
+	}
+	res := debugger.New(ctx.Stdin, stdout).Run(ctx, flags.Arg(0))
+	if err, ok := res.(*object.Error); ok {
+		_, _ = fmt.Fprintf(stderr, "%s: %s\n", flags.Arg(0), err.Inspect())
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"monkey_interpreter/formatter"
+	"os"
+)
+
+func runFmt(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("fmt", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	write := flags.Bool("w", false, "write the result to the source file instead of standard output")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		if *write {
+			_, _ = fmt.Fprintln(stderr, "cannot use -w with standard input")
+			return 2
+		}
+		src, err := io.ReadAll(stdin)
+		if err != nil {
+			_, _ = fmt.Fprintln(stderr, err)
+			return 1
+		}
+		out, err := formatter.Format(string(src))
+		if err != nil {
+			_, _ = fmt.Fprintf(stderr, "<standard input>: %s\n", err)
+			return 1
+		}
+		_, _ = io.WriteString(stdout, out)
+		return 0
+	}
+
+	status := 0
+	for _, path := range flags.Args() {
+		if err := formatFile(path, *write, stdout); err != nil {
+			_, _ = fmt.Fprintf(stderr, "%s: %s\n", path, err)
+			status = 1
+		}
+	}
+	return status
+}
+
+func formatFile(path string, write bool, stdout io.Writer) error {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	out, err := formatter.Format(string(src))
+	if err != nil {
+		return err
+	}
+	if !write {
+		_, err = io.WriteString(stdout, out)
+		return err
+	}
+	if out == string(src) {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(out), info.Mode().Perm())
+}
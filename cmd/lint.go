@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"monkey_interpreter/evaluator"
+	"monkey_interpreter/lexer"
+	"monkey_interpreter/lint"
+	"monkey_interpreter/parser"
+	"os"
+)
+
+func runLint(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	builtins := evaluator.NewContext(stdin, stdout, stderr).BuiltinNames()
+	if len(args) == 0 {
+		src, err := io.ReadAll(stdin)
+		if err != nil {
+			_, _ = fmt.Fprintln(stderr, err)
+			return 1
+		}
+		return lintSource("<standard input>", string(src), builtins, stdout)
+	}
+
+	status := 0
+	for _, path := range args {
+		src, err := os.ReadFile(path)
+		if err != nil {
+			_, _ = fmt.Fprintln(stderr, err)
+			status = 1
+			continue
+		}
+		if code := lintSource(path, string(src), builtins, stdout); code != 0 {
+			status = code
+		}
+	}
+	return status
+}
+
+// lintSource prints the diagnostics of a file, or its parse errors since the linter needs a tree
+func lintSource(name, src string, builtins []string, stdout io.Writer) int {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Error()) > 0 {
+		for _, msg := range p.Error() {
+			_, _ = fmt.Fprintf(stdout, "%s: %s\n", name, msg)
+		}
+		return 1
+	}
+
+	diagnostics := lint.Lint(program, builtins)
+	for _, d := range diagnostics {
+		_, _ = fmt.Fprintf(stdout, "%s:%s\n", name, d)
+	}
+	if len(diagnostics) > 0 {
+		return 1
+	}
+	return 0
+}
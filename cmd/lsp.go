@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"monkey_interpreter/evaluator"
+	"monkey_interpreter/lsp"
+)
+
+func runLsp(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	if len(args) != 0 {
+		_, _ = fmt.Fprintln(stderr, "usage: monkey lsp")
+		return 2
+	}
+	builtins := evaluator.NewContext(stdin, stdout, stderr).BuiltinNames()
+	if err := lsp.NewServer(stdin, stdout, builtins).Run(); err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"monkey_interpreter/evaluator"
+	"monkey_interpreter/object"
+	"monkey_interpreter/profile"
+	"os"
+	"path/filepath"
+)
+
+func runProfile(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("profile", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("path", os.Getenv("MONKEYPATH"), "list of directories searched for imports, separated by "+string(filepath.ListSeparator))
+	output := flags.String("o", "", "write a pprof profile to `file`")
+	top := flags.Int("n", 20, "number of functions and lines in the report, all of them if not positive")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		_, _ = fmt.Fprintln(stderr, "usage: monkey profile [-path dirs] [-o file] [-n count] file")
+		return 2
+	}
+
+	ctx := evaluator.NewContext(stdin, stdout, stderr)
+	if *path != "" {
+		ctx.SearchPath = filepath.SplitList(*path)
+	}
+	profiler := profile.Start(ctx)
+	res := ctx.EvalFile(flags.Arg(0), object.NewEnvironment())
+	prof := profiler.Stop()
+
+	code := 0
+	if err, ok := res.(*object.Error); ok {
+		_, _ = fmt.Fprintf(stderr, "%s: %s\n", flags.Arg(0), err.Inspect())
+		code = 1
+	}
+	if err := prof.WriteReport(stdout, *top); err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if *output != "" {
+		if err := writePprof(prof, *output); err != nil {
+			_, _ = fmt.Fprintln(stderr, err)
+			return 1
+		}
+	}
+	return code
+}
+
+func writePprof(prof *profile.Profile, path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := prof.WritePprof(f); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"monkey_interpreter/evaluator"
+	"monkey_interpreter/object"
+	"monkey_interpreter/trace"
+	"os"
+	"path/filepath"
+)
+
+func runRun(args []string, stdin io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("path", os.Getenv("MONKEYPATH"), "list of directories searched for imports, separated by "+string(filepath.ListSeparator))
+	traceFile := flags.String("trace", "", "write a Chrome trace of the function calls to `file`")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() != 1 {
+		_, _ = fmt.Fprintln(stderr, "usage: monkey run [-path dirs] [-trace file] file")
+		return 2
+	}
+
+	ctx := evaluator.NewContext(stdin, stdout, stderr)
+	if *path != "" {
+		ctx.SearchPath = filepath.SplitList(*path)
+	}
+	var closeTrace func() error
+	if *traceFile != "" {
+		f, err := os.Create(*traceFile)
+		if err != nil {
+			_, _ = fmt.Fprintln(stderr, err)
+			return 1
+		}
+		w := bufio.NewWriter(f)
+		tracer := trace.NewChrome(w)
+		ctx.Tracer = tracer
+		closeTrace = func() error {
+			err := tracer.Close()
+			if err == nil {
+				err = w.Flush()
+			}
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			return err
+		}
+	}
+
+	code := 0
+	if res, ok := ctx.EvalFile(flags.Arg(0), object.NewEnvironment()).(*object.Error); ok {
+		_, _ = fmt.Fprintf(stderr, "%s: %s\n", flags.Arg(0), res.Inspect())
+		code = 1
+	}
+	if closeTrace != nil {
+		if err := closeTrace(); err != nil {
+			_, _ = fmt.Fprintf(stderr, "%s: %s\n", *traceFile, err)
+			return 1
+		}
+	}
+	return code
+}
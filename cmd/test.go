@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"monkey_interpreter/tester"
+	"os"
+	"path/filepath"
+	"regexp"
+)
+
+func runTest(args []string, _ io.Reader, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	path := flags.String("path", os.Getenv("MONKEYPATH"), "list of directories searched for imports, separated by "+string(filepath.ListSeparator))
+	run := flags.String("run", "", "only run the tests whose name matches `regexp`")
+	verbose := flags.Bool("v", false, "list the tests that passed too")
+	format := flags.String("format", "text", "write the results as text, tap or junit")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "text" && *format != "tap" && *format != "junit" {
+		_, _ = fmt.Fprintf(stderr, "unknown format %q, want text, tap or junit\n", *format)
+		return 2
+	}
+
+	runner := &tester.Runner{}
+	if *path != "" {
+		runner.SearchPath = filepath.SplitList(*path)
+	}
+	if *run != "" {
+		re, err := regexp.Compile(*run)
+		if err != nil {
+			_, _ = fmt.Fprintln(stderr, err)
+			return 2
+		}
+		runner.Run = re
+	}
+
+	patterns := flags.Args()
+	if len(patterns) == 0 {
+		patterns = []string{"."}
+	}
+	paths, err := tester.Find(patterns)
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 2
+	}
+	var files []*tester.File
+	for _, path := range paths {
+		files = append(files, runner.RunFile(path))
+	}
+
+	switch *format {
+	case "tap":
+		err = tester.WriteTAP(stdout, files)
+	case "junit":
+		err = tester.WriteJUnit(stdout, files)
+	default:
+		err = tester.WriteText(stdout, files, *verbose)
+	}
+	if err != nil {
+		_, _ = fmt.Fprintln(stderr, err)
+		return 1
+	}
+	if s := tester.Summarize(files); s.Failed+s.Errors > 0 {
+		return 1
+	}
+	return 0
+}
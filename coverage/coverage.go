@@ -0,0 +1,191 @@
+// Package coverage records which statements, branches of if expressions and functions of the
+// files a Monkey program evaluates were run, and reports it as per file percentages, an annotated
+// HTML view of the sources or LCOV tracefiles.
+package coverage
+
+import (
+	"fmt"
+	"monkey_interpreter/ast"
+	"monkey_interpreter/evaluator"
+	"monkey_interpreter/object"
+	"os"
+	"sort"
+)
+
+type Statement struct {
+	Line, Column int
+	Hits         int
+}
+
+// Branch is the consequence or the alternative of an if expression. An if without an alternative
+// has an implicit one, taken when its condition is false
+type Branch struct {
+	Line, Column int // of the if expression
+	Block        int // index of the if expression in its file
+	Index        int // 0 for the consequence and 1 for the alternative
+	Hits         int
+}
+
+type Function struct {
+	Name string // the let the function is bound to, or fn@line for anonymous ones
+	Line int
+	Hits int
+}
+
+// File is the coverage of a file, its statements, branches and functions in source order
+type File struct {
+	Path       string // absolute path, empty for code outside of files
+	Source     []byte
+	Statements []*Statement
+	Branches   []*Branch
+	Functions  []*Function
+}
+
+// Coverage is the coverage of the files of an evaluation by path
+type Coverage struct {
+	Files []*File
+}
+
+// ifExpression is an if being evaluated
+type ifExpression struct {
+	node  *ast.IfExpression
+	taken bool // whether one of its blocks was entered
+}
+
+// Collector records the coverage of the files evaluated by a context
+type Collector struct {
+	ctx        *evaluator.Context
+	files      map[string]*File
+	statements map[ast.Statement]*Statement
+	branches   map[*ast.BlockStatement]*Branch
+	implicit   map[*ast.IfExpression]*Branch // the alternatives of ifs without one
+	functions  map[*ast.BlockStatement]*Function
+	ifs        []*ifExpression
+}
+
+// Start records the coverage of the files evaluated by ctx until Stop is called
+func Start(ctx *evaluator.Context) *Collector {
+	c := &Collector{
+		ctx:        ctx,
+		files:      make(map[string]*File),
+		statements: make(map[ast.Statement]*Statement),
+		branches:   make(map[*ast.BlockStatement]*Branch),
+		implicit:   make(map[*ast.IfExpression]*Branch),
+		functions:  make(map[*ast.BlockStatement]*Function),
+	}
+	ctx.Tracer = c
+	return c
+}
+
+// Stop ends the recording and returns the coverage of the files evaluated
+func (c *Collector) Stop() *Coverage {
+	c.ctx.Tracer = nil
+	cov := &Coverage{Files: make([]*File, 0, len(c.files))}
+	for _, f := range c.files {
+		cov.Files = append(cov.Files, f)
+	}
+	sort.Slice(cov.Files, func(i, j int) bool {
+		return cov.Files[i].Path < cov.Files[j].Path
+	})
+	return cov
+}
+
+func (c *Collector) Enter(node ast.Node) {
+	switch node := node.(type) {
+	case *ast.Program:
+		c.register(node)
+	case *ast.IfExpression:
+		c.ifs = append(c.ifs, &ifExpression{node: node})
+	case *ast.BlockStatement:
+		if b, ok := c.branches[node]; ok {
+			b.Hits++
+			if len(c.ifs) > 0 {
+				c.ifs[len(c.ifs)-1].taken = true
+			}
+		}
+	case ast.Statement:
+		if s, ok := c.statements[node]; ok {
+			s.Hits++
+		}
+	}
+}
+
+func (c *Collector) Exit(node ast.Node, result object.Object) {
+	n, ok := node.(*ast.IfExpression)
+	if !ok {
+		return
+	}
+	top := c.ifs[len(c.ifs)-1]
+	c.ifs = c.ifs[:len(c.ifs)-1]
+	if _, failed := result.(*object.Error); !top.taken && !failed {
+		if b, ok := c.implicit[n]; ok {
+			b.Hits++
+		}
+	}
+}
+
+func (c *Collector) Call(_ string, fn *object.Function, _ []object.Object) {
+	if f, ok := c.functions[fn.Body]; ok {
+		f.Hits++
+	}
+}
+
+func (c *Collector) Return(string, *object.Function, object.Object) {}
+
+func (c *Collector) BuiltinCall(string, []object.Object) {}
+
+func (c *Collector) BuiltinReturn(string, object.Object) {}
+
+// register adds the statements, branches and functions of the program of a file being evaluated
+func (c *Collector) register(program *ast.Program) {
+	path := c.ctx.File()
+	if _, ok := c.files[path]; ok {
+		return
+	}
+	f := &File{Path: path}
+	if path != "" {
+		f.Source, _ = os.ReadFile(path)
+	}
+	c.files[path] = f
+
+	names := make(map[*ast.FunctionLiteral]string)
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			if fn, ok := node.Value.(*ast.FunctionLiteral); ok {
+				names[fn] = node.Name.Value
+			}
+		case *ast.IfExpression:
+			block := len(f.Branches) / 2
+			then := &Branch{Line: node.Token.Line, Column: node.Token.Column, Block: block}
+			otherwise := &Branch{Line: node.Token.Line, Column: node.Token.Column, Block: block, Index: 1}
+			c.branches[node.Consequence] = then
+			if node.Alternative != nil {
+				c.branches[node.Alternative] = otherwise
+			} else {
+				c.implicit[node] = otherwise
+			}
+			f.Branches = append(f.Branches, then, otherwise)
+		case *ast.FunctionLiteral:
+			name, ok := names[node]
+			if !ok {
+				name = fmt.Sprintf("fn@%d", node.Token.Line)
+			}
+			fn := &Function{Name: name, Line: node.Token.Line}
+			c.functions[node.Body] = fn
+			f.Functions = append(f.Functions, fn)
+		}
+		// Blocks are not run as statements, the statements in them are
+		if _, ok := node.(*ast.BlockStatement); ok {
+			return true
+		}
+		if stmt, ok := node.(ast.Statement); ok {
+			if tok := ast.StartToken(stmt); tok.Line > 0 {
+				s := &Statement{Line: tok.Line, Column: tok.Column}
+				c.statements[stmt] = s
+				f.Statements = append(f.Statements, s)
+			}
+		}
+		return true
+	})
+}
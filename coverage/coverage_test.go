@@ -0,0 +1,157 @@
+package coverage
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"monkey_interpreter/evaluator"
+	"monkey_interpreter/object"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// cover evaluates main.mk of files and returns the coverage and the directory of the files
+func cover(t *testing.T, files map[string]string) (*Coverage, string) {
+	dir := t.TempDir()
+	for name, src := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(src), 0644))
+	}
+	ctx := evaluator.NewContext(strings.NewReader(""), io.Discard, io.Discard)
+	c := Start(ctx)
+	res := ctx.EvalFile(filepath.Join(dir, "main.mk"), object.NewEnvironment())
+	assert.NotEqual(t, object.ErrorObj, res.Type(), res.Inspect())
+	return c.Stop(), dir
+}
+
+const (
+	libSource  = "let abs = fn(x) {\n  if (x < 0) { -x } else { x }\n};\nlet unused = fn() { 1 };\n"
+	mainSource = `import "./lib.mk" as lib;
+let sign = fn(x) {
+  if (x > 0) { return 1; }
+  if (x < 0) { return -1; }
+  0
+};
+sign(2);
+lib.abs(3);
+`
+)
+
+func TestCoverage(t *testing.T) {
+	cov, dir := cover(t, map[string]string{"lib.mk": libSource, "main.mk": mainSource})
+	assert.Len(t, cov.Files, 2)
+
+	lib := cov.Files[0]
+	assert.Equal(t, filepath.Join(dir, "lib.mk"), lib.Path)
+	assert.Equal(t, libSource, string(lib.Source))
+	assert.Equal(t, []*Statement{
+		{Line: 1, Column: 1, Hits: 1},
+		{Line: 2, Column: 3, Hits: 1},
+		{Line: 2, Column: 16, Hits: 0},
+		{Line: 2, Column: 28, Hits: 1},
+		{Line: 4, Column: 1, Hits: 1},
+		{Line: 4, Column: 21, Hits: 0},
+	}, lib.Statements)
+	assert.Equal(t, []*Branch{
+		{Line: 2, Column: 3, Block: 0, Index: 0, Hits: 0},
+		{Line: 2, Column: 3, Block: 0, Index: 1, Hits: 1},
+	}, lib.Branches)
+	assert.Equal(t, []*Function{
+		{Name: "abs", Line: 1, Hits: 1},
+		{Name: "unused", Line: 4, Hits: 0},
+	}, lib.Functions)
+
+	main := cov.Files[1]
+	// The implicit alternatives are taken when the conditions are false
+	assert.Equal(t, []*Branch{
+		{Line: 3, Column: 3, Block: 0, Index: 0, Hits: 1},
+		{Line: 3, Column: 3, Block: 0, Index: 1, Hits: 0},
+		{Line: 4, Column: 3, Block: 1, Index: 0, Hits: 0},
+		{Line: 4, Column: 3, Block: 1, Index: 1, Hits: 0},
+	}, main.Branches)
+	statements, branches, functions := main.Counts()
+	assert.Equal(t, Count{Covered: 6, Total: 9}, statements)
+	assert.Equal(t, Count{Covered: 1, Total: 4}, branches)
+	assert.Equal(t, Count{Covered: 1, Total: 1}, functions)
+	assert.Equal(t, []string{"", "covered", "covered", "partial", "uncovered", "uncovered", "", "covered", "covered", ""}, main.lines())
+}
+
+func TestImplicitBranch(t *testing.T) {
+	cov, _ := cover(t, map[string]string{
+		"main.mk": "let f = fn(x) { if (x) { 1 } };\nf(false);\nf(false);\nf(true);\n",
+	})
+	assert.Equal(t, []*Branch{
+		{Line: 1, Column: 17, Block: 0, Index: 0, Hits: 1},
+		{Line: 1, Column: 17, Block: 0, Index: 1, Hits: 2},
+	}, cov.Files[0].Branches)
+}
+
+func TestWriteSummary(t *testing.T) {
+	cov, dir := cover(t, map[string]string{"lib.mk": libSource, "main.mk": mainSource})
+	var b bytes.Buffer
+	assert.NoError(t, cov.WriteSummary(&b, dir))
+	assert.Equal(t, `file             statements            branches           functions
+lib.mk          66.7% (4/6)         50.0% (1/2)         50.0% (1/2)
+main.mk         66.7% (6/9)         25.0% (1/4)        100.0% (1/1)
+total         66.7% (10/15)         33.3% (2/6)         66.7% (2/3)
+`, b.String())
+}
+
+func TestWriteLCOV(t *testing.T) {
+	cov, dir := cover(t, map[string]string{"lib.mk": libSource, "main.mk": mainSource})
+	var b bytes.Buffer
+	assert.NoError(t, cov.WriteLCOV(&b))
+	assert.Equal(t, `TN:
+SF:`+filepath.Join(dir, "lib.mk")+`
+FN:1,abs
+FN:4,unused
+FNDA:1,abs
+FNDA:0,unused
+FNF:2
+FNH:1
+BRDA:2,0,0,0
+BRDA:2,0,1,1
+BRF:2
+BRH:1
+DA:1,1
+DA:2,1
+DA:4,1
+LF:3
+LH:3
+end_of_record
+SF:`+filepath.Join(dir, "main.mk")+`
+FN:2,sign
+FNDA:1,sign
+FNF:1
+FNH:1
+BRDA:3,0,0,1
+BRDA:3,0,1,0
+BRDA:4,1,0,-
+BRDA:4,1,1,-
+BRF:4
+BRH:1
+DA:1,1
+DA:2,1
+DA:3,1
+DA:4,0
+DA:5,0
+DA:7,1
+DA:8,1
+LF:7
+LH:5
+end_of_record
+`, b.String())
+}
+
+func TestWriteHTML(t *testing.T) {
+	cov, dir := cover(t, map[string]string{"lib.mk": libSource, "main.mk": mainSource})
+	var b bytes.Buffer
+	assert.NoError(t, cov.WriteHTML(&b, dir))
+	html := b.String()
+	assert.Contains(t, html, `<option value="file1">main.mk (66.7% (6/9) statements, 25.0% (1/4) branches)</option>`)
+	assert.Contains(t, html, `<span class="line uncovered"><span class="number">4</span>  if (x &lt; 0) { return -1; }</span>`)
+	assert.Contains(t, html, `<span class="line partial"><span class="number">3</span>  if (x &gt; 0) { return 1; }</span>`)
+	assert.Contains(t, html, `<span class="line"><span class="number">6</span>};</span>`)
+	assert.Contains(t, html, `<span class="line partial"><span class="number">4</span>let unused = fn() { 1 };</span>`)
+}
@@ -0,0 +1,82 @@
+package coverage
+
+import (
+	"html/template"
+	"io"
+	"strings"
+)
+
+var htmlTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Monkey coverage</title>
+<style>
+body { background: #fff; color: #222; font-family: sans-serif; margin: 0; }
+#topbar { background: #eee; padding: 8px; position: sticky; top: 0; }
+#topbar span { margin-left: 16px; }
+pre { font-family: monospace; margin: 0; padding: 8px; }
+.line { display: block; }
+.number { color: #999; display: inline-block; padding-right: 12px; text-align: right; user-select: none; width: 4em; }
+.covered { background: #d8f5d8; }
+.partial { background: #f8efc6; }
+.uncovered { background: #f8d7d7; }
+</style>
+</head>
+<body>
+<div id="topbar">
+<select id="files" onchange="show(this.value)">
+{{- range $i, $f := .}}
+<option value="file{{$i}}">{{$f.Name}} ({{$f.Statements}} statements, {{$f.Branches}} branches)</option>
+{{- end}}
+</select>
+<span class="covered">covered</span>
+<span class="partial">partially covered</span>
+<span class="uncovered">not covered</span>
+</div>
+{{- range $i, $f := .}}
+<pre class="file" id="file{{$i}}"{{if $i}} style="display: none"{{end}}>
+{{- range $f.Lines}}<span class="line{{with .Class}} {{.}}{{end}}"><span class="number">{{.Number}}</span>{{.Text}}</span>{{end -}}
+</pre>
+{{- end}}
+<script>
+function show(id) {
+	for (const file of document.querySelectorAll(".file")) {
+		file.style.display = file.id === id ? "block" : "none";
+	}
+}
+</script>
+</body>
+</html>
+`))
+
+type htmlFile struct {
+	Name                 string
+	Statements, Branches Count
+	Lines                []htmlLine
+}
+
+type htmlLine struct {
+	Number int
+	Text   string
+	Class  string
+}
+
+// WriteHTML writes the sources of the files with the lines colored by whether they were run, with
+// the paths relative to dir
+func (c *Coverage) WriteHTML(w io.Writer, dir string) error {
+	var files []htmlFile
+	for _, f := range c.Files {
+		if f.Source == nil {
+			continue
+		}
+		statements, branches, _ := f.Counts()
+		file := htmlFile{Name: f.Name(dir), Statements: statements, Branches: branches}
+		classes := f.lines()
+		for i, text := range strings.Split(strings.TrimSuffix(string(f.Source), "\n"), "\n") {
+			file.Lines = append(file.Lines, htmlLine{Number: i + 1, Text: text, Class: classes[i+1]})
+		}
+		files = append(files, file)
+	}
+	return htmlTemplate.Execute(w, files)
+}
@@ -0,0 +1,195 @@
+package coverage
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Counts returns how many statements, branches and functions of the file exist and were run
+func (f *File) Counts() (statements, branches, functions Count) {
+	for _, s := range f.Statements {
+		statements.add(s.Hits)
+	}
+	for _, b := range f.Branches {
+		branches.add(b.Hits)
+	}
+	for _, fn := range f.Functions {
+		functions.add(fn.Hits)
+	}
+	return statements, branches, functions
+}
+
+// Count is how many of something were run
+type Count struct {
+	Covered, Total int
+}
+
+func (c *Count) add(hits int) {
+	c.Total++
+	if hits > 0 {
+		c.Covered++
+	}
+}
+
+func (c *Count) plus(o Count) {
+	c.Covered += o.Covered
+	c.Total += o.Total
+}
+
+// String returns the percentage of what was run followed by the counts, or - if there is nothing
+func (c Count) String() string {
+	if c.Total == 0 {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%% (%d/%d)", float64(c.Covered)*100/float64(c.Total), c.Covered, c.Total)
+}
+
+// Line classes of the annotated sources
+const (
+	lineNone      = ""          // nothing starts on the line
+	lineCovered   = "covered"   // everything on the line was run
+	linePartial   = "partial"   // some statements or branches on the line were not run
+	lineUncovered = "uncovered" // nothing on the line was run
+)
+
+// lines returns the class of every line of the file, indexed from 1
+func (f *File) lines() []string {
+	n := strings.Count(string(f.Source), "\n") + 1
+	covered := make([]int, n+1)
+	total := make([]int, n+1)
+	count := func(line, hits int) {
+		if line < 1 || line > n {
+			return
+		}
+		total[line]++
+		if hits > 0 {
+			covered[line]++
+		}
+	}
+	for _, s := range f.Statements {
+		count(s.Line, s.Hits)
+	}
+	for _, b := range f.Branches {
+		count(b.Line, b.Hits)
+	}
+
+	classes := make([]string, n+1)
+	for line := 1; line <= n; line++ {
+		switch {
+		case total[line] == 0:
+			classes[line] = lineNone
+		case covered[line] == total[line]:
+			classes[line] = lineCovered
+		case covered[line] == 0:
+			classes[line] = lineUncovered
+		default:
+			classes[line] = linePartial
+		}
+	}
+	return classes
+}
+
+// Name returns the path of the file relative to dir when it is inside it
+func (f *File) Name(dir string) string {
+	if f.Path == "" {
+		return "<input>"
+	}
+	if dir != "" {
+		if rel, err := filepath.Rel(dir, f.Path); err == nil && !strings.HasPrefix(rel, "..") {
+			return rel
+		}
+	}
+	return f.Path
+}
+
+// WriteSummary writes the percentages of statements, branches and functions run per file and in
+// total, with the paths relative to dir
+func (c *Coverage) WriteSummary(w io.Writer, dir string) error {
+	var b strings.Builder
+	rows := [][4]string{{"file", "statements", "branches", "functions"}}
+	var statements, branches, functions Count
+	for _, f := range c.Files {
+		s, br, fn := f.Counts()
+		rows = append(rows, [4]string{f.Name(dir), s.String(), br.String(), fn.String()})
+		statements.plus(s)
+		branches.plus(br)
+		functions.plus(fn)
+	}
+	rows = append(rows, [4]string{"total", statements.String(), branches.String(), functions.String()})
+
+	width := 0
+	for _, row := range rows {
+		if len(row[0]) > width {
+			width = len(row[0])
+		}
+	}
+	for _, row := range rows {
+		fmt.Fprintf(&b, "%-*s  %18s  %18s  %18s\n", width, row[0], row[1], row[2], row[3])
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteLCOV writes the coverage as an LCOV tracefile, the format read by genhtml and most coverage
+// services
+func (c *Coverage) WriteLCOV(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	_, _ = fmt.Fprintln(bw, "TN:")
+	for _, f := range c.Files {
+		if f.Path == "" {
+			continue
+		}
+		_, _ = fmt.Fprintf(bw, "SF:%s\n", f.Path)
+
+		_, branches, functions := f.Counts()
+		for _, fn := range f.Functions {
+			_, _ = fmt.Fprintf(bw, "FN:%d,%s\n", fn.Line, fn.Name)
+		}
+		for _, fn := range f.Functions {
+			_, _ = fmt.Fprintf(bw, "FNDA:%d,%s\n", fn.Hits, fn.Name)
+		}
+		_, _ = fmt.Fprintf(bw, "FNF:%d\nFNH:%d\n", functions.Total, functions.Covered)
+
+		for i := 0; i+1 < len(f.Branches); i += 2 {
+			then, otherwise := f.Branches[i], f.Branches[i+1]
+			for _, br := range []*Branch{then, otherwise} {
+				// An if that never ran has - as the count of its branches
+				hits := "-"
+				if then.Hits+otherwise.Hits > 0 {
+					hits = strconv.Itoa(br.Hits)
+				}
+				_, _ = fmt.Fprintf(bw, "BRDA:%d,%d,%d,%s\n", br.Line, br.Block, br.Index, hits)
+			}
+		}
+		_, _ = fmt.Fprintf(bw, "BRF:%d\nBRH:%d\n", branches.Total, branches.Covered)
+
+		// The count of a line is the most any statement starting on it was run
+		var lines []int
+		hits := make(map[int]int)
+		for _, s := range f.Statements {
+			h, ok := hits[s.Line]
+			if !ok {
+				lines = append(lines, s.Line)
+			}
+			if !ok || s.Hits > h {
+				hits[s.Line] = s.Hits
+			}
+		}
+		sort.Ints(lines)
+		hit := 0
+		for _, line := range lines {
+			_, _ = fmt.Fprintf(bw, "DA:%d,%d\n", line, hits[line])
+			if hits[line] > 0 {
+				hit++
+			}
+		}
+		_, _ = fmt.Fprintf(bw, "LF:%d\nLH:%d\n", len(lines), hit)
+		_, _ = fmt.Fprintln(bw, "end_of_record")
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,185 @@
+package dap
+
+import (
+	"errors"
+	"fmt"
+	"monkey_interpreter/ast"
+	"monkey_interpreter/debugger"
+	"monkey_interpreter/evaluator"
+	"monkey_interpreter/lexer"
+	"monkey_interpreter/object"
+	"monkey_interpreter/parser"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// errQuit stops the program when the client disconnects
+var errQuit = errors.New("debugging session ended")
+
+// program is a launched file. It is the Debugger of the context it runs in
+type program struct {
+	s       *Server
+	ctx     *evaluator.Context
+	path    string
+	noDebug bool
+	resumed chan bool // receives whether to quit when the program is resumed
+	done    chan struct{}
+
+	// Guarded by the mutex of the server
+	stepper   debugger.Stepper
+	quitting  bool
+	stopped   []*evaluator.Frame // the stack while the program is stopped, nil while it runs
+	stoppedAt ast.Statement
+}
+
+func newProgram(s *Server, args *LaunchArguments) *program {
+	ctx := evaluator.NewContext(strings.NewReader(""), output{s, "stdout"}, output{s, "stderr"})
+	ctx.SearchPath = s.SearchPath
+	if len(args.SearchPath) > 0 {
+		ctx.SearchPath = args.SearchPath
+	}
+	p := &program{
+		s:       s,
+		ctx:     ctx,
+		path:    args.Program,
+		noDebug: args.NoDebug,
+		resumed: make(chan bool, 1),
+		done:    make(chan struct{}),
+		stepper: debugger.Stepper{Mode: debugger.Continue},
+	}
+	if args.StopOnEntry {
+		p.stepper.Mode = debugger.Entry
+	}
+	return p
+}
+
+// start runs the program in a goroutine, the client is told when it exits
+func (p *program) start() {
+	if !p.noDebug {
+		p.ctx.Debugger = p
+	}
+	go func() {
+		defer close(p.done)
+		code := 0
+		res := p.ctx.EvalFile(p.path, object.NewEnvironment())
+		if err, ok := res.(*object.Error); ok && !errors.Is(err.Err, errQuit) {
+			_ = p.s.event("output", OutputEventBody{Category: "stderr", Output: fmt.Sprintf("%s: %s\n", p.path, err.Inspect())})
+			code = 1
+		}
+		_ = p.s.event("exited", ExitedEventBody{ExitCode: code})
+		_ = p.s.event("terminated", nil)
+	}()
+}
+
+// Statement stops the program if a breakpoint is hit, a step ends or the client paused it, and
+// waits until the client resumes it
+func (p *program) Statement(c *evaluator.Context, stmt ast.Statement, env *object.Environment) error {
+	stack := c.Stack()
+	frame := stack[len(stack)-1]
+	line := ast.StartToken(stmt).Line
+
+	p.s.mu.Lock()
+	if p.quitting {
+		p.s.mu.Unlock()
+		return errQuit
+	}
+	reason := p.stepper.Stop(stack, line, p.s.breakpoints[frame.File][line])
+	if reason == "" {
+		p.s.mu.Unlock()
+		return nil
+	}
+	p.stopped, p.stoppedAt = stack, stmt
+	p.s.mu.Unlock()
+
+	_ = p.s.event("stopped", StoppedEventBody{Reason: reason, ThreadID: threadID, AllThreadsStopped: true})
+	if quit := <-p.resumed; quit {
+		return errQuit
+	}
+	return nil
+}
+
+// resume continues the stopped program in mode
+func (p *program) resume(m debugger.Mode) {
+	p.s.mu.Lock()
+	p.stepper.Resume(m, p.stopped)
+	p.stopped, p.stoppedAt = nil, nil
+	p.s.mu.Unlock()
+	p.resumed <- false
+}
+
+// pause stops the program at its next statement
+func (p *program) pause() {
+	p.s.mu.Lock()
+	defer p.s.mu.Unlock()
+	p.stepper.Mode = debugger.Pause
+}
+
+// quit stops the program at its next statement. A stopped program ends right away and is waited
+// for, a running one may be blocked in a builtin and never reach another statement
+func (p *program) quit() {
+	p.s.mu.Lock()
+	p.quitting = true
+	stopped := p.stopped != nil
+	p.stopped, p.stoppedAt = nil, nil
+	p.s.mu.Unlock()
+	if stopped {
+		p.resumed <- true
+		<-p.done
+	}
+}
+
+// stack returns the frames of the stopped program, innermost last, or nil if it is not stopped
+func (s *Server) stack() []*evaluator.Frame {
+	if s.program == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.program.stopped
+}
+
+// output sends what a program writes to a stream as output events
+type output struct {
+	s        *Server
+	category string
+}
+
+func (o output) Write(b []byte) (int, error) {
+	if err := o.s.event("output", OutputEventBody{Category: o.category, Output: string(b)}); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// statementLines parses a file and returns its absolute path and the lines statements start on
+func statementLines(path string) (string, map[int]bool, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return path, nil, err
+	}
+	src, err := os.ReadFile(abs)
+	if err != nil {
+		return abs, nil, err
+	}
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Error()) > 0 {
+		return abs, nil, errors.New(strings.Join(p.Error(), "; "))
+	}
+
+	lines := make(map[int]bool)
+	ast.Inspect(program, func(node ast.Node) bool {
+		// Blocks are not run as statements, the statements in them are
+		if _, ok := node.(*ast.BlockStatement); ok {
+			return true
+		}
+		if stmt, ok := node.(ast.Statement); ok {
+			if tok := ast.StartToken(stmt); tok.Line > 0 {
+				lines[tok.Line] = true
+			}
+		}
+		return true
+	})
+	return abs, lines, nil
+}
@@ -0,0 +1,276 @@
+// Package dap implements a Debug Adapter Protocol server for Monkey over the statement hook of the
+// evaluator. A client launches a single file, sets line breakpoints in it and the files it
+// imports, steps into, over and out of function calls, and inspects the stack, the variables of
+// the environment chain of each frame and the elements of arrays, hashes and modules. Variables
+// can be changed and expressions evaluated while the program is stopped.
+//
+// The program runs in a goroutine of its own while the server keeps answering requests. Its
+// standard output and error are sent to the client as output events.
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"monkey_interpreter/debugger"
+	"monkey_interpreter/wire"
+	"sync"
+)
+
+// Server answers the requests of a single client and runs the program it launches
+type Server struct {
+	in *bufio.Reader
+
+	writeMu sync.Mutex // guards out and seq, events are sent by the program too
+	out     io.Writer
+	seq     int
+
+	// SearchPath lists the directories imports are looked up in when the launch configuration
+	// does not set any
+	SearchPath []string
+
+	mu          sync.Mutex              // guards breakpoints and the state shared with program
+	breakpoints map[string]map[int]bool // lines by absolute path
+
+	initialized  bool
+	configured   bool
+	launch       *LaunchArguments
+	program      *program
+	disconnected bool
+	afterReply   []func() // sent or started once the response to the current request is written
+
+	// references are the variables references handed out since the program last stopped, a
+	// reference is its index plus one
+	references []interface{}
+}
+
+type handler func(s *Server, args json.RawMessage) (interface{}, error)
+
+var handlers = map[string]handler{
+	"initialize":        (*Server).initialize,
+	"launch":            (*Server).launchRequest,
+	"setBreakpoints":    (*Server).setBreakpoints,
+	"configurationDone": (*Server).configurationDone,
+	"threads":           (*Server).threads,
+	"continue":          (*Server).continueRequest,
+	"next":              (*Server).next,
+	"stepIn":            (*Server).stepIn,
+	"stepOut":           (*Server).stepOut,
+	"pause":             (*Server).pause,
+	"stackTrace":        (*Server).stackTrace,
+	"scopes":            (*Server).scopes,
+	"variables":         (*Server).variables,
+	"setVariable":       (*Server).setVariable,
+	"evaluate":          (*Server).evaluate,
+	"disconnect":        (*Server).disconnect,
+}
+
+// The single thread programs run in
+const threadID = 1
+
+func NewServer(in io.Reader, out io.Writer) *Server {
+	return &Server{
+		in:          bufio.NewReader(in),
+		out:         out,
+		breakpoints: make(map[string]map[int]bool),
+	}
+}
+
+// Run serves requests until the client disconnects or closes the input. The result is nil if the
+// client disconnected
+func (s *Server) Run() error {
+	for {
+		body, err := wire.ReadMessage(s.in)
+		if err != nil {
+			s.stopProgram()
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			return fmt.Errorf("invalid message: %s", err)
+		}
+		if req.Type != "request" {
+			continue
+		}
+
+		result, err := s.handle(&req)
+		if err := s.reply(&req, result, err); err != nil {
+			return err
+		}
+		for _, f := range s.afterReply {
+			f()
+		}
+		s.afterReply = nil
+		if s.disconnected {
+			return nil
+		}
+	}
+}
+
+func (s *Server) handle(req *request) (result interface{}, err error) {
+	h, ok := handlers[req.Command]
+	if !ok {
+		return nil, fmt.Errorf("command not supported: %s", req.Command)
+	}
+	if !s.initialized && req.Command != "initialize" {
+		return nil, errors.New("server not initialized")
+	}
+	defer func() {
+		// A bug in a feature should not take the debugging session down
+		if r := recover(); r != nil {
+			result, err = nil, fmt.Errorf("%v", r)
+		}
+	}()
+	return h(s, req.Arguments)
+}
+
+func (s *Server) reply(req *request, body interface{}, err error) error {
+	resp := &response{Type: "response", RequestSeq: req.Seq, Success: err == nil, Command: req.Command, Body: body}
+	if err != nil {
+		resp.Body, resp.Message = nil, err.Error()
+	}
+	return s.send(func(seq int) interface{} {
+		resp.Seq = seq
+		return resp
+	})
+}
+
+func (s *Server) event(name string, body interface{}) error {
+	return s.send(func(seq int) interface{} {
+		return &event{Seq: seq, Type: "event", Event: name, Body: body}
+	})
+}
+
+// send writes the message made with the next sequence number
+func (s *Server) send(message func(seq int) interface{}) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	s.seq++
+	return wire.WriteMessage(s.out, message(s.seq))
+}
+
+func (s *Server) initialize(json.RawMessage) (interface{}, error) {
+	s.initialized = true
+	s.afterReply = append(s.afterReply, func() {
+		_ = s.event("initialized", nil)
+	})
+	return Capabilities{
+		SupportsConfigurationDoneRequest: true,
+		SupportsEvaluateForHovers:        true,
+		SupportsSetVariable:              true,
+	}, nil
+}
+
+// launchRequest starts the program once the client is done configuring breakpoints
+func (s *Server) launchRequest(raw json.RawMessage) (interface{}, error) {
+	var args LaunchArguments
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	if s.launch != nil {
+		return nil, errors.New("a program is already launched")
+	}
+	if args.Program == "" {
+		return nil, errors.New("the launch configuration has no program")
+	}
+	s.launch = &args
+	s.startWhenReady()
+	return nil, nil
+}
+
+func (s *Server) configurationDone(json.RawMessage) (interface{}, error) {
+	s.configured = true
+	s.startWhenReady()
+	return nil, nil
+}
+
+func (s *Server) startWhenReady() {
+	if s.launch == nil || !s.configured || s.program != nil {
+		return
+	}
+	s.program = newProgram(s, s.launch)
+	s.afterReply = append(s.afterReply, s.program.start)
+}
+
+func (s *Server) setBreakpoints(raw json.RawMessage) (interface{}, error) {
+	var args SetBreakpointsArguments
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	path, lines, err := statementLines(args.Source.Path)
+
+	breakpoints := make([]Breakpoint, 0, len(args.Breakpoints))
+	set := make(map[int]bool)
+	for _, bp := range args.Breakpoints {
+		breakpoint := Breakpoint{Source: &args.Source, Line: bp.Line}
+		switch {
+		case err != nil:
+			breakpoint.Message = err.Error()
+		case !lines[bp.Line]:
+			breakpoint.Message = fmt.Sprintf("no statement starts on line %d", bp.Line)
+		default:
+			breakpoint.Verified = true
+			set[bp.Line] = true
+		}
+		breakpoints = append(breakpoints, breakpoint)
+	}
+
+	s.mu.Lock()
+	s.breakpoints[path] = set
+	s.mu.Unlock()
+	return SetBreakpointsResponseBody{Breakpoints: breakpoints}, nil
+}
+
+func (s *Server) threads(json.RawMessage) (interface{}, error) {
+	return ThreadsResponseBody{Threads: []Thread{{ID: threadID, Name: "main"}}}, nil
+}
+
+func (s *Server) continueRequest(json.RawMessage) (interface{}, error) {
+	return ContinueResponseBody{AllThreadsContinued: true}, s.resume(debugger.Continue)
+}
+
+func (s *Server) next(json.RawMessage) (interface{}, error) {
+	return nil, s.resume(debugger.Next)
+}
+
+func (s *Server) stepIn(json.RawMessage) (interface{}, error) {
+	return nil, s.resume(debugger.Step)
+}
+
+func (s *Server) stepOut(json.RawMessage) (interface{}, error) {
+	return nil, s.resume(debugger.Out)
+}
+
+// resume runs the stopped program until the step of mode ends
+func (s *Server) resume(m debugger.Mode) error {
+	if s.stack() == nil {
+		return errors.New("the program is not stopped")
+	}
+	s.references = nil
+	s.program.resume(m)
+	return nil
+}
+
+func (s *Server) pause(json.RawMessage) (interface{}, error) {
+	if s.program == nil {
+		return nil, errors.New("the program is not running")
+	}
+	s.program.pause()
+	return nil, nil
+}
+
+// disconnect ends the program and then the session
+func (s *Server) disconnect(json.RawMessage) (interface{}, error) {
+	s.stopProgram()
+	s.disconnected = true
+	return nil, nil
+}
+
+func (s *Server) stopProgram() {
+	if s.program != nil {
+		s.program.quit()
+	}
+}
@@ -0,0 +1,357 @@
+package dap
+
+import (
+	"bufio"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"monkey_interpreter/wire"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+type message struct {
+	Type       string          `json:"type"`
+	Event      string          `json:"event"`
+	RequestSeq int             `json:"request_seq"`
+	Success    bool            `json:"success"`
+	Message    string          `json:"message"`
+	Body       json.RawMessage `json:"body"`
+}
+
+// client talks to a server running in a goroutine, reading its messages in another one like an
+// editor does
+type client struct {
+	t        *testing.T
+	in       *io.PipeWriter
+	messages chan *message
+	seq      int
+	events   []*message // received but not waited for yet
+	result   chan error
+}
+
+func newClient(t *testing.T) *client {
+	inReader, inWriter := io.Pipe()
+	outReader, outWriter := io.Pipe()
+	c := &client{t: t, in: inWriter, messages: make(chan *message, 100), result: make(chan error, 1)}
+	go func() {
+		c.result <- NewServer(inReader, outWriter).Run()
+		_ = outWriter.Close()
+	}()
+	go func() {
+		defer close(c.messages)
+		out := bufio.NewReader(outReader)
+		for {
+			body, err := wire.ReadMessage(out)
+			if err != nil {
+				return
+			}
+			var msg message
+			assert.NoError(t, json.Unmarshal(body, &msg))
+			c.messages <- &msg
+		}
+	}()
+	return c
+}
+
+func (c *client) read() *message {
+	msg, ok := <-c.messages
+	if !ok {
+		c.t.Fatal("the server closed its output")
+	}
+	return msg
+}
+
+// request sends a request and returns its response, keeping the events sent before it
+func (c *client) request(command string, args interface{}) *message {
+	c.seq++
+	assert.NoError(c.t, wire.WriteMessage(c.in, map[string]interface{}{"seq": c.seq, "type": "request", "command": command, "arguments": args}))
+	for {
+		msg := c.read()
+		if msg.Type == "response" {
+			assert.Equal(c.t, c.seq, msg.RequestSeq)
+			return msg
+		}
+		c.events = append(c.events, msg)
+	}
+}
+
+// call sends a request that must succeed and returns the body of its response
+func (c *client) call(command string, args interface{}) string {
+	resp := c.request(command, args)
+	assert.True(c.t, resp.Success, resp.Message)
+	return string(resp.Body)
+}
+
+// wait returns the next event with one of names, dropping the other events before it
+func (c *client) wait(names ...string) *message {
+	for {
+		var msg *message
+		if len(c.events) > 0 {
+			msg, c.events = c.events[0], c.events[1:]
+		} else {
+			msg = c.read()
+		}
+		for _, name := range names {
+			if msg.Event == name {
+				return msg
+			}
+		}
+	}
+}
+
+// exit waits for the program to end and returns its output by category and its exit code
+func (c *client) exit() (map[string]string, int) {
+	output := make(map[string]string)
+	for {
+		msg := c.wait("output", "exited")
+		if msg.Event == "exited" {
+			var body ExitedEventBody
+			assert.NoError(c.t, json.Unmarshal(msg.Body, &body))
+			c.wait("terminated")
+			return output, body.ExitCode
+		}
+		var body OutputEventBody
+		assert.NoError(c.t, json.Unmarshal(msg.Body, &body))
+		output[body.Category] += body.Output
+	}
+}
+
+// launch initializes the server and launches main.mk from files with breakpoints on lines of it
+func (c *client) launch(files map[string]string, args map[string]interface{}, lines ...int) string {
+	dir := c.t.TempDir()
+	for name, src := range files {
+		assert.NoError(c.t, os.WriteFile(filepath.Join(dir, name), []byte(src), 0644))
+	}
+	main := filepath.Join(dir, "main.mk")
+
+	c.call("initialize", map[string]interface{}{"adapterID": "monkey"})
+	c.wait("initialized")
+	if len(lines) > 0 {
+		breakpoints := make([]SourceBreakpoint, 0, len(lines))
+		for _, line := range lines {
+			breakpoints = append(breakpoints, SourceBreakpoint{Line: line})
+		}
+		c.call("setBreakpoints", SetBreakpointsArguments{Source: Source{Path: main}, Breakpoints: breakpoints})
+	}
+	args["program"] = main
+	c.call("launch", args)
+	c.call("configurationDone", nil)
+	return main
+}
+
+func (c *client) stopped(reason string) {
+	var body StoppedEventBody
+	assert.NoError(c.t, json.Unmarshal(c.wait("stopped").Body, &body))
+	assert.Equal(c.t, reason, body.Reason)
+}
+
+func (c *client) disconnect() {
+	c.call("disconnect", nil)
+	assert.NoError(c.t, <-c.result)
+}
+
+func TestLifecycle(t *testing.T) {
+	c := newClient(t)
+	resp := c.request("threads", nil)
+	assert.False(t, resp.Success)
+	assert.Equal(t, "server not initialized", resp.Message)
+
+	assert.JSONEq(t, `{"supportsConfigurationDoneRequest": true, "supportsEvaluateForHovers": true, "supportsSetVariable": true}`,
+		c.call("initialize", nil))
+	assert.Equal(t, "command not supported: attach", c.request("attach", nil).Message)
+	assert.Equal(t, "the program is not stopped", c.request("continue", ThreadArguments{ThreadID: threadID}).Message)
+	assert.Equal(t, "the launch configuration has no program", c.request("launch", map[string]interface{}{}).Message)
+	assert.JSONEq(t, `{"threads": [{"id": 1, "name": "main"}]}`, c.call("threads", nil))
+	c.disconnect()
+
+	assert.Error(t, NewServer(&io.LimitedReader{}, io.Discard).Run())
+}
+
+func TestSetBreakpoints(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "main.mk")
+	assert.NoError(t, os.WriteFile(file, []byte("let f = fn() {\n  1\n};\n\nf();\n"), 0644))
+
+	c := newClient(t)
+	c.call("initialize", nil)
+	source := Source{Path: file}
+	body := c.call("setBreakpoints", SetBreakpointsArguments{Source: source, Breakpoints: []SourceBreakpoint{{Line: 2}, {Line: 4}, {Line: 5}}})
+	assert.JSONEq(t, `{"breakpoints": [
+		{"verified": true, "source": {"path": "`+file+`"}, "line": 2},
+		{"verified": false, "message": "no statement starts on line 4", "source": {"path": "`+file+`"}, "line": 4},
+		{"verified": true, "source": {"path": "`+file+`"}, "line": 5}
+	]}`, body)
+
+	missing := filepath.Join(dir, "missing.mk")
+	body = c.call("setBreakpoints", SetBreakpointsArguments{Source: Source{Path: missing}, Breakpoints: []SourceBreakpoint{{Line: 1}}})
+	assert.Contains(t, body, `"verified":false,"message":"open `+missing+`: no such file or directory"`)
+	c.disconnect()
+}
+
+func TestDebugging(t *testing.T) {
+	files := map[string]string{
+		"lib.mk": "let double = fn(x) { x * 2 };\n",
+		"main.mk": `import "./lib.mk" as lib;
+let add = fn(a, b) {
+  let sum = a + b;
+  sum
+};
+let xs = [1, {"a": 2}];
+let y = add(1, 2);
+puts(lib.double(add(y, 10)));
+`,
+	}
+	c := newClient(t)
+	main := c.launch(files, map[string]interface{}{}, 3)
+	c.stopped("breakpoint")
+
+	assert.JSONEq(t, `{"stackFrames": [
+		{"id": 1, "name": "add", "source": {"name": "main.mk", "path": "`+main+`"}, "line": 3, "column": 3},
+		{"id": 2, "name": "main.mk", "source": {"name": "main.mk", "path": "`+main+`"}, "line": 7, "column": 1}
+	], "totalFrames": 2}`, c.call("stackTrace", StackTraceArguments{ThreadID: threadID}))
+	assert.JSONEq(t, `{"stackFrames": [
+		{"id": 2, "name": "main.mk", "source": {"name": "main.mk", "path": "`+main+`"}, "line": 7, "column": 1}
+	], "totalFrames": 2}`, c.call("stackTrace", StackTraceArguments{ThreadID: threadID, StartFrame: 1, Levels: 1}))
+
+	assert.JSONEq(t, `{"scopes": [
+		{"name": "Locals", "variablesReference": 1, "expensive": false},
+		{"name": "Globals", "variablesReference": 2, "expensive": false}
+	]}`, c.call("scopes", ScopesArguments{FrameID: 1}))
+	assert.JSONEq(t, `{"variables": [
+		{"name": "a", "value": "1", "type": "INTEGER", "variablesReference": 0},
+		{"name": "b", "value": "2", "type": "INTEGER", "variablesReference": 0}
+	]}`, c.call("variables", VariablesArguments{VariablesReference: 1}))
+	assert.JSONEq(t, `{"variables": [
+		{"name": "add", "value": "fn(a, b)", "type": "FUNCTION", "variablesReference": 0},
+		{"name": "lib", "value": "<module `+filepath.Join(filepath.Dir(main), "lib.mk")+`>", "type": "MODULE", "variablesReference": 3},
+		{"name": "xs", "value": "[1, {a : 2}]", "type": "ARRAY", "variablesReference": 4}
+	]}`, c.call("variables", VariablesArguments{VariablesReference: 2}))
+	assert.JSONEq(t, `{"variables": [
+		{"name": "double", "value": "fn(x)", "type": "FUNCTION", "variablesReference": 0}
+	]}`, c.call("variables", VariablesArguments{VariablesReference: 3}))
+	assert.JSONEq(t, `{"variables": [
+		{"name": "[0]", "value": "1", "type": "INTEGER", "variablesReference": 0},
+		{"name": "[1]", "value": "{a : 2}", "type": "HASH", "variablesReference": 5}
+	]}`, c.call("variables", VariablesArguments{VariablesReference: 4}))
+	assert.JSONEq(t, `{"variables": [
+		{"name": "a", "value": "2", "type": "INTEGER", "variablesReference": 0}
+	]}`, c.call("variables", VariablesArguments{VariablesReference: 5}))
+	assert.Equal(t, "invalid variables reference 6", c.request("variables", VariablesArguments{VariablesReference: 6}).Message)
+
+	assert.JSONEq(t, `{"result": "21", "type": "INTEGER", "variablesReference": 0}`,
+		c.call("evaluate", EvaluateArguments{Expression: "a + b * 10", FrameID: 1}))
+	assert.JSONEq(t, `{"result": "1", "type": "INTEGER", "variablesReference": 0}`,
+		c.call("evaluate", EvaluateArguments{Expression: "xs[0]", FrameID: 2}))
+	assert.Equal(t, "identifier not found: nope", c.request("evaluate", EvaluateArguments{Expression: "nope"}).Message)
+	assert.Equal(t, "invalid frame 3", c.request("evaluate", EvaluateArguments{Expression: "1", FrameID: 3}).Message)
+
+	assert.JSONEq(t, `{"value": "40", "type": "INTEGER", "variablesReference": 0}`,
+		c.call("setVariable", SetVariableArguments{VariablesReference: 1, Name: "b", Value: "a * 40"}))
+	assert.Equal(t, "no variable c", c.request("setVariable", SetVariableArguments{VariablesReference: 1, Name: "c", Value: "1"}).Message)
+	assert.Equal(t, "only variables of scopes can be set", c.request("setVariable", SetVariableArguments{VariablesReference: 4, Name: "[0]", Value: "1"}).Message)
+
+	c.call("next", ThreadArguments{ThreadID: threadID})
+	c.stopped("step")
+	assert.Contains(t, c.call("stackTrace", StackTraceArguments{ThreadID: threadID}), `"name":"add","source":{"name":"main.mk","path":"`+main+`"},"line":4`)
+	// The references are handed out again after every stop
+	assert.Contains(t, c.call("scopes", ScopesArguments{FrameID: 1}), `"name":"Locals","variablesReference":1`)
+	assert.Contains(t, c.call("variables", VariablesArguments{VariablesReference: 1}), `{"name":"sum","value":"41","type":"INTEGER","variablesReference":0}`)
+
+	c.call("stepOut", ThreadArguments{ThreadID: threadID})
+	c.stopped("step")
+	assert.Contains(t, c.call("stackTrace", StackTraceArguments{ThreadID: threadID}), `"totalFrames":1`)
+	assert.Contains(t, c.call("evaluate", EvaluateArguments{Expression: "y"}), `"result":"41"`)
+
+	c.call("stepIn", ThreadArguments{ThreadID: threadID})
+	c.stopped("breakpoint")
+	c.call("stepIn", ThreadArguments{ThreadID: threadID})
+	c.stopped("step")
+	assert.Contains(t, c.call("stackTrace", StackTraceArguments{ThreadID: threadID}), `"name":"add","source":{"name":"main.mk","path":"`+main+`"},"line":4`)
+	c.call("stepIn", ThreadArguments{ThreadID: threadID})
+	c.stopped("step")
+	lib := filepath.Join(filepath.Dir(main), "lib.mk")
+	assert.Contains(t, c.call("stackTrace", StackTraceArguments{ThreadID: threadID}), `"name":"lib.double","source":{"name":"lib.mk","path":"`+lib+`"},"line":1,"column":22`)
+
+	assert.JSONEq(t, `{"allThreadsContinued": true}`, c.call("continue", ThreadArguments{ThreadID: threadID}))
+	output, code := c.exit()
+	assert.Equal(t, map[string]string{"stdout": "102\n"}, output)
+	assert.Equal(t, 0, code)
+	assert.Equal(t, "the program is not stopped", c.request("stackTrace", StackTraceArguments{ThreadID: threadID}).Message)
+	c.disconnect()
+}
+
+func TestStopOnEntry(t *testing.T) {
+	c := newClient(t)
+	main := c.launch(map[string]string{"main.mk": "puts(1);\n1 + true;\n"}, map[string]interface{}{"stopOnEntry": true})
+	c.stopped("entry")
+	assert.Contains(t, c.call("stackTrace", StackTraceArguments{ThreadID: threadID}), `"line":1,"column":1`)
+
+	c.call("continue", ThreadArguments{ThreadID: threadID})
+	output, code := c.exit()
+	assert.Equal(t, map[string]string{"stdout": "1\n", "stderr": main + ": Error: type mismatch: INTEGER + BOOLEAN\n"}, output)
+	assert.Equal(t, 1, code)
+	c.disconnect()
+}
+
+func TestNoDebug(t *testing.T) {
+	c := newClient(t)
+	c.launch(map[string]string{"main.mk": "puts(1);\n"}, map[string]interface{}{"noDebug": true, "stopOnEntry": true}, 1)
+	output, code := c.exit()
+	assert.Equal(t, map[string]string{"stdout": "1\n"}, output)
+	assert.Equal(t, 0, code)
+	c.disconnect()
+}
+
+func TestDisconnectWhileStopped(t *testing.T) {
+	c := newClient(t)
+	c.launch(map[string]string{"main.mk": "puts(1);\nputs(2);\n"}, map[string]interface{}{}, 2)
+	c.stopped("breakpoint")
+	c.call("disconnect", nil)
+	// The program ends without evaluating the statement it stopped at
+	output, code := c.exit()
+	assert.Equal(t, map[string]string{}, output)
+	assert.Equal(t, 0, code)
+	assert.NoError(t, <-c.result)
+}
+
+// blockingReader never returns from Read, it closes reading when it is first called
+type blockingReader struct {
+	reading chan struct{}
+}
+
+func (r blockingReader) Read([]byte) (int, error) {
+	close(r.reading)
+	select {}
+}
+
+func TestQuitWhileBlocked(t *testing.T) {
+	main := filepath.Join(t.TempDir(), "main.mk")
+	assert.NoError(t, os.WriteFile(main, []byte("readline();\n"), 0644))
+	s := NewServer(strings.NewReader(""), io.Discard)
+	p := newProgram(s, &LaunchArguments{Program: main})
+	stdin := blockingReader{reading: make(chan struct{})}
+	p.ctx.Stdin = bufio.NewReader(stdin)
+	p.start()
+	// The program never reaches another statement
+	<-stdin.reading
+
+	quit := make(chan struct{})
+	go func() {
+		p.quit()
+		close(quit)
+	}()
+	select {
+	case <-quit:
+	case <-time.After(time.Second):
+		t.Fatal("quit waited for a program blocked in readline")
+	}
+}
+
+func TestInvalidContentLength(t *testing.T) {
+	err := NewServer(strings.NewReader("Content-Length: -1\r\n\r\n"), io.Discard).Run()
+	assert.EqualError(t, err, "invalid Content-Length -1, must be between 0 and 67108864")
+}
@@ -0,0 +1,224 @@
+package dap
+
+import (
+	"encoding/json"
+	"errors"
+	"monkey_interpreter/ast"
+	"monkey_interpreter/debugger"
+	"monkey_interpreter/evaluator"
+	"monkey_interpreter/lexer"
+	"monkey_interpreter/object"
+	"monkey_interpreter/parser"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// Frame IDs count the frames of the stopped program from the innermost one, starting at one
+
+func (s *Server) stackTrace(raw json.RawMessage) (interface{}, error) {
+	var args StackTraceArguments
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	stack := s.stack()
+	if stack == nil {
+		return nil, errors.New("the program is not stopped")
+	}
+
+	frames := make([]StackFrame, 0, len(stack))
+	for id := args.StartFrame + 1; id <= len(stack); id++ {
+		if args.Levels > 0 && len(frames) == args.Levels {
+			break
+		}
+		frame := stack[len(stack)-id]
+		sf := StackFrame{ID: id, Name: frame.Name}
+		if frame.File != "" {
+			sf.Source = &Source{Name: filepath.Base(frame.File), Path: frame.File}
+		}
+		if frame.Statement != nil {
+			tok := ast.StartToken(frame.Statement)
+			sf.Line, sf.Column = tok.Line, tok.Column
+		}
+		frames = append(frames, sf)
+	}
+	return StackTraceResponseBody{StackFrames: frames, TotalFrames: len(stack)}, nil
+}
+
+// frame returns the frame of the stopped program with an ID, zero is the innermost one
+func (s *Server) frame(id int) (*evaluator.Frame, error) {
+	stack := s.stack()
+	if stack == nil {
+		return nil, errors.New("the program is not stopped")
+	}
+	if id == 0 {
+		id = 1
+	}
+	if id < 0 || id > len(stack) {
+		return nil, errors.New("invalid frame " + strconv.Itoa(id))
+	}
+	return stack[len(stack)-id], nil
+}
+
+// scopes returns the environment chain of a frame. The innermost environment holds its locals, the
+// outermost one the globals of its file and the ones in between the variables of enclosing
+// functions
+func (s *Server) scopes(raw json.RawMessage) (interface{}, error) {
+	var args ScopesArguments
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	frame, err := s.frame(args.FrameID)
+	if err != nil {
+		return nil, err
+	}
+
+	scopes := make([]Scope, 0)
+	for env := frame.Env; env != nil; env = env.Outer(1) {
+		name := "Closure"
+		switch {
+		case env.Outer(1) == nil:
+			name = "Globals"
+		case env == frame.Env:
+			name = "Locals"
+		}
+		scopes = append(scopes, Scope{Name: name, VariablesReference: s.reference(env)})
+	}
+	return ScopesResponseBody{Scopes: scopes}, nil
+}
+
+// reference returns a new variables reference to an environment or a value with elements
+func (s *Server) reference(container interface{}) int {
+	s.references = append(s.references, container)
+	return len(s.references)
+}
+
+func (s *Server) referenced(ref int) (interface{}, error) {
+	if ref < 1 || ref > len(s.references) {
+		return nil, errors.New("invalid variables reference " + strconv.Itoa(ref))
+	}
+	return s.references[ref-1], nil
+}
+
+func (s *Server) variables(raw json.RawMessage) (interface{}, error) {
+	var args VariablesArguments
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	container, err := s.referenced(args.VariablesReference)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := make([]Variable, 0)
+	switch container := container.(type) {
+	case *object.Environment:
+		for _, name := range container.Names() {
+			val, _ := container.Get(name)
+			vars = append(vars, s.variable(name, val))
+		}
+	case *object.Array:
+		for i, elem := range container.Elements {
+			vars = append(vars, s.variable("["+strconv.Itoa(i)+"]", elem))
+		}
+	case *object.Hash:
+		for _, pair := range container.Pairs() {
+			vars = append(vars, s.variable(pair.Key.Inspect(), pair.Value))
+		}
+	}
+	return VariablesResponseBody{Variables: vars}, nil
+}
+
+func (s *Server) variable(name string, val object.Object) Variable {
+	return Variable{Name: name, Value: debugger.Summary(val), Type: string(val.Type()), VariablesReference: s.children(val)}
+}
+
+// children returns a variables reference to the elements of arrays, hashes and modules, or zero
+// for the other values
+func (s *Server) children(val object.Object) int {
+	switch val := val.(type) {
+	case *object.Array:
+		if len(val.Elements) > 0 {
+			return s.reference(val)
+		}
+	case *object.Hash:
+		if val.Len() > 0 {
+			return s.reference(val)
+		}
+	case *object.Module:
+		return s.reference(val.Env)
+	}
+	return 0
+}
+
+// setVariable assigns the value of an expression, evaluated in the scope of the variable
+func (s *Server) setVariable(raw json.RawMessage) (interface{}, error) {
+	var args SetVariableArguments
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	container, err := s.referenced(args.VariablesReference)
+	if err != nil {
+		return nil, err
+	}
+	env, ok := container.(*object.Environment)
+	if !ok {
+		return nil, errors.New("only variables of scopes can be set")
+	}
+	val, err := s.eval(args.Value, env)
+	if err != nil {
+		return nil, err
+	}
+	if !env.Assign(args.Name, val) {
+		return nil, errors.New("no variable " + args.Name)
+	}
+	v := s.variable(args.Name, val)
+	return SetVariableResponseBody{Value: v.Value, Type: v.Type, VariablesReference: v.VariablesReference}, nil
+}
+
+// evaluate evaluates an expression in the environment of a frame
+func (s *Server) evaluate(raw json.RawMessage) (interface{}, error) {
+	var args EvaluateArguments
+	if err := json.Unmarshal(raw, &args); err != nil {
+		return nil, err
+	}
+	frame, err := s.frame(args.FrameID)
+	if err != nil {
+		return nil, err
+	}
+	val, err := s.eval(args.Expression, frame.Env)
+	if err != nil {
+		return nil, err
+	}
+	v := s.variable("", val)
+	return EvaluateResponseBody{Result: v.Value, Type: v.Type, VariablesReference: v.VariablesReference}, nil
+}
+
+// eval evaluates an expression in env without stopping at the statements of the functions it
+// calls. It must only be called while the program is stopped
+func (s *Server) eval(input string, env *object.Environment) (object.Object, error) {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Error()) > 0 {
+		return nil, errors.New(strings.Join(p.Error(), "; "))
+	}
+	if len(program.Statements) != 1 {
+		return nil, errors.New("want a single expression")
+	}
+	exp, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		return nil, errors.New("want an expression")
+	}
+
+	c := s.program.ctx
+	c.Debugger = nil
+	val := c.Eval(exp.Expression, env)
+	c.Debugger = s.program
+	if err, ok := val.(*object.Error); ok {
+		return nil, errors.New(err.Message)
+	}
+	if val == nil {
+		return evaluator.NULL, nil
+	}
+	return val, nil
+}
@@ -0,0 +1,337 @@
+// Package debugger implements an interactive command line debugger over the statement hook of the
+// evaluator. The program stops before its first statement, then runs until a line breakpoint is
+// hit or a step ends. While it is stopped, the variables of the current environment chain can be
+// printed and changed and expressions evaluated in it.
+package debugger
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"monkey_interpreter/ast"
+	"monkey_interpreter/evaluator"
+	"monkey_interpreter/lexer"
+	"monkey_interpreter/object"
+	"monkey_interpreter/parser"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// ErrQuit stops the program when the user quits
+var ErrQuit = errors.New("debugging session ended")
+
+// Breakpoint is a line of a file
+type Breakpoint struct {
+	File string // absolute path
+	Line int
+}
+
+// Debugger reads commands from in and writes to out whenever the program stops
+type Debugger struct {
+	in  *bufio.Reader
+	out io.Writer
+
+	main        string // absolute path of the debugged file
+	breakpoints []Breakpoint
+	stepper     Stepper
+	previous    string              // the last command, repeated by an empty line
+	sources     map[string][]string // lines of the files by absolute path
+}
+
+func New(in *bufio.Reader, out io.Writer) *Debugger {
+	return &Debugger{in: in, out: out, sources: make(map[string][]string)}
+}
+
+// Run evaluates the file at path under the debugger and returns its result, or nil if the user
+// quit before it finished
+func (d *Debugger) Run(ctx *evaluator.Context, path string) object.Object {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return &object.Error{Message: err.Error()}
+	}
+	d.main = abs
+
+	ctx.Debugger = d
+	defer func() {
+		ctx.Debugger = nil
+	}()
+	res := ctx.EvalFile(abs, object.NewEnvironment())
+	if err, ok := res.(*object.Error); ok && errors.Is(err.Err, ErrQuit) {
+		return nil
+	}
+	_, _ = fmt.Fprintln(d.out, "Program finished")
+	return res
+}
+
+// Statement stops the program if a breakpoint is hit or a step ends, and then handles commands
+// until one resumes it
+func (d *Debugger) Statement(c *evaluator.Context, stmt ast.Statement, env *object.Environment) error {
+	stack := c.Stack()
+	frame := stack[len(stack)-1]
+	line := ast.StartToken(stmt).Line
+	if d.stepper.Stop(stack, line, d.hasBreakpoint(frame.File, line)) == "" {
+		return nil
+	}
+
+	_, _ = fmt.Fprintf(d.out, "Stopped in %s at %s:%d\n", frame.Name, displayName(frame.File), line)
+	d.printSource(frame.File, stmt, line, 0)
+	for {
+		_, _ = fmt.Fprint(d.out, "(monkey) ")
+		input, err := d.in.ReadString('\n')
+		if err != nil && input == "" {
+			_, _ = fmt.Fprintln(d.out)
+			return ErrQuit
+		}
+		input = strings.TrimSpace(input)
+		if input == "" {
+			input = d.previous
+		}
+		d.previous = input
+
+		resume, err := d.command(c, stmt, env, input, stack)
+		if err != nil {
+			return err
+		}
+		if resume {
+			return nil
+		}
+	}
+}
+
+func (d *Debugger) hasBreakpoint(file string, line int) bool {
+	for _, bp := range d.breakpoints {
+		if bp.File == file && bp.Line == line {
+			return true
+		}
+	}
+	return false
+}
+
+const help = `break [file:]line    stop before the statements of a line (b)
+delete [[file:]line] remove a breakpoint, or all of them (d)
+breakpoints          list the breakpoints
+continue             run until a breakpoint is hit (c)
+step                 run until the next statement, entering calls (s)
+next                 run until the next statement of this function (n)
+out                  run until the current function returns (o)
+print expression     evaluate an expression in the current scope (p)
+set name = value     change a variable of the current scope chain
+locals               print the variables of the current scope chain
+stack                print the call stack (bt)
+list                 print the source around the current line (l)
+quit                 stop the program (q)
+`
+
+// command handles a line of input and reports whether the program resumes
+func (d *Debugger) command(c *evaluator.Context, stmt ast.Statement, env *object.Environment, input string, stack []*evaluator.Frame) (bool, error) {
+	name, arg := input, ""
+	if i := strings.IndexAny(input, " \t"); i >= 0 {
+		name, arg = input[:i], strings.TrimSpace(input[i+1:])
+	}
+
+	switch name {
+	case "continue", "c":
+		d.stepper.Resume(Continue, stack)
+		return true, nil
+	case "step", "s":
+		d.stepper.Resume(Step, stack)
+		return true, nil
+	case "next", "n":
+		d.stepper.Resume(Next, stack)
+		return true, nil
+	case "out", "o":
+		d.stepper.Resume(Out, stack)
+		return true, nil
+	case "quit", "q":
+		return false, ErrQuit
+	case "break", "b":
+		d.addBreakpoint(arg)
+	case "delete", "d":
+		d.deleteBreakpoint(arg)
+	case "breakpoints":
+		for _, bp := range d.breakpoints {
+			_, _ = fmt.Fprintf(d.out, "%s:%d\n", displayName(bp.File), bp.Line)
+		}
+	case "print", "p":
+		if val := d.eval(c, env, arg); val != nil {
+			_, _ = fmt.Fprintln(d.out, val.Inspect())
+		}
+	case "set":
+		d.set(c, env, arg)
+	case "locals":
+		d.printLocals(env)
+	case "stack", "bt":
+		d.printStack(stack)
+	case "list", "l":
+		d.printSource(stack[len(stack)-1].File, stmt, ast.StartToken(stmt).Line, 3)
+	case "help", "h":
+		_, _ = io.WriteString(d.out, help)
+	default:
+		_, _ = fmt.Fprintf(d.out, "unknown command %q, try help\n", name)
+	}
+	return false, nil
+}
+
+// parseBreakpoint parses [file:]line, files are relative to the debugged one
+func (d *Debugger) parseBreakpoint(arg string) (Breakpoint, bool) {
+	file, lineText := d.main, arg
+	if i := strings.LastIndex(arg, ":"); i >= 0 {
+		file, lineText = arg[:i], arg[i+1:]
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(filepath.Dir(d.main), file)
+		}
+	}
+	line, err := strconv.Atoi(lineText)
+	if err != nil || line < 1 {
+		_, _ = fmt.Fprintf(d.out, "invalid breakpoint %q, want [file:]line\n", arg)
+		return Breakpoint{}, false
+	}
+	return Breakpoint{File: file, Line: line}, true
+}
+
+func (d *Debugger) addBreakpoint(arg string) {
+	bp, ok := d.parseBreakpoint(arg)
+	if !ok {
+		return
+	}
+	if !d.hasBreakpoint(bp.File, bp.Line) {
+		d.breakpoints = append(d.breakpoints, bp)
+	}
+	_, _ = fmt.Fprintf(d.out, "Breakpoint at %s:%d\n", displayName(bp.File), bp.Line)
+}
+
+func (d *Debugger) deleteBreakpoint(arg string) {
+	if arg == "" {
+		d.breakpoints = nil
+		return
+	}
+	bp, ok := d.parseBreakpoint(arg)
+	if !ok {
+		return
+	}
+	for i, existing := range d.breakpoints {
+		if existing == bp {
+			d.breakpoints = append(d.breakpoints[:i], d.breakpoints[i+1:]...)
+			return
+		}
+	}
+	_, _ = fmt.Fprintf(d.out, "no breakpoint at %s:%d\n", displayName(bp.File), bp.Line)
+}
+
+// eval evaluates an expression in env without stopping at the statements of the functions it
+// calls. Errors are printed and nil is returned
+func (d *Debugger) eval(c *evaluator.Context, env *object.Environment, input string) object.Object {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Error()) > 0 {
+		_, _ = fmt.Fprintln(d.out, strings.Join(p.Error(), "; "))
+		return nil
+	}
+	if len(program.Statements) != 1 {
+		_, _ = fmt.Fprintln(d.out, "want a single expression")
+		return nil
+	}
+	exp, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		_, _ = fmt.Fprintln(d.out, "want an expression")
+		return nil
+	}
+
+	c.Debugger = nil
+	val := c.Eval(exp.Expression, env)
+	c.Debugger = d
+	if err, ok := val.(*object.Error); ok {
+		_, _ = fmt.Fprintln(d.out, err.Inspect())
+		return nil
+	}
+	if val == nil {
+		return evaluator.NULL
+	}
+	return val
+}
+
+func (d *Debugger) set(c *evaluator.Context, env *object.Environment, arg string) {
+	i := strings.Index(arg, "=")
+	if i < 0 {
+		_, _ = fmt.Fprintln(d.out, "usage: set name = value")
+		return
+	}
+	name := strings.TrimSpace(arg[:i])
+	val := d.eval(c, env, arg[i+1:])
+	if val == nil {
+		return
+	}
+	if !env.Assign(name, val) {
+		_, _ = fmt.Fprintf(d.out, "no variable %s\n", name)
+		return
+	}
+	_, _ = fmt.Fprintf(d.out, "%s = %s\n", name, Summary(val))
+}
+
+func (d *Debugger) printLocals(env *object.Environment) {
+	for scope := env; scope != nil; scope = scope.Outer(1) {
+		if scope != env {
+			_, _ = fmt.Fprintln(d.out, "--- enclosing scope ---")
+		}
+		for _, name := range scope.Names() {
+			val, _ := scope.Get(name)
+			_, _ = fmt.Fprintf(d.out, "%s = %s\n", name, Summary(val))
+		}
+	}
+}
+
+func (d *Debugger) printStack(stack []*evaluator.Frame) {
+	for i := len(stack) - 1; i >= 0; i-- {
+		frame := stack[i]
+		line := 0
+		if frame.Statement != nil {
+			line = ast.StartToken(frame.Statement).Line
+		}
+		_, _ = fmt.Fprintf(d.out, "#%d %s at %s:%d\n", len(stack)-1-i, frame.Name, displayName(frame.File), line)
+	}
+}
+
+// printSource prints the lines of a file around line, or the statement if its file is unknown
+func (d *Debugger) printSource(file string, stmt ast.Statement, line, context int) {
+	lines := d.source(file)
+	if lines == nil {
+		_, _ = fmt.Fprintf(d.out, "%4d  %s\n", line, stmt.String())
+		return
+	}
+	for i := line - context; i <= line+context; i++ {
+		if i < 1 || i > len(lines) {
+			continue
+		}
+		marker := " "
+		if i == line {
+			marker = ">"
+		}
+		_, _ = fmt.Fprintf(d.out, "%s%4d  %s\n", marker, i, lines[i-1])
+	}
+}
+
+func (d *Debugger) source(file string) []string {
+	if file == "" {
+		return nil
+	}
+	if lines, ok := d.sources[file]; ok {
+		return lines
+	}
+	src, err := os.ReadFile(file)
+	if err != nil {
+		d.sources[file] = nil
+		return nil
+	}
+	d.sources[file] = strings.Split(string(src), "\n")
+	return d.sources[file]
+}
+
+func displayName(file string) string {
+	if file == "" {
+		return "<input>"
+	}
+	return filepath.Base(file)
+}
@@ -0,0 +1,137 @@
+package debugger
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"monkey_interpreter/evaluator"
+	"monkey_interpreter/object"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// debug runs the main.mk file of files under the debugger with commands as input
+func debug(t *testing.T, files map[string]string, commands ...string) (string, string, object.Object) {
+	dir := t.TempDir()
+	for name, src := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(src), 0644))
+	}
+	in := bufio.NewReader(strings.NewReader(strings.Join(commands, "\n") + "\n"))
+	var stdout, debugOut bytes.Buffer
+	ctx := evaluator.NewContext(in, &stdout, io.Discard)
+	res := New(in, &debugOut).Run(ctx, filepath.Join(dir, "main.mk"))
+	return debugOut.String(), stdout.String(), res
+}
+
+const fib = `let fib = fn(n) {
+  if (n < 2) { return n; }
+  fib(n - 1) + fib(n - 2)
+};
+let x = 3;
+puts(fib(x));
+`
+
+func TestBreakpoints(t *testing.T) {
+	out, stdout, res := debug(t, map[string]string{"main.mk": fib},
+		"break 2", "breakpoints", "continue", "print n", "stack", "c", "p n", "delete 2", "c")
+
+	assert.Equal(t, `Stopped in main.mk at main.mk:1
+>   1  let fib = fn(n) {
+(monkey) Breakpoint at main.mk:2
+(monkey) main.mk:2
+(monkey) Stopped in fib at main.mk:2
+>   2    if (n < 2) { return n; }
+(monkey) 3
+(monkey) #0 fib at main.mk:2
+#1 main.mk at main.mk:6
+(monkey) Stopped in fib at main.mk:2
+>   2    if (n < 2) { return n; }
+(monkey) 2
+(monkey) (monkey) Program finished
+`, out)
+	assert.Equal(t, "2\n", stdout)
+	assert.Equal(t, evaluator.NULL, res)
+}
+
+func TestStepping(t *testing.T) {
+	files := map[string]string{
+		"lib.mk": "let double = fn(x) {\n  x * 2\n};\n",
+		"main.mk": `import "./lib.mk" as lib;
+let add = fn(a, b) {
+  let sum = a + b;
+  sum
+};
+let y = add(1, 2);
+puts(lib.double(y));
+`,
+	}
+	out, stdout, _ := debug(t, files, "next", "n", "step", "", "out", "step", "stack", "", "c")
+
+	assert.Equal(t, `Stopped in main.mk at main.mk:1
+>   1  import "./lib.mk" as lib;
+(monkey) Stopped in main.mk at main.mk:2
+>   2  let add = fn(a, b) {
+(monkey) Stopped in main.mk at main.mk:6
+>   6  let y = add(1, 2);
+(monkey) Stopped in add at main.mk:3
+>   3    let sum = a + b;
+(monkey) Stopped in add at main.mk:4
+>   4    sum
+(monkey) Stopped in main.mk at main.mk:7
+>   7  puts(lib.double(y));
+(monkey) Stopped in lib.double at lib.mk:2
+>   2    x * 2
+(monkey) #0 lib.double at lib.mk:2
+#1 main.mk at main.mk:7
+(monkey) #0 lib.double at lib.mk:2
+#1 main.mk at main.mk:7
+(monkey) Program finished
+`, out)
+	assert.Equal(t, "6\n", stdout)
+}
+
+func TestVariables(t *testing.T) {
+	out, stdout, _ := debug(t, map[string]string{"main.mk": fib},
+		"b 3", "c", "locals", "p n * 10", "p nope", "p let", "set n = 1", "set nope = 1", "set n", "p fib(n + 5)", "d", "c")
+
+	assert.Contains(t, out, `(monkey) n = 3
+--- enclosing scope ---
+fib = fn(n)
+x = 3
+(monkey) 30
+(monkey) Error: identifier not found: nope
+(monkey) Expected next token to be 'IDENT' - got 'EOF' instead
+(monkey) n = 1
+(monkey) no variable nope
+(monkey) usage: set name = value
+(monkey) 8
+`)
+	// fib(0) + fib(-1) after n was changed to 1
+	assert.Equal(t, "-1\n", stdout)
+}
+
+func TestQuit(t *testing.T) {
+	out, stdout, res := debug(t, map[string]string{"main.mk": fib}, "list", "quit")
+	assert.Equal(t, `Stopped in main.mk at main.mk:1
+>   1  let fib = fn(n) {
+(monkey) >   1  let fib = fn(n) {
+    2    if (n < 2) { return n; }
+    3    fib(n - 1) + fib(n - 2)
+    4  };
+(monkey) `, out)
+	assert.Equal(t, "", stdout)
+	assert.Nil(t, res)
+
+	// The end of the input quits too
+	_, _, res = debug(t, map[string]string{"main.mk": fib})
+	assert.Nil(t, res)
+}
+
+func TestRuntimeError(t *testing.T) {
+	out, _, res := debug(t, map[string]string{"main.mk": "let x = 1;\nx + true;\n"}, "c")
+	assert.True(t, strings.HasSuffix(out, "Program finished\n"))
+	assert.Equal(t, "type mismatch: INTEGER + BOOLEAN", res.(*object.Error).Message)
+}
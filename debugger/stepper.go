@@ -0,0 +1,94 @@
+package debugger
+
+import (
+	"monkey_interpreter/evaluator"
+	"monkey_interpreter/object"
+	"strings"
+)
+
+// Mode is how far a resumed program runs before it stops again
+type Mode int
+
+const (
+	Entry    Mode = iota // stop at the first statement
+	Step                 // stop at the next statement
+	Next                 // stop at the next statement of the frame the step started in or a caller
+	Out                  // stop at the next statement of a caller
+	Continue             // stop at breakpoints only
+	Pause                // stop at the next statement, even on a breakpoint
+)
+
+// location is where the program last stopped. Statements of the same frame on that line do not
+// stop the program again, so continuing from a breakpoint leaves its line
+type location struct {
+	frame *evaluator.Frame
+	line  int
+}
+
+// Stepper decides at which statements a program stops. Its zero value stops at the first one
+type Stepper struct {
+	Mode  Mode
+	frame *evaluator.Frame // the frame the current step started in
+	depth int              // its depth in the stack
+	last  location
+}
+
+// Stop returns why the program stops at a statement starting on line, with "entry", "step",
+// "breakpoint" or "pause", or an empty string if it does not. The statement is in the innermost
+// frame of stack and breakpoint reports whether one is set on its line
+func (s *Stepper) Stop(stack []*evaluator.Frame, line int, breakpoint bool) string {
+	frame := stack[len(stack)-1]
+	if s.last.frame == frame && s.last.line == line {
+		return ""
+	}
+	reason := s.reason(len(stack), frame, breakpoint)
+	if reason != "" {
+		s.last = location{frame: frame, line: line}
+	}
+	return reason
+}
+
+func (s *Stepper) reason(depth int, frame *evaluator.Frame, breakpoint bool) string {
+	switch {
+	case s.Mode == Pause:
+		return "pause"
+	case breakpoint:
+		return "breakpoint"
+	}
+	switch s.Mode {
+	case Entry:
+		return "entry"
+	case Step:
+		return "step"
+	case Next:
+		if frame == s.frame || depth < s.depth {
+			return "step"
+		}
+	case Out:
+		if depth < s.depth {
+			return "step"
+		}
+	}
+	return ""
+}
+
+// Resume sets the mode the program stopped with stack runs in
+func (s *Stepper) Resume(m Mode, stack []*evaluator.Frame) {
+	s.Mode = m
+	if m == Next || m == Out {
+		s.frame, s.depth = stack[len(stack)-1], len(stack)
+	}
+}
+
+// Summary is a single line representation of a value, functions are shown without their body
+func Summary(val object.Object) string {
+	fn, ok := val.(*object.Function)
+	if !ok {
+		return val.Inspect()
+	}
+	params := make([]string, 0, len(fn.Parameters))
+	for _, param := range fn.Parameters {
+		params = append(params, param.Value)
+	}
+	return "fn(" + strings.Join(params, ", ") + ")"
+}
@@ -0,0 +1,128 @@
+package evaluator
+
+import (
+	"monkey_interpreter/object"
+	"strings"
+)
+
+// assertBuiltins fail with an error describing what was expected, so a failing assertion ends the
+// test calling it. assert_throws calls back into user functions, so they are bound to the Context
+func (c *Context) assertBuiltins() map[string]*object.BuiltIn {
+	return map[string]*object.BuiltIn{
+		"assert": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 && len(args) != 2 {
+					return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+				}
+				message, err := assertMessage("assert", args[1:])
+				if err != nil {
+					return err
+				}
+				if !isTruthy(args[0]) {
+					return assertionError(message, "expected a truthy value, got %s", args[0].Inspect())
+				}
+				return NULL
+			},
+		},
+		"assert_eq": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 2 && len(args) != 3 {
+					return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+				}
+				message, err := assertMessage("assert_eq", args[2:])
+				if err != nil {
+					return err
+				}
+				if !equal(args[0], args[1]) {
+					return assertionError(message, "expected %s, got %s", args[1].Inspect(), args[0].Inspect())
+				}
+				return NULL
+			},
+		},
+		"assert_throws": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 && len(args) != 2 {
+					return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+				}
+				if !isCallable(args[0]) {
+					return newError("argument to `assert_throws` must be FUNCTION, got %s", args[0].Type())
+				}
+				want, err := assertMessage("assert_throws", args[1:])
+				if err != nil {
+					return err
+				}
+				res := c.applyFunction(args[0], nil)
+				thrown, ok := res.(*object.Error)
+				if !ok {
+					return assertionError("", "expected an error, got %s", res.Inspect())
+				}
+				if !strings.Contains(thrown.Message, want) {
+					return assertionError("", "expected an error containing %q, got %q", want, thrown.Message)
+				}
+				return &object.String{Value: thrown.Message}
+			},
+		},
+	}
+}
+
+// assertMessage returns the optional message argument of an assertion
+func assertMessage(name string, args []object.Object) (string, *object.Error) {
+	if len(args) == 0 {
+		return "", nil
+	}
+	message, ok := args[0].(*object.String)
+	if !ok {
+		return "", newError("argument to `%s` must be STRING, got %s", name, args[0].Type())
+	}
+	return message.Value, nil
+}
+
+func assertionError(message, format string, a ...interface{}) *object.Error {
+	if message != "" {
+		format = message + ": " + format
+	}
+	err := newError("assertion failed: "+format, a...)
+	err.Assertion = true
+	return err
+}
+
+// equal reports whether two values are the same, comparing arrays and hashes element by element
+func equal(a, b object.Object) bool {
+	switch a := a.(type) {
+	case *object.Integer:
+		b, ok := b.(*object.Integer)
+		return ok && a.Value == b.Value
+	case *object.String:
+		b, ok := b.(*object.String)
+		return ok && a.Value == b.Value
+	case *object.Boolean:
+		b, ok := b.(*object.Boolean)
+		return ok && a.Value == b.Value
+	case *object.Array:
+		b, ok := b.(*object.Array)
+		if !ok || len(a.Elements) != len(b.Elements) {
+			return false
+		}
+		for i := range a.Elements {
+			if !equal(a.Elements[i], b.Elements[i]) {
+				return false
+			}
+		}
+		return true
+	case *object.Hash:
+		b, ok := b.(*object.Hash)
+		if !ok || a.Len() != b.Len() {
+			return false
+		}
+		for _, pair := range a.Pairs() {
+			key, _ := hashKey(pair.Key)
+			other, ok := b.Get(key)
+			if !ok || !equal(pair.Value, other.Value) {
+				return false
+			}
+		}
+		return true
+	default:
+		return a == b
+	}
+}
@@ -1,10 +1,8 @@
 package evaluator
 
-import (
-	"fmt"
-	"monkey_interpreter/object"
-)
+import "monkey_interpreter/object"
 
+// builtins do not depend on a Context. The map is only read, every Context binds copies of them
 var builtins = map[string]*object.BuiltIn{
 	"len": {
 		Fn: func(args ...object.Object) object.Object {
@@ -31,7 +29,7 @@ var builtins = map[string]*object.BuiltIn{
 				if len(arg.Elements) == 0 {
 					return NULL
 				}
-				return &object.ReturnValue{Value: arg.Elements[0]}
+				return arg.Elements[0]
 			default:
 				return newError("argument to `len` not supported, got %s", arg.Type())
 			}
@@ -47,7 +45,7 @@ var builtins = map[string]*object.BuiltIn{
 				if len(arg.Elements) == 0 {
 					return NULL
 				}
-				return &object.ReturnValue{Value: arg.Elements[len(arg.Elements)-1]}
+				return arg.Elements[len(arg.Elements)-1]
 			default:
 				return newError("argument to `len` not supported, got %s", arg.Type())
 			}
@@ -78,19 +76,144 @@ var builtins = map[string]*object.BuiltIn{
 			}
 			switch arg := args[0].(type) {
 			case *object.Array:
-				newElem := args[1]
-				return &object.Array{Elements: append(arg.Elements, newElem)}
+				// Copy the elements, the array may be shared and have room to append in place
+				elems := make([]object.Object, len(arg.Elements), len(arg.Elements)+1)
+				copy(elems, arg.Elements)
+				return &object.Array{Elements: append(elems, args[1])}
 			default:
 				return newError("argument to `push` not supported, got %s", arg.Type())
 			}
 		},
 	},
-	"puts": {
+	"keys": {
 		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.Hash:
+				keys := make([]object.Object, 0, arg.Len())
+				for _, pair := range arg.Pairs() {
+					keys = append(keys, pair.Key)
+				}
+				return &object.Array{Elements: keys}
+			default:
+				return newError("argument to `keys` not supported, got %s", arg.Type())
+			}
+		},
+	},
+	"values": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.Hash:
+				values := make([]object.Object, 0, arg.Len())
+				for _, pair := range arg.Pairs() {
+					values = append(values, pair.Value)
+				}
+				return &object.Array{Elements: values}
+			default:
+				return newError("argument to `values` not supported, got %s", arg.Type())
+			}
+		},
+	},
+	"entries": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.Hash:
+				entries := make([]object.Object, 0, arg.Len())
+				for _, pair := range arg.Pairs() {
+					entries = append(entries, &object.Array{Elements: []object.Object{pair.Key, pair.Value}})
+				}
+				return &object.Array{Elements: entries}
+			default:
+				return newError("argument to `entries` not supported, got %s", arg.Type())
+			}
+		},
+	},
+	"has": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.Hash:
+				key, err := hashKey(args[1])
+				if err != nil {
+					return err
+				}
+				_, ok := arg.Get(key)
+				return booleanToNativeBoolean(ok)
+			default:
+				return newError("argument to `has` not supported, got %s", arg.Type())
+			}
+		},
+	},
+	"delete": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.Hash:
+				key, err := hashKey(args[1])
+				if err != nil {
+					return err
+				}
+				hash := object.NewHash()
+				for _, pair := range arg.Pairs() {
+					pairKey, _ := hashKey(pair.Key)
+					if pairKey != key {
+						hash.Set(pairKey, pair)
+					}
+				}
+				return hash
+			default:
+				return newError("argument to `delete` not supported, got %s", arg.Type())
+			}
+		},
+	},
+	"put": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 3 {
+				return newError("wrong number of arguments. got=%d, want=3", len(args))
+			}
+			switch arg := args[0].(type) {
+			case *object.Hash:
+				key, err := hashKey(args[1])
+				if err != nil {
+					return err
+				}
+				hash := arg.Copy()
+				hash.Set(key, object.HashPair{Key: args[1], Value: args[2]})
+				return hash
+			default:
+				return newError("argument to `put` not supported, got %s", arg.Type())
+			}
+		},
+	},
+	"merge": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) < 2 {
+				return newError("wrong number of arguments. got=%d, want at least 2", len(args))
+			}
+			hash := object.NewHash()
 			for _, arg := range args {
-				fmt.Println(arg.Inspect())
+				h, ok := arg.(*object.Hash)
+				if !ok {
+					return newError("argument to `merge` not supported, got %s", arg.Type())
+				}
+				for _, pair := range h.Pairs() {
+					key, _ := hashKey(pair.Key)
+					hash.Set(key, pair)
+				}
 			}
-			return NULL
+			return hash
 		},
 	},
 }
@@ -0,0 +1,346 @@
+package evaluator
+
+import (
+	"monkey_interpreter/object"
+	"sort"
+)
+
+// maxRangeLength bounds the arrays built by range
+const maxRangeLength = 64 << 20
+
+// collectionBuiltins call back into user functions, so they are bound to the Context they run in
+func (c *Context) collectionBuiltins() map[string]*object.BuiltIn {
+	return map[string]*object.BuiltIn{
+		"map": {
+			Fn: func(args ...object.Object) object.Object {
+				arr, fn, err := arrayAndFunctionArgs("map", args)
+				if err != nil {
+					return err
+				}
+				mapped := make([]object.Object, 0, len(arr.Elements))
+				for _, elem := range arr.Elements {
+					res := c.applyFunction(fn, []object.Object{elem})
+					if isError(res) {
+						return res
+					}
+					mapped = append(mapped, res)
+				}
+				return &object.Array{Elements: mapped}
+			},
+		},
+		"filter": {
+			Fn: func(args ...object.Object) object.Object {
+				arr, fn, err := arrayAndFunctionArgs("filter", args)
+				if err != nil {
+					return err
+				}
+				filtered := make([]object.Object, 0)
+				for _, elem := range arr.Elements {
+					res := c.applyFunction(fn, []object.Object{elem})
+					if isError(res) {
+						return res
+					}
+					if isTruthy(res) {
+						filtered = append(filtered, elem)
+					}
+				}
+				return &object.Array{Elements: filtered}
+			},
+		},
+		"reduce": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 2 && len(args) != 3 {
+					return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+				}
+				arr, fn, err := arrayAndFunctionArgs("reduce", args[:2])
+				if err != nil {
+					return err
+				}
+				elems := arr.Elements
+				var acc object.Object
+				if len(args) == 3 {
+					acc = args[2]
+				} else {
+					if len(elems) == 0 {
+						return newError("reduce of empty array with no initial value")
+					}
+					acc, elems = elems[0], elems[1:]
+				}
+				for _, elem := range elems {
+					acc = c.applyFunction(fn, []object.Object{acc, elem})
+					if isError(acc) {
+						return acc
+					}
+				}
+				return acc
+			},
+		},
+		"any": {
+			Fn: func(args ...object.Object) object.Object {
+				arr, fn, err := arrayAndFunctionArgs("any", args)
+				if err != nil {
+					return err
+				}
+				for _, elem := range arr.Elements {
+					res := c.applyFunction(fn, []object.Object{elem})
+					if isError(res) {
+						return res
+					}
+					if isTruthy(res) {
+						return TRUE
+					}
+				}
+				return FALSE
+			},
+		},
+		"all": {
+			Fn: func(args ...object.Object) object.Object {
+				arr, fn, err := arrayAndFunctionArgs("all", args)
+				if err != nil {
+					return err
+				}
+				for _, elem := range arr.Elements {
+					res := c.applyFunction(fn, []object.Object{elem})
+					if isError(res) {
+						return res
+					}
+					if !isTruthy(res) {
+						return FALSE
+					}
+				}
+				return TRUE
+			},
+		},
+		"find": {
+			Fn: func(args ...object.Object) object.Object {
+				arr, fn, err := arrayAndFunctionArgs("find", args)
+				if err != nil {
+					return err
+				}
+				for _, elem := range arr.Elements {
+					res := c.applyFunction(fn, []object.Object{elem})
+					if isError(res) {
+						return res
+					}
+					if isTruthy(res) {
+						return elem
+					}
+				}
+				return NULL
+			},
+		},
+		"sort": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 && len(args) != 2 {
+					return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+				}
+				arr, ok := args[0].(*object.Array)
+				if !ok {
+					return newError("argument to `sort` not supported, got %s", args[0].Type())
+				}
+
+				var less func(a, b object.Object) object.Object
+				if len(args) == 2 {
+					if !isCallable(args[1]) {
+						return newError("argument to `sort` must be FUNCTION, got %s", args[1].Type())
+					}
+					less = func(a, b object.Object) object.Object {
+						return c.applyFunction(args[1], []object.Object{a, b})
+					}
+				} else {
+					less = defaultLess
+				}
+
+				sorted := make([]object.Object, len(arr.Elements))
+				copy(sorted, arr.Elements)
+
+				var sortErr object.Object
+				sort.SliceStable(sorted, func(i, j int) bool {
+					if sortErr != nil {
+						return false
+					}
+					res := less(sorted[i], sorted[j])
+					switch res := res.(type) {
+					case *object.Boolean:
+						return res.Value
+					case *object.Integer:
+						return res.Value < 0
+					case *object.Error:
+						sortErr = res
+					default:
+						sortErr = newError("sort comparator must return BOOLEAN or INTEGER, got %s", res.Type())
+					}
+					return false
+				})
+				if sortErr != nil {
+					return sortErr
+				}
+				return &object.Array{Elements: sorted}
+			},
+		},
+		"reverse": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				switch arg := args[0].(type) {
+				case *object.Array:
+					size := len(arg.Elements)
+					reversed := make([]object.Object, size)
+					for i, elem := range arg.Elements {
+						reversed[size-1-i] = elem
+					}
+					return &object.Array{Elements: reversed}
+				case *object.String:
+					size := len(arg.Value)
+					reversed := make([]byte, size)
+					for i := 0; i < size; i++ {
+						reversed[size-1-i] = arg.Value[i]
+					}
+					return &object.String{Value: string(reversed)}
+				default:
+					return newError("argument to `reverse` not supported, got %s", arg.Type())
+				}
+			},
+		},
+		"zip": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) < 2 {
+					return newError("wrong number of arguments. got=%d, want at least 2", len(args))
+				}
+				arrays := make([]*object.Array, 0, len(args))
+				size := -1
+				for _, arg := range args {
+					arr, ok := arg.(*object.Array)
+					if !ok {
+						return newError("argument to `zip` not supported, got %s", arg.Type())
+					}
+					if size == -1 || len(arr.Elements) < size {
+						size = len(arr.Elements)
+					}
+					arrays = append(arrays, arr)
+				}
+				zipped := make([]object.Object, 0, size)
+				for i := 0; i < size; i++ {
+					tuple := make([]object.Object, 0, len(arrays))
+					for _, arr := range arrays {
+						tuple = append(tuple, arr.Elements[i])
+					}
+					zipped = append(zipped, &object.Array{Elements: tuple})
+				}
+				return &object.Array{Elements: zipped}
+			},
+		},
+		"range": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) < 1 || len(args) > 3 {
+					return newError("wrong number of arguments. got=%d, want=1, 2 or 3", len(args))
+				}
+				bounds := make([]int64, 0, len(args))
+				for _, arg := range args {
+					integer, ok := arg.(*object.Integer)
+					if !ok {
+						return newError("argument to `range` not supported, got %s", arg.Type())
+					}
+					bounds = append(bounds, integer.Value)
+				}
+
+				start, end, step := int64(0), bounds[0], int64(1)
+				if len(bounds) > 1 {
+					start, end = bounds[0], bounds[1]
+				}
+				if len(bounds) > 2 {
+					step = bounds[2]
+				}
+				if step == 0 {
+					return newError("range step cannot be zero")
+				}
+
+				// The distance and the step are unsigned so neither overflows
+				var n uint64
+				if step > 0 && start < end {
+					n = (uint64(end)-uint64(start)-1)/uint64(step) + 1
+				} else if step < 0 && start > end {
+					n = (uint64(start)-uint64(end)-1)/(-uint64(step)) + 1
+				}
+				if n > maxRangeLength {
+					return newError("result of `range` would have more than %d elements", maxRangeLength)
+				}
+				elems := make([]object.Object, 0, n)
+				for i, k := start, uint64(0); k < n; i, k = i+step, k+1 {
+					elems = append(elems, &object.Integer{Value: i})
+				}
+				return &object.Array{Elements: elems}
+			},
+		},
+		"flatten": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 && len(args) != 2 {
+					return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+				}
+				arr, ok := args[0].(*object.Array)
+				if !ok {
+					return newError("argument to `flatten` not supported, got %s", args[0].Type())
+				}
+				depth := int64(-1)
+				if len(args) == 2 {
+					integer, ok := args[1].(*object.Integer)
+					if !ok {
+						return newError("argument to `flatten` not supported, got %s", args[1].Type())
+					}
+					depth = integer.Value
+				}
+				return &object.Array{Elements: flatten(arr.Elements, depth)}
+			},
+		},
+	}
+}
+
+// flatten inlines nested arrays up to depth levels deep - a negative depth flattens completely
+func flatten(elems []object.Object, depth int64) []object.Object {
+	flat := make([]object.Object, 0, len(elems))
+	for _, elem := range elems {
+		nested, ok := elem.(*object.Array)
+		if !ok || depth == 0 {
+			flat = append(flat, elem)
+			continue
+		}
+		flat = append(flat, flatten(nested.Elements, depth-1)...)
+	}
+	return flat
+}
+
+// defaultLess orders integers numerically and strings lexicographically
+func defaultLess(a, b object.Object) object.Object {
+	switch {
+	case a.Type() == object.IntegerObj && b.Type() == object.IntegerObj:
+		return booleanToNativeBoolean(a.(*object.Integer).Value < b.(*object.Integer).Value)
+	case a.Type() == object.StringObj && b.Type() == object.StringObj:
+		return booleanToNativeBoolean(a.(*object.String).Value < b.(*object.String).Value)
+	default:
+		return newError("cannot compare %s and %s", a.Type(), b.Type())
+	}
+}
+
+func arrayAndFunctionArgs(name string, args []object.Object) (*object.Array, object.Object, *object.Error) {
+	if len(args) != 2 {
+		return nil, nil, newError("wrong number of arguments. got=%d, want=2", len(args))
+	}
+	arr, ok := args[0].(*object.Array)
+	if !ok {
+		return nil, nil, newError("argument to `%s` not supported, got %s", name, args[0].Type())
+	}
+	if !isCallable(args[1]) {
+		return nil, nil, newError("argument to `%s` must be FUNCTION, got %s", name, args[1].Type())
+	}
+	return arr, args[1], nil
+}
+
+func isCallable(obj object.Object) bool {
+	switch obj.(type) {
+	case *object.Function, *object.BuiltIn:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,163 @@
+package evaluator
+
+import "monkey_interpreter/object"
+
+// concurrencyBuiltins run functions on other goroutines and pass values between them through
+// channels. The functions spawned evaluate in a fork of the Context
+func (c *Context) concurrencyBuiltins() map[string]*object.BuiltIn {
+	return map[string]*object.BuiltIn{
+		"spawn": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) == 0 {
+					return newError("wrong number of arguments. got=0, want at least 1")
+				}
+				if !isCallable(args[0]) {
+					return newError("argument to `spawn` must be FUNCTION, got %s", args[0].Type())
+				}
+				fn, fnArgs := args[0], args[1:]
+				// The result, or the error the function failed with, is received from the channel
+				result := c.shared.scheduler.NewChannel(1)
+				forked := c.fork()
+				c.shared.scheduler.Go(func() {
+					_ = result.Send(forked.applyFunction(fn, fnArgs))
+					_ = result.Close()
+				})
+				return result
+			},
+		},
+		"channel": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) > 1 {
+					return newError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+				}
+				capacity := int64(0)
+				if len(args) == 1 {
+					n, ok := args[0].(*object.Integer)
+					if !ok {
+						return newError("argument to `channel` must be INTEGER, got %s", args[0].Type())
+					}
+					if n.Value < 0 {
+						return newError("channel capacity cannot be negative, got %d", n.Value)
+					}
+					capacity = n.Value
+				}
+				return c.shared.scheduler.NewChannel(int(capacity))
+			},
+		},
+		"send": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 2 {
+					return newError("wrong number of arguments. got=%d, want=2", len(args))
+				}
+				ch, err := channelArg("send", args[0])
+				if err != nil {
+					return err
+				}
+				if err := ch.Send(args[1]); err != nil {
+					return err
+				}
+				return NULL
+			},
+		},
+		"recv": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				ch, err := channelArg("recv", args[0])
+				if err != nil {
+					return err
+				}
+				value, ok, err := ch.Recv()
+				if err != nil {
+					return err
+				}
+				if !ok {
+					return NULL
+				}
+				return value
+			},
+		},
+		"close": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got=%d, want=1", len(args))
+				}
+				ch, err := channelArg("close", args[0])
+				if err != nil {
+					return err
+				}
+				if err := ch.Close(); err != nil {
+					return err
+				}
+				return NULL
+			},
+		},
+		"select": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 1 && len(args) != 2 {
+					return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+				}
+				return c.selectCase(args[0], args[1:])
+			},
+		},
+	}
+}
+
+// selectCase waits for the first of the cases to proceed and calls its function. A case is
+// [channel, fn(value)] to receive, or [channel, value, fn()] to send. With a default function, it
+// is called instead of waiting when no case can proceed
+func (c *Context) selectCase(casesArg object.Object, defaultArg []object.Object) object.Object {
+	arr, ok := casesArg.(*object.Array)
+	if !ok {
+		return newError("argument to `select` must be ARRAY, got %s", casesArg.Type())
+	}
+	cases := make([]object.SelectCase, 0, len(arr.Elements))
+	handlers := make([]object.Object, 0, len(arr.Elements))
+	for _, elem := range arr.Elements {
+		parts, ok := elem.(*object.Array)
+		if !ok || len(parts.Elements) != 2 && len(parts.Elements) != 3 {
+			return newError("select case must be [channel, fn] or [channel, value, fn], got %s", elem.Inspect())
+		}
+		ch, err := channelArg("select", parts.Elements[0])
+		if err != nil {
+			return err
+		}
+		handler := parts.Elements[len(parts.Elements)-1]
+		if !isCallable(handler) {
+			return newError("select case must end with a FUNCTION, got %s", handler.Type())
+		}
+		sc := object.SelectCase{Channel: ch}
+		if len(parts.Elements) == 3 {
+			sc.Send, sc.Value = true, parts.Elements[1]
+		}
+		cases = append(cases, sc)
+		handlers = append(handlers, handler)
+	}
+	if len(defaultArg) == 1 && !isCallable(defaultArg[0]) {
+		return newError("default of `select` must be FUNCTION, got %s", defaultArg[0].Type())
+	}
+
+	i, value, ok, err := c.shared.scheduler.Select(cases, len(defaultArg) == 0)
+	if err != nil {
+		return err
+	}
+	if i < 0 {
+		return c.applyFunction(defaultArg[0], nil)
+	}
+	if cases[i].Send {
+		return c.applyFunction(handlers[i], nil)
+	}
+	if !ok {
+		value = NULL
+	}
+	return c.applyFunction(handlers[i], []object.Object{value})
+}
+
+func channelArg(name string, arg object.Object) (*object.Channel, *object.Error) {
+	ch, ok := arg.(*object.Channel)
+	if !ok {
+		return nil, newError("argument to `%s` must be CHANNEL, got %s", name, arg.Type())
+	}
+	return ch, nil
+}
@@ -0,0 +1,111 @@
+package evaluator
+
+import (
+	"bufio"
+	"io"
+	"monkey_interpreter/ast"
+	"monkey_interpreter/object"
+	"monkey_interpreter/resolver"
+	"sort"
+	"sync"
+)
+
+// Context holds the state of a single evaluation - the streams used by the I/O builtins and the
+// builtins bound to them. A Context evaluates one program at a time, but independent Contexts can
+// run concurrently, sharing values through frozen environments
+type Context struct {
+	Stdout io.Writer
+	Stderr io.Writer
+	Stdin  *bufio.Reader
+
+	// SearchPath lists the directories imports are looked up in when they are not found next to
+	// the importing file
+	SearchPath []string
+
+	// Debugger is called before every statement when set
+	Debugger Debugger
+
+	// Tracer is told about every node evaluated and function applied when set
+	Tracer Tracer
+
+	builtins map[string]*object.BuiltIn
+	shared   *shared
+	files    []string            // absolute paths of the files being evaluated, innermost last
+	frames   []*Frame            // only tracked while debugging
+	call     *ast.CallExpression // the call expression whose function is about to be applied
+	running  bool                // whether the goroutine of the Context is counted by the scheduler
+}
+
+// shared is the state of an evaluation common to its Context and the ones of the functions it
+// spawns on other goroutines
+type shared struct {
+	scheduler *object.Scheduler
+	mu        sync.Mutex                // guards modules
+	modules   map[string]*object.Module // evaluated modules by absolute path
+	output    sync.Mutex                // serializes the writes to Stdout and Stderr
+	input     sync.Mutex                // serializes the reads of Stdin
+}
+
+func NewContext(stdin io.Reader, stdout, stderr io.Writer) *Context {
+	reader, ok := stdin.(*bufio.Reader)
+	if !ok {
+		reader = bufio.NewReader(stdin)
+	}
+	c := &Context{
+		Stdout: stdout,
+		Stderr: stderr,
+		Stdin:  reader,
+		shared: &shared{
+			scheduler: object.NewScheduler(),
+			modules:   make(map[string]*object.Module),
+		},
+	}
+	c.bindBuiltins()
+	return c
+}
+
+// fork returns a Context for a function spawned on another goroutine. It shares the streams and the
+// modules, but not the Debugger and the Tracer which are not safe for concurrent use
+func (c *Context) fork() *Context {
+	f := &Context{
+		Stdout:     c.Stdout,
+		Stderr:     c.Stderr,
+		Stdin:      c.Stdin,
+		SearchPath: c.SearchPath,
+		shared:     c.shared,
+		files:      append([]string{}, c.files...),
+		// The scheduler counts the goroutine from when it is spawned
+		running: true,
+	}
+	f.bindBuiltins()
+	return f
+}
+
+func (c *Context) bindBuiltins() {
+	c.builtins = make(map[string]*object.BuiltIn)
+	for _, group := range []map[string]*object.BuiltIn{builtins, c.collectionBuiltins(), c.ioBuiltins(), c.assertBuiltins(), c.concurrencyBuiltins(), jsonBuiltins, stringBuiltins} {
+		for name, builtin := range group {
+			c.builtins[name] = &object.BuiltIn{Name: name, Fn: builtin.Fn}
+		}
+	}
+}
+
+// Resolve binds the variables of program for evaluation in env, treating the builtins and the
+// variables already defined in env and the environments enclosing it as declared
+func (c *Context) Resolve(program *ast.Program, env *object.Environment) []*resolver.Error {
+	names := c.BuiltinNames()
+	for scope := env; scope != nil; scope = scope.Outer(1) {
+		names = append(names, scope.Names()...)
+	}
+	return resolver.Resolve(program, names)
+}
+
+// BuiltinNames returns the sorted names of the builtins available to programs
+func (c *Context) BuiltinNames() []string {
+	names := make([]string, 0, len(c.builtins))
+	for name := range c.builtins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
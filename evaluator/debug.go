@@ -0,0 +1,75 @@
+package evaluator
+
+import (
+	"monkey_interpreter/ast"
+	"monkey_interpreter/object"
+	"path/filepath"
+)
+
+// Debugger is called by a Context before every statement it evaluates, and may block there to
+// let a user inspect the program. Returning an error stops the evaluation with it
+type Debugger interface {
+	Statement(c *Context, stmt ast.Statement, env *object.Environment) error
+}
+
+// Frame is a file or a call of a user function being evaluated. Frames are only tracked while a
+// Debugger is set
+type Frame struct {
+	Name      string              // the callee as written at the call site, or the base name of a file
+	File      string              // absolute path of the file the code comes from, empty for the REPL
+	Call      *ast.CallExpression // nil for files and functions called by builtins
+	Function  *object.Function    // nil for files
+	Env       *object.Environment
+	Statement ast.Statement // the statement being evaluated, nil until the first one starts
+}
+
+// Stack returns the frames being evaluated, the innermost last
+func (c *Context) Stack() []*Frame {
+	return append([]*Frame{}, c.frames...)
+}
+
+// debugStatement records the statement being evaluated in the current frame and calls the
+// Debugger. The result is nil or the error the evaluation stops with
+func (c *Context) debugStatement(stmt ast.Statement, env *object.Environment) object.Object {
+	if len(c.frames) == 0 {
+		// Code evaluated without EvalFile, like the REPL
+		c.frames = append(c.frames, &Frame{Name: "<main>", Env: env})
+	}
+	frame := c.frames[len(c.frames)-1]
+	frame.Statement, frame.Env = stmt, env
+	if err := c.Debugger.Statement(c, stmt, env); err != nil {
+		return &object.Error{Message: err.Error(), Err: err}
+	}
+	return nil
+}
+
+func (c *Context) pushFileFrame(path string, env *object.Environment) {
+	c.frames = append(c.frames, &Frame{Name: filepath.Base(path), File: path, Env: env})
+}
+
+func (c *Context) pushCallFrame(call *ast.CallExpression, fn *object.Function, env *object.Environment) {
+	c.frames = append(c.frames, &Frame{Name: callName(call), File: fn.File, Call: call, Function: fn, Env: env})
+}
+
+// callName returns the callee as written at a call site, or fn for functions called by builtins
+func callName(call *ast.CallExpression) string {
+	if call == nil {
+		return "fn"
+	}
+	if member, ok := call.Function.(*ast.MemberExpression); ok {
+		return member.Object.String() + "." + member.Member.Value
+	}
+	return call.Function.String()
+}
+
+func (c *Context) popFrame() {
+	c.frames = c.frames[:len(c.frames)-1]
+}
+
+// currentFile returns the file of the innermost frame, where function literals are being defined
+func (c *Context) currentFile() string {
+	if len(c.frames) == 0 {
+		return ""
+	}
+	return c.frames[len(c.frames)-1].File
+}
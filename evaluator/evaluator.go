@@ -3,6 +3,7 @@ package evaluator
 import (
 	"monkey_interpreter/ast"
 	"monkey_interpreter/object"
+	"os"
 )
 
 var (
@@ -11,46 +12,94 @@ var (
 	NULL  = &object.Null{}
 )
 
+// Eval evaluates the node in a new Context bound to the process standard streams, so concurrent
+// calls do not share any state
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	return NewContext(os.Stdin, os.Stdout, os.Stderr).Eval(node, env)
+}
+
+func (c *Context) Eval(node ast.Node, env *object.Environment) object.Object {
+	if !c.running {
+		return c.run(func() object.Object { return c.Eval(node, env) })
+	}
+	if c.Tracer == nil {
+		return c.eval(node, env)
+	}
+	c.Tracer.Enter(node)
+	res := c.eval(node, env)
+	c.Tracer.Exit(node, res)
+	return res
+}
+
+// Call applies a user function or a builtin to args, like a call expression without a call site
+func (c *Context) Call(fn object.Object, args ...object.Object) object.Object {
+	if !c.running {
+		return c.run(func() object.Object { return c.applyFunction(fn, args) })
+	}
+	return c.applyFunction(fn, args)
+}
+
+// run evaluates f on the goroutine of the evaluation, which is live for the scheduler until f
+// returns. The goroutines it spawned and left blocked fail with a deadlock then
+func (c *Context) run(f func() object.Object) object.Object {
+	c.running = true
+	c.shared.scheduler.Enter()
+	defer func() {
+		c.running = false
+		c.shared.scheduler.Leave()
+	}()
+	return f()
+}
+
+func (c *Context) eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	case *ast.Program:
-		return evalProgram(node, env)
+		return c.evalProgram(node, env)
 	case *ast.ExpressionStatement:
-		return Eval(node.Expression, env)
+		return c.Eval(node.Expression, env)
 	case *ast.LetStatement:
-		val := Eval(node.Value, env)
+		val := c.Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
-		env.Set(node.Name.Value, val)
+		if node.Name.Resolution.Kind == ast.Local {
+			env.SetSlot(node.Name.Resolution.Slot, val)
+		} else {
+			if env.Frozen() {
+				return frozenError(node.Name.Value)
+			}
+			env.Set(node.Name.Value, val)
+		}
+	case *ast.ImportStatement:
+		return c.evalImportStatement(node, env)
 	case *ast.Identifier:
-		return evalIdentifier(node, env)
+		return c.evalIdentifier(node, env)
 	case *ast.PrefixExpression:
-		right := Eval(node.Right, env)
+		right := c.Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
 		return evalPrefixExpression(node.Operator, right)
 	case *ast.InfixExpression:
-		left := Eval(node.LeftValue, env)
+		left := c.Eval(node.LeftValue, env)
 		if isError(left) {
 			return left
 		}
-		right := Eval(node.RightValue, env)
+		right := c.Eval(node.RightValue, env)
 		if isError(right) {
 			return right
 		}
 		return evalInfixExpression(left, node.Operator, right)
 	case *ast.BlockStatement:
-		return evalBlockStatement(node, env)
+		return c.evalBlockStatement(node, env)
 	case *ast.ReturnStatement:
-		val := Eval(node.ReturnValue, env)
+		val := c.Eval(node.ReturnValue, env)
 		if isError(val) {
 			return val
 		}
 		return &object.ReturnValue{Value: val}
 	case *ast.IfExpression:
-		return evalIfExpression(node, env)
+		return c.evalIfExpression(node, env)
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
 	case *ast.StringLiteral:
@@ -64,19 +113,27 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			Parameters: params,
 			Body:       body,
 			Env:        env,
+			Locals:     node.Locals,
+			File:       c.currentFile(),
 		}
+	case *ast.MacroLiteral:
+		return newError("macro literals can only be bound by a top level let statement")
 	case *ast.CallExpression:
-		function := Eval(node.Function, env)
+		if isCallTo(node, "quote") {
+			return c.quote(node.Arguments, env)
+		}
+		function := c.Eval(node.Function, env)
 		if isError(function) {
 			return function
 		}
-		args := evalExpressions(node.Arguments, env)
+		args := c.evalExpressions(node.Arguments, env)
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return applyFunction(function, args)
+		c.call = node
+		return c.applyFunction(function, args)
 	case *ast.ArrayLiteral:
-		arr := evalExpressions(node.Elements, env)
+		arr := c.evalExpressions(node.Elements, env)
 		if len(arr) == 1 && isError(arr[0]) {
 			return arr[0]
 		}
@@ -84,17 +141,25 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			Elements: arr,
 		}
 	case *ast.IndexExpression:
-		left := Eval(node.Left, env)
+		left := c.Eval(node.Left, env)
 		if isError(left) {
 			return left
 		}
-		idx := Eval(node.Index, env)
+		idx := c.Eval(node.Index, env)
 		if isError(idx) {
 			return idx
 		}
 		return evalIndexExpression(left, idx)
+	case *ast.MemberExpression:
+		obj := c.Eval(node.Object, env)
+		if isError(obj) {
+			return obj
+		}
+		return evalMemberExpression(obj, node.Member)
+	case *ast.SliceExpression:
+		return c.evalSliceExpression(node, env)
 	case *ast.HashLiteral:
-		return evalHashLiteral(node, env)
+		return c.evalHashLiteral(node, env)
 	}
 	return nil
 }
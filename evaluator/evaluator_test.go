@@ -1,11 +1,21 @@
 package evaluator
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
 	"github.com/stretchr/testify/assert"
+	"io"
+	"monkey_interpreter/ast"
 	"monkey_interpreter/lexer"
 	"monkey_interpreter/object"
 	"monkey_interpreter/parser"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestEvalIntegerExpression(t *testing.T) {
@@ -46,6 +56,19 @@ func testIntegerObject(t *testing.T, obj object.Object, exp int64) {
 	assert.Equal(t, exp, res.Value)
 }
 
+func TestEvalConcurrently(t *testing.T) {
+	program := parser.New(lexer.New("let f = fn(x) { x * 2 }; f(21)")).ParseProgram()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Equal(t, "42", Eval(program, object.NewEnvironment()).Inspect())
+		}()
+	}
+	wg.Wait()
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -70,6 +93,11 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{`"a" < "b"`, true},
+		{`"b" < "a"`, false},
+		{`"abc" > "abd"`, false},
+		{`"b" > "abc"`, true},
+		{`"a" < "a"`, false},
 	}
 	for _, test := range tests {
 		l := lexer.New(test.input)
@@ -280,6 +308,7 @@ func TestFunctionApplication(t *testing.T) {
 		{"let add = fn(x, y) { x + y; }; add(5, 5);", 10},
 		{"let add = fn(x, y) { x + y; }; add(5 + 5, add(5, 5));", 20},
 		{"fn(x) { x; }(5)", 5},
+		{"let first = fn(x) { x; }; first(5, 6);", 5},
 	}
 
 	for _, test := range tests {
@@ -293,6 +322,18 @@ func TestFunctionApplication(t *testing.T) {
 	}
 }
 
+func TestFunctionWithoutValue(t *testing.T) {
+	tests := []string{
+		"let f = fn() { let x = 1; }; f()",
+		"fn() {}()",
+		"let f = fn() { if (false) { 1 } }; f()",
+	}
+	for _, input := range tests {
+		eval := Eval(parser.New(lexer.New(input)).ParseProgram(), object.NewEnvironment())
+		assert.Equal(t, NULL, eval, input)
+	}
+}
+
 func TestStringLiteral(t *testing.T) {
 	input := `"Hello, World!"`
 	l := lexer.New(input)
@@ -349,6 +390,7 @@ func TestBuiltInFunctions(t *testing.T) {
 		{`last([1, 2, 3, 4, 5 * 5 + 5])`, 30},
 		{`let a = [1, 2, 3, 4]; rest(a)`, []int{2, 3, 4}},
 		{`let a = [1, 2, 3, 4]; let b = push(a, 5); push(b, 6)`, []int{1, 2, 3, 4, 5, 6}},
+		{`let a = push(push(push([], 1), 2), 3); let b = push(a, 4); let c = push(a, 5); b`, []int{1, 2, 3, 4}},
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
 	}
@@ -459,9 +501,11 @@ func TestHashLiterals(t *testing.T) {
 		TRUE.HashKey():                             5,
 		FALSE.HashKey():                            6,
 	}
-	assert.Equal(t, len(expected), len(hashLit.Pairs))
+	assert.Equal(t, len(expected), hashLit.Len())
 	for key, val := range expected {
-		testIntegerObject(t, hashLit.Pairs[key].Value, val)
+		pair, ok := hashLit.Get(key)
+		assert.True(t, ok)
+		testIntegerObject(t, pair.Value, val)
 	}
 }
 
@@ -516,3 +560,1045 @@ func TestHashIndexExpression(t *testing.T) {
 		}
 	}
 }
+
+func TestNegativeIndexExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   interface{}
+	}{
+		{"[1, 2, 3][-1]", 3},
+		{"[1, 2, 3][-3]", 1},
+		{"[1, 2, 3][-4]", nil},
+		{"[1, 2, 3][3]", nil},
+		{`"abc"[0]`, "a"},
+		{`"abc"[-1]`, "c"},
+		{`"abc"[5]`, nil},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		env := object.NewEnvironment()
+		eval := Eval(program, env)
+
+		switch expected := test.exp.(type) {
+		case int:
+			testIntegerObject(t, eval, int64(expected))
+		case string:
+			strObj, ok := eval.(*object.String)
+			assert.True(t, ok)
+			assert.Equal(t, expected, strObj.Value)
+		default:
+			testNullObject(t, eval)
+		}
+	}
+}
+
+func TestSliceExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   interface{}
+	}{
+		{"[1, 2, 3, 4, 5][1:3]", []int{2, 3}},
+		{"[1, 2, 3, 4, 5][:2]", []int{1, 2}},
+		{"[1, 2, 3, 4, 5][3:]", []int{4, 5}},
+		{"[1, 2, 3, 4, 5][:]", []int{1, 2, 3, 4, 5}},
+		{"[1, 2, 3, 4, 5][-2:]", []int{4, 5}},
+		{"[1, 2, 3, 4, 5][:-2]", []int{1, 2, 3}},
+		{"[1, 2, 3, 4, 5][::2]", []int{1, 3, 5}},
+		{"[1, 2, 3, 4, 5][1:4:2]", []int{2, 4}},
+		{"[1, 2, 3, 4, 5][::-1]", []int{5, 4, 3, 2, 1}},
+		{"[1, 2, 3, 4, 5][3:0:-1]", []int{4, 3, 2}},
+		{"[1, 2, 3, 4, 5][-100:100]", []int{1, 2, 3, 4, 5}},
+		{"[1, 2, 3, 4, 5][4:1]", []int{}},
+		{"[1, 2, 3][0:3:9223372036854775807]", []int{1}},
+		{"[1, 2, 3, 4, 5, 6][5::-9223372036854775807]", []int{6}},
+		{"[1, 2, 3, 4, 5, 6][5::-9223372036854775807 - 1]", []int{6}},
+		{"[1, 2, 3, 4, 5, 6][-9223372036854775807 - 1:9223372036854775807:9223372036854775807]", []int{1}},
+		{"[][::-9223372036854775807 - 1]", []int{}},
+		{`"abc"[::-9223372036854775807 - 1]`, "c"},
+		{"let a = [1, 2, 3]; let i = 1; a[i:i + 1]", []int{2}},
+		{`"Hello, World!"[0:5]`, "Hello"},
+		{`"Hello, World!"[-6:]`, "World!"},
+		{`"abc"[::-1]`, "cba"},
+		{`"abc"[1:1]`, ""},
+		{"[1, 2, 3][::0]", errors.New("slice step cannot be zero")},
+		{`[1, 2, 3]["a":]`, errors.New("slice index must be INTEGER, got STRING")},
+		{"5[1:]", errors.New("slice operator not supported: INTEGER")},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		env := object.NewEnvironment()
+		eval := Eval(program, env)
+
+		switch expected := test.exp.(type) {
+		case []int:
+			arr, ok := eval.(*object.Array)
+			assert.True(t, ok)
+			arrVals := []int{}
+			for _, elem := range arr.Elements {
+				arrVals = append(arrVals, int(elem.(*object.Integer).Value))
+			}
+			assert.Equal(t, expected, arrVals)
+		case string:
+			strObj, ok := eval.(*object.String)
+			assert.True(t, ok)
+			assert.Equal(t, expected, strObj.Value)
+		case error:
+			errObj, ok := eval.(*object.Error)
+			assert.True(t, ok)
+			assert.Equal(t, expected.Error(), errObj.Message)
+		}
+	}
+}
+
+func TestHashInspectOrder(t *testing.T) {
+	input := `let h = {"b": 2, "a": 1, 3: [3], true: "t", "b": 20}; h`
+	l := lexer.New(input)
+	p := parser.New(l)
+	program := p.ParseProgram()
+	env := object.NewEnvironment()
+	eval := Eval(program, env)
+
+	hashLit, ok := eval.(*object.Hash)
+	assert.True(t, ok)
+
+	assert.Equal(t, 4, hashLit.Len())
+	assert.Equal(t, "{b : 20, a : 1, 3 : [3], true : t}", hashLit.Inspect())
+}
+
+func TestHashBuiltInFunctions(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   interface{}
+	}{
+		{`keys({"b": 1, "a": 2, 3: 3})`, "[b, a, 3]"},
+		{`keys({})`, "[]"},
+		{`values({"b": 1, "a": 2, 3: 3})`, "[1, 2, 3]"},
+		{`entries({"b": 1, true: if (false) { 1 }})`, "[[b, 1], [true, null]]"},
+		{`has({"a": if (false) { 1 }}, "a")`, "true"},
+		{`has({"a": 1}, "b")`, "false"},
+		{`delete({"a": 1, "b": 2, "c": 3}, "b")`, "{a : 1, c : 3}"},
+		{`delete({"a": 1}, "b")`, "{a : 1}"},
+		{`let h = {"a": 1}; let d = delete(h, "a"); h`, "{a : 1}"},
+		{`put({"a": 1}, "b", 2)`, "{a : 1, b : 2}"},
+		{`put({"a": 1, "b": 2}, "a", 3)`, "{a : 3, b : 2}"},
+		{`let h = {"a": 1}; let p = put(h, "b", 2); h`, "{a : 1}"},
+		{`merge({"a": 1, "b": 2}, {"b": 3, "c": 4})`, "{a : 1, b : 3, c : 4}"},
+		{`merge({"a": 1}, {}, {"a": 2})`, "{a : 2}"},
+		{`keys([1])`, errors.New("argument to `keys` not supported, got ARRAY")},
+		{`has({}, fn(x) { x })`, errors.New("unusable as hash key: FUNCTION")},
+		{`put({}, [1], 1)`, errors.New("unusable as hash key: ARRAY")},
+		{`delete({}, {})`, errors.New("unusable as hash key: HASH")},
+		{`merge({"a": 1}, 1)`, errors.New("argument to `merge` not supported, got INTEGER")},
+		{`merge({})`, errors.New("wrong number of arguments. got=1, want at least 2")},
+		{`{"a": 1}[[1]]`, errors.New("unusable as hash key: ARRAY")},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		env := object.NewEnvironment()
+		eval := Eval(program, env)
+
+		switch expected := test.exp.(type) {
+		case string:
+			assert.Equal(t, expected, eval.Inspect())
+		case error:
+			errObj, ok := eval.(*object.Error)
+			assert.True(t, ok)
+			assert.Equal(t, expected.Error(), errObj.Message)
+		}
+	}
+}
+
+func TestCollectionBuiltInFunctions(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   interface{}
+	}{
+		{`map([1, 2, 3], fn(x) { x * 2 })`, "[2, 4, 6]"},
+		{`map([1, 2, 3], fn(x) { return x * 2; })`, "[2, 4, 6]"},
+		{`map([], fn(x) { x })`, "[]"},
+		{`map([[1], [2, 3]], len)`, "[1, 2]"},
+		{`filter([1, 2, 3, 4], fn(x) { x > 2 })`, "[3, 4]"},
+		{`reduce([1, 2, 3, 4], fn(acc, x) { acc + x })`, "10"},
+		{`reduce([1, 2, 3], fn(acc, x) { push(acc, x * x) }, [])`, "[1, 4, 9]"},
+		{`reduce([], fn(acc, x) { acc + x }, 0)`, "0"},
+		{`any([1, 2, 3], fn(x) { x == 2 })`, "true"},
+		{`any([], fn(x) { true })`, "false"},
+		{`all([1, 2, 3], fn(x) { x > 0 })`, "true"},
+		{`all([1, 2, 3], fn(x) { x > 1 })`, "false"},
+		{`find([1, 2, 3, 4], fn(x) { x > 2 })`, "3"},
+		{`find([1, 2], fn(x) { x > 2 })`, "null"},
+		{`sort([3, 1, 2])`, "[1, 2, 3]"},
+		{`sort(["b", "c", "a"])`, "[a, b, c]"},
+		{`sort([3, 1, 2], fn(a, b) { a > b })`, "[3, 2, 1]"},
+		{`sort([3, 1, 2], fn(a, b) { b - a })`, "[3, 2, 1]"},
+		{`let a = [3, 1, 2]; let s = sort(a); a`, "[3, 1, 2]"},
+		{`reverse([1, 2, 3])`, "[3, 2, 1]"},
+		{`reverse("abc")`, "cba"},
+		{`zip([1, 2, 3], ["a", "b"])`, "[[1, a], [2, b]]"},
+		{`zip([1], [2], [3])`, "[[1, 2, 3]]"},
+		{`range(4)`, "[0, 1, 2, 3]"},
+		{`range(2, 5)`, "[2, 3, 4]"},
+		{`range(5, 0, -2)`, "[5, 3, 1]"},
+		{`range(0)`, "[]"},
+		{`range(9223372036854775800, 9223372036854775807, 10)`, "[9223372036854775800]"},
+		{`range(9223372036854775807, -9223372036854775807 - 1, -9223372036854775807 - 1)`, "[9223372036854775807, -1]"},
+		{`range(-9223372036854775807 - 1, 9223372036854775807, 9223372036854775807)`, "[-9223372036854775808, -1, 9223372036854775806]"},
+		{`flatten([1, [2, [3, [4]]], 5])`, "[1, 2, 3, 4, 5]"},
+		{`flatten([1, [2, [3, [4]]]], 1)`, "[1, 2, [3, [4]]]"},
+		{`let double = fn(x) { x * 2 }; let inc = fn(x) { x + 1 }; map(map([1, 2], double), inc)`, "[3, 5]"},
+		{`map(1, fn(x) { x })`, errors.New("argument to `map` not supported, got INTEGER")},
+		{`filter([1], 1)`, errors.New("argument to `filter` must be FUNCTION, got INTEGER")},
+		{`map([1], fn(x, y) { x })`, errors.New("wrong number of arguments. got=1, want=2")},
+		{`map([1, true], fn(x) { -x })`, errors.New("unknown operator: -BOOLEAN")},
+		{`reduce([], fn(acc, x) { acc })`, errors.New("reduce of empty array with no initial value")},
+		{`sort([1, "a"])`, errors.New("cannot compare STRING and INTEGER")},
+		{`sort([1, 2], fn(a, b) { "a" })`, errors.New("sort comparator must return BOOLEAN or INTEGER, got STRING")},
+		{`range(1, 2, 0)`, errors.New("range step cannot be zero")},
+		{`range(67108865)`, errors.New("result of `range` would have more than 67108864 elements")},
+		{`range(0, 1099511627776)`, errors.New("result of `range` would have more than 67108864 elements")},
+		{`zip([1], "a")`, errors.New("argument to `zip` not supported, got STRING")},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		env := object.NewEnvironment()
+		eval := Eval(program, env)
+
+		switch expected := test.exp.(type) {
+		case string:
+			assert.Equal(t, expected, eval.Inspect(), test.input)
+		case error:
+			errObj, ok := eval.(*object.Error)
+			assert.True(t, ok, test.input)
+			assert.Equal(t, expected.Error(), errObj.Message)
+		}
+	}
+}
+
+func TestIOBuiltInFunctions(t *testing.T) {
+	tests := []struct {
+		input     string
+		stdin     string
+		expStdout string
+		expStderr string
+		exp       string
+	}{
+		{`puts("a", 1, [2])`, "", "a\n1\n[2]\n", "", "null"},
+		{`print("a", 1); print("b")`, "", "a 1b", "", "null"},
+		{`eprint("oops", 1)`, "", "", "oops 1", "null"},
+		{`readline()`, "first\nsecond\n", "", "", "first"},
+		{`let a = readline(); let b = readline(); b + a`, "first\r\nsecond", "", "", "secondfirst"},
+		{`readline()`, "", "", "", "null"},
+		{`input("name: ")`, "monkey\n", "name: ", "", "monkey"},
+		{`readline(1)`, "", "", "", "Error: wrong number of arguments. got=1, want=0"},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		env := object.NewEnvironment()
+		var stdout, stderr bytes.Buffer
+		ctx := NewContext(strings.NewReader(test.stdin), &stdout, &stderr)
+		eval := ctx.Eval(program, env)
+
+		assert.Equal(t, test.exp, eval.Inspect())
+		assert.Equal(t, test.expStdout, stdout.String())
+		assert.Equal(t, test.expStderr, stderr.String())
+	}
+}
+
+func TestAssertBuiltInFunctions(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{`assert(1 < 2)`, "null"},
+		{`assert(false)`, "Error: assertion failed: expected a truthy value, got false"},
+		{`assert(first([]), "lookup")`, "Error: assertion failed: lookup: expected a truthy value, got null"},
+		{`assert_eq([1, {"a": "b"}], [1, {"a": "b"}])`, "null"},
+		{`assert_eq({"a": 1, "b": 2}, {"b": 2, "a": 1})`, "null"},
+		{`assert_eq("a", "a")`, "null"},
+		{`assert_eq([1, 2], [1, 3])`, "Error: assertion failed: expected [1, 3], got [1, 2]"},
+		{`assert_eq(1, "1", "sum")`, "Error: assertion failed: sum: expected 1, got 1"},
+		{`let f = fn() { 1 }; assert_eq(f, f)`, "null"},
+		{`assert_throws(fn() { 1 + true })`, "type mismatch: INTEGER + BOOLEAN"},
+		{`assert_throws(fn() { 1 + true }, "mismatch")`, "type mismatch: INTEGER + BOOLEAN"},
+		{`assert_throws(fn() { 1 + true }, "unknown")`, `Error: assertion failed: expected an error containing "unknown", got "type mismatch: INTEGER + BOOLEAN"`},
+		{`assert_throws(fn() { return [1]; })`, "Error: assertion failed: expected an error, got [1]"},
+		{`assert_throws(fn() { let x = 1; })`, "Error: assertion failed: expected an error, got null"},
+		{`assert_throws(fn() { assert(false) })`, "assertion failed: expected a truthy value, got false"},
+		{`assert_throws(1)`, "Error: argument to `assert_throws` must be FUNCTION, got INTEGER"},
+		{`assert(true, 1)`, "Error: argument to `assert` must be STRING, got INTEGER"},
+		{`assert_eq(1)`, "Error: wrong number of arguments. got=1, want=2 or 3"},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		eval := Eval(program, object.NewEnvironment())
+		assert.Equal(t, test.exp, eval.Inspect(), test.input)
+		if err, ok := eval.(*object.Error); ok {
+			assert.Equal(t, strings.HasPrefix(err.Message, "assertion failed"), err.Assertion, test.input)
+		}
+	}
+}
+
+func TestStringBuiltInFunctions(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{`split("a,b,,c", ",")`, "[a, b, , c]"},
+		{`split("héllo", "")`, "[h, é, l, l, o]"},
+		{`join(["a", "b", "c"], ", ")`, "a, b, c"},
+		{`join([], "-")`, ""},
+		{`join(["a", 1], "-")`, "Error: elements of the array passed to `join` must be STRING, got INTEGER"},
+		{`trim("  a b  ") + "|"`, "a b|"},
+		{`trim_left("  a  ") + "|"`, "a  |"},
+		{`trim_right("  a  ") + "|"`, "  a|"},
+		{`upper("abc") + lower("DEF")`, "ABCdef"},
+		{`contains("monkey", "key")`, "true"},
+		{`contains("monkey", "ape")`, "false"},
+		{`starts_with("monkey", "mon")`, "true"},
+		{`ends_with("monkey", "mon")`, "false"},
+		{`index_of("monkey", "key")`, "3"},
+		{`index_of("monkey", "ape")`, "-1"},
+		{`replace("a-b-c", "-", "+")`, "a+b+c"},
+		{`repeat("ab", 3)`, "ababab"},
+		{`repeat("ab", -1)`, "Error: count of `repeat` cannot be negative, got -1"},
+		{`repeat("", 9223372036854775807)`, ""},
+		{`repeat("ab", 9223372036854775807)`, "Error: result of `repeat` would be longer than 67108864 bytes"},
+		{`repeat("ab", 33554433)`, "Error: result of `repeat` would be longer than 67108864 bytes"},
+		{`len(repeat("ab", 33554432))`, "67108864"},
+		{`pad("7", 3, "0")`, "007"},
+		{`pad("a", -3) + "|"`, "a  |"},
+		{`pad("long", 2)`, "long"},
+		{`pad("a", -9223372036854775807 - 1)`, "Error: width of `pad` must be between -67108864 and 67108864, got -9223372036854775808"},
+		{`pad("a", 67108865)`, "Error: width of `pad` must be between -67108864 and 67108864, got 67108865"},
+		{`pad("a", 3, "ab")`, `Error: fill of ` + "`pad`" + ` must be a single character, got "ab"`},
+		{`format("%s is %d, %v %v", "x", 42, [1, "a"], true)`, "x is 42, [1, a] true"},
+		{`format("%5s|%-4d|%03d|%x|%q|100%%", "ab", 7, 5, 255, "hi")`, `   ab|7   |005|ff|"hi"|100%`},
+		{`format("%d", "a")`, "Error: format: %d needs INTEGER, got STRING"},
+		{`format("%s %s", "a")`, "Error: format: missing argument for %s"},
+		{`format("%s", "a", "b")`, "Error: format: too many arguments. got=2, want=1"},
+		{`format("%y", 1)`, "Error: format: unknown verb 'y'"},
+		{`format("50%")`, `Error: format: missing verb at the end of "50%"`},
+		{`chars("abc")`, "[a, b, c]"},
+		{`len(chars("héllo"))`, "5"},
+		{`upper(1)`, "Error: argument to `upper` must be STRING, got INTEGER"},
+		{`split("a")`, "Error: wrong number of arguments. got=1, want=2"},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		eval := Eval(program, object.NewEnvironment())
+		assert.Equal(t, test.exp, eval.Inspect(), test.input)
+	}
+}
+
+func TestJSONBuiltInFunctions(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{`json_encode({"b": [1, true, first([])], "a": "x<y"})`, `{"b":[1,true,null],"a":"x<y"}`},
+		{`json_encode([])`, `[]`},
+		{`json_encode([map([1], fn(x) { let y = x; })])`, `[[null]]`},
+		{`json_encode({"a": [1], "b": {}}, 2)`, "{\n  \"a\": [\n    1\n  ],\n  \"b\": {}\n}"},
+		{`json_encode([1], "  ")`, "[\n  1\n]"},
+		{`json_decode(json_encode({"z": -3, "a": ["s", false]}))`, "{z : -3, a : [s, false]}"},
+		{`json_decode(json_encode(first([]))) == first([])`, "true"},
+		{`json_decode(json_encode(true)) == true`, "true"},
+		{`json_encode(fn(x) { x })`, "Error: cannot encode FUNCTION as JSON"},
+		{`json_encode([len])`, "Error: cannot encode BUILTIN as JSON"},
+		{`json_encode({1: 2})`, "Error: cannot encode hash key 1 as JSON, keys must be STRING, got INTEGER"},
+		{`json_encode(1, -1)`, "Error: indent of `json_encode` must be between 0 and 16, got -1"},
+		{`json_encode([1], 16)`, "[\n                1\n]"},
+		{`json_encode(1, 9223372036854775807)`, "Error: indent of `json_encode` must be between 0 and 16, got 9223372036854775807"},
+		{`json_encode(1, true)`, "Error: indent of `json_encode` must be INTEGER or STRING, got BOOLEAN"},
+		{`json_decode(1)`, "Error: argument to `json_decode` must be STRING, got INTEGER"},
+		{`json_decode("")`, "Error: invalid JSON: unexpected end of JSON input at offset 0"},
+		{`json_decode("[1, 2")`, "Error: invalid JSON: unexpected end of JSON input at offset 5"},
+		{`json_decode("1 2")`, "Error: invalid JSON: invalid character '2' after top-level value at offset 3"},
+		{`json_decode("[1,]")`, "Error: invalid JSON: invalid character ']' looking for beginning of value at offset 4"},
+		{`json_decode("1.5")`, "Error: cannot decode JSON number 1.5, only 64-bit integers are supported"},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		eval := Eval(program, object.NewEnvironment())
+		assert.Equal(t, test.exp, eval.Inspect(), test.input)
+	}
+
+	// Monkey strings have no escapes, so documents with strings are passed from Go
+	decode := jsonBuiltins["json_decode"].Fn
+	decoded := decode(&object.String{Value: `{"name": "monkey", "tags": ["a\"b", "é"], "n": 12, "name": "gorilla", "ok": null}`})
+	assert.Equal(t, `{name : gorilla, tags : [a"b, é], n : 12, ok : null}`, decoded.Inspect())
+	encoded := jsonBuiltins["json_encode"].Fn(decoded)
+	assert.Equal(t, `{"name":"gorilla","tags":["a\"b","é"],"n":12,"ok":null}`, encoded.Inspect())
+	// Elements without a value are encoded like null
+	encoded = jsonBuiltins["json_encode"].Fn(&object.Array{Elements: []object.Object{nil}})
+	assert.Equal(t, `[null]`, encoded.Inspect())
+	assert.Equal(t, "Error: invalid JSON: invalid character '}' after object key at offset 6",
+		decode(&object.String{Value: `{"a" }`}).Inspect())
+}
+
+func TestConcurrencyBuiltInFunctions(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{`recv(spawn(fn(a, b) { a + b }, 1, 2))`, "3"},
+		{`let r = spawn(fn() { 1 }); recv(r); recv(r)`, "null"},
+		{`recv(spawn(fn() { 1 + true }))`, "Error: type mismatch: INTEGER + BOOLEAN"},
+		{`let ch = channel(); spawn(fn() { send(ch, 1); send(ch, 2); close(ch) }); [recv(ch), recv(ch), recv(ch)]`, "[1, 2, null]"},
+		{`let ch = channel(2); send(ch, "a"); send(ch, "b"); close(ch); [recv(ch), recv(ch), recv(ch)]`, "[a, b, null]"},
+		{`let square = fn(x) { x * x }; let rs = map([1, 2, 3, 4], fn(x) { spawn(square, x) }); map(rs, recv)`, "[1, 4, 9, 16]"},
+		{`let ch = channel(); let r = spawn(fn() { reduce(range(10), fn(acc, x) { send(ch, x); acc + x }, 0) }); reduce(range(10), fn(acc, x) { acc + recv(ch) }, 0) + recv(r)`, "90"},
+		{`let a = channel(); let b = channel(1); send(b, 2); select([[a, fn(x) { "a" }], [b, fn(x) { x }]])`, "2"},
+		{`let a = channel(); select([[a, fn(x) { x }]], fn() { "default" })`, "default"},
+		{`let a = channel(1); select([[a, 5, fn() { recv(a) }]])`, "5"},
+		{`let a = channel(); spawn(fn() { send(a, 7) }); select([[a, fn(x) { x * 2 }]])`, "14"},
+		{`let a = channel(); close(a); select([[a, fn(x) { x }]])`, "null"},
+		{`recv(channel())`, "Error: all goroutines are asleep - deadlock!"},
+		{`let ch = channel(); send(ch, 1)`, "Error: all goroutines are asleep - deadlock!"},
+		{`let a = channel(); let b = channel(); spawn(fn() { recv(a) }); recv(b)`, "Error: all goroutines are asleep - deadlock!"},
+		{`let a = channel(); select([[a, fn(x) { x }]])`, "Error: all goroutines are asleep - deadlock!"},
+		{`let ch = channel(); close(ch); send(ch, 1)`, "Error: send on closed channel"},
+		{`let ch = channel(); close(ch); close(ch)`, "Error: close of closed channel"},
+		{`let ch = channel(); spawn(fn() { close(ch) }); send(ch, 1)`, "Error: send on closed channel"},
+		{`channel(-1)`, "Error: channel capacity cannot be negative, got -1"},
+		{`recv(1)`, "Error: argument to `recv` must be CHANNEL, got INTEGER"},
+		{`spawn(1)`, "Error: argument to `spawn` must be FUNCTION, got INTEGER"},
+		{`select([[channel()]])`, "Error: select case must be [channel, fn] or [channel, value, fn], got [channel(0)]"},
+		{`channel(3)`, "channel(3)"},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input)
+		p := parser.New(l)
+		program := p.ParseProgram()
+		eval := Eval(program, object.NewEnvironment())
+		assert.Equal(t, test.exp, eval.Inspect(), test.input)
+	}
+}
+
+func TestSpawnSharesEnvironment(t *testing.T) {
+	input := `
+let results = channel(10);
+let worker = fn(id) { send(results, id * 10); puts(id) };
+let done = map(range(10), fn(id) { spawn(worker, id) });
+map(done, recv);
+close(results);
+let sum = fn(total) {
+  let x = recv(results);
+  if (x == first([])) { total } else { sum(total + x) }
+};
+sum(0)
+`
+	var stdout bytes.Buffer
+	ctx := NewContext(strings.NewReader(""), &stdout, &stdout)
+	res := ctx.Eval(parser.New(lexer.New(input)).ParseProgram(), object.NewEnvironment())
+	assert.Equal(t, "450", res.Inspect())
+	assert.Equal(t, 10, strings.Count(stdout.String(), "\n"))
+}
+
+func TestSpawnSharesValues(t *testing.T) {
+	input := `
+let a = push(push(push([], 1), 2), 3);
+let done = map(range(8), fn(i) { spawn(fn() { push(a, i) }) });
+let pushed = map(done, recv);
+[a, pushed[0], pushed[7]]
+`
+	ctx := NewContext(strings.NewReader(""), io.Discard, io.Discard)
+	res := ctx.Eval(parser.New(lexer.New(input)).ParseProgram(), object.NewEnvironment())
+	assert.Equal(t, "[[1, 2, 3], [1, 2, 3, 0], [1, 2, 3, 7]]", res.Inspect())
+}
+
+func TestSpawnOutlivingProgram(t *testing.T) {
+	ctx := NewContext(strings.NewReader(""), io.Discard, io.Discard)
+	env := object.NewEnvironment()
+	input := "let ch = channel(); let r = spawn(fn() { recv(ch) });"
+	ctx.Eval(parser.New(lexer.New(input)).ParseProgram(), env)
+
+	// Nothing can send on ch once the program returned, so the goroutine fails instead of leaking.
+	// Polling does not count as waiting, a blocking receive out of an evaluation would deadlock
+	r, _ := env.Get("r")
+	var res object.Object
+	for deadline := time.Now().Add(time.Second); res == nil && time.Now().Before(deadline); {
+		_, res, _, _ = ctx.shared.scheduler.Select([]object.SelectCase{{Channel: r.(*object.Channel)}}, false)
+		time.Sleep(time.Millisecond)
+	}
+	assert.NotNil(t, res, "the goroutine is still blocked")
+	if res != nil {
+		assert.Equal(t, "Error: all goroutines are asleep - deadlock!", res.Inspect())
+	}
+}
+
+func TestConcurrentContexts(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"prelude.mk": "import \"./strings.mk\" as strings;\nlet double = fn(x) { x * 2 };\nlet table = {\"a\": 1, \"b\": 2};\n",
+		"strings.mk": "let repeat = fn(s, n) { if (n == 0) { \"\" } else { s + repeat(s, n - 1) } };\n",
+		"main.mk":    "import \"./strings.mk\" as local;\nlet n = len(input());\nputs(strings.repeat(\"ab\", n));\nsort(map(range(n), double), fn(a, b) { a > b })[0] + table[\"b\"] + len(local.repeat(\"x\", n))\n",
+	})
+
+	// The prelude is evaluated once and shared by all the evaluations without copying
+	base := object.NewEnvironment()
+	res := NewContext(strings.NewReader(""), io.Discard, io.Discard).EvalFile(filepath.Join(dir, "prelude.mk"), base)
+	assert.False(t, isError(res), res)
+	base.Freeze()
+
+	const n = 16
+	results := make([]string, n)
+	outputs := make([]string, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var stdout bytes.Buffer
+			ctx := NewContext(strings.NewReader(strings.Repeat("x", i+1)+"\n"), &stdout, &stdout)
+			results[i] = ctx.EvalFile(filepath.Join(dir, "main.mk"), object.NewEnclosedEnvironment(base)).Inspect()
+			outputs[i] = stdout.String()
+		}(i)
+	}
+	wg.Wait()
+	for i := 0; i < n; i++ {
+		// The largest double, the table entry and the length of the repeated string
+		assert.Equal(t, fmt.Sprint(2*i+2+i+1), results[i])
+		assert.Equal(t, strings.Repeat("ab", i+1)+"\n", outputs[i])
+	}
+}
+
+func TestFrozenEnvironmentBindings(t *testing.T) {
+	base := object.NewEnvironment()
+	base.Set("x", &object.Integer{Value: 1})
+	base.Freeze()
+
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"let y = x + 1; y", "2"},
+		{"let x = 5; x", "5"},
+		{"let f = fn() { x * 3 }; f()", "3"},
+	}
+	for _, test := range tests {
+		program := parser.New(lexer.New(test.input)).ParseProgram()
+		env := object.NewEnclosedEnvironment(base)
+		ctx := NewContext(strings.NewReader(""), io.Discard, io.Discard)
+		assert.Empty(t, ctx.Resolve(program, env))
+		assert.Equal(t, test.exp, ctx.Eval(program, env).Inspect(), test.input)
+	}
+
+	res := Eval(parser.New(lexer.New("let y = 1;")).ParseProgram(), base)
+	assert.Equal(t, "Error: cannot bind y, the environment is frozen", res.Inspect())
+	x, _ := base.Get("x")
+	assert.Equal(t, "1", x.Inspect())
+}
+
+// evalResolved resolves and evaluates input in env like the REPL
+func evalResolved(t *testing.T, ctx *Context, input string, env *object.Environment) object.Object {
+	program := parser.New(lexer.New(input)).ParseProgram()
+	assert.Empty(t, ctx.Resolve(program, env), input)
+	return ctx.Eval(program, env)
+}
+
+func TestSnapshotRestore(t *testing.T) {
+	ctx := NewContext(strings.NewReader(""), io.Discard, io.Discard)
+	env := object.NewEnvironment()
+	evalResolved(t, ctx, `
+let n = 42;
+let s = "monkey";
+let yes = true;
+let nothing = first([]);
+let arr = [1, "two", [3]];
+let same = arr;
+let h = {"a": 1, 2: [true], false: "f"};
+let fact = fn(x) { if (x < 2) { 1 } else { x * fact(x - 1) } };
+let counter = fn(start) { let step = 2; fn() { start + step } };
+let fromTen = counter(10);
+let fromZero = counter(0);
+let size = len;
+`, env)
+
+	data, err := ctx.Snapshot(env)
+	assert.NoError(t, err)
+
+	restored, err := NewContext(strings.NewReader(""), io.Discard, io.Discard).Restore(data)
+	assert.NoError(t, err)
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"n", "42"},
+		{"s", "monkey"},
+		{"yes == true", "true"},
+		{"nothing == first([])", "true"},
+		{"arr", `[1, two, [3]]`},
+		{"arr == same", "true"},
+		{"h", "{a : 1, 2 : [true], false : f}"},
+		{`h[2][0] == true`, "true"},
+		{`h[false]`, "f"},
+		{"fact(5)", "120"},
+		{"fromTen() + fromZero()", "14"},
+		{"counter(1)()", "3"},
+		{"size(arr)", "3"},
+		{"let m = n + 1; m", "43"},
+	}
+	for _, test := range tests {
+		ctx := NewContext(strings.NewReader(""), io.Discard, io.Discard)
+		assert.Equal(t, test.exp, evalResolved(t, ctx, test.input, restored).Inspect(), test.input)
+	}
+
+	// The closures created by the same literal share its body
+	from, _ := restored.Get("fromTen")
+	zero, _ := restored.Get("fromZero")
+	assert.Same(t, from.(*object.Function).Body, zero.(*object.Function).Body)
+	assert.NotSame(t, from.(*object.Function).Env, zero.(*object.Function).Env)
+}
+
+func TestSnapshotEnvironmentChain(t *testing.T) {
+	ctx := NewContext(strings.NewReader(""), io.Discard, io.Discard)
+	base := object.NewEnvironment()
+	evalResolved(t, ctx, "let greeting = \"hi\";", base)
+	base.Freeze()
+	env := object.NewEnclosedEnvironment(base)
+	evalResolved(t, ctx, "let greet = fn(name) { greeting + \" \" + name };", env)
+
+	data, err := ctx.Snapshot(env)
+	assert.NoError(t, err)
+	restored, err := ctx.Restore(data)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"greet"}, restored.Names())
+	assert.True(t, restored.Outer(1).Frozen())
+	assert.Equal(t, "hi bob", evalResolved(t, ctx, `greet("bob")`, restored).Inspect())
+	assert.Equal(t, "Error: cannot bind greeting, the environment is frozen",
+		Eval(parser.New(lexer.New(`let greeting = "yo";`)).ParseProgram(), restored.Outer(1)).Inspect())
+
+	// Snapshots of a restored environment are the same document
+	again, err := ctx.Snapshot(restored)
+	assert.NoError(t, err)
+	assert.JSONEq(t, string(data), string(again))
+}
+
+func TestSnapshotNil(t *testing.T) {
+	ctx := NewContext(strings.NewReader(""), io.Discard, io.Discard)
+	env := object.NewEnvironment()
+	evalResolved(t, ctx, "let x = fn() { let y = 1; }();", env)
+	env.Set("unset", nil)
+
+	data, err := ctx.Snapshot(env)
+	assert.NoError(t, err)
+	restored, err := ctx.Restore(data)
+	assert.NoError(t, err)
+	for _, name := range []string{"x", "unset"} {
+		val, ok := restored.Get(name)
+		assert.True(t, ok, name)
+		assert.Equal(t, NULL, val, name)
+	}
+}
+
+func TestSnapshotErrors(t *testing.T) {
+	ctx := NewContext(strings.NewReader(""), io.Discard, io.Discard)
+	env := object.NewEnvironment()
+	evalResolved(t, ctx, "let ch = channel();", env)
+	_, err := ctx.Snapshot(env)
+	assert.EqualError(t, err, "cannot snapshot a value of type CHANNEL")
+
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{`{"version": 2}`, "unsupported snapshot version 2, want 1"},
+		{`{"version": 1, "env": 1, "environments": [{"outer": null, "bindings": []}]}`, "invalid environment reference"},
+		{`{"version": 1, "env": 0, "environments": [{"outer": 0, "bindings": []}]}`, "environment 0 encloses itself"},
+		{`{"version": 1, "env": 0, "environments": [{"outer": null, "bindings": [{"name": "x", "value": {"ref": 0}}]}]}`, "invalid value"},
+		{`{"version": 1, "env": 0, "environments": [{"outer": null, "bindings": []}], "objects": [{"kind": "builtin", "name": "nope"}]}`, `unknown builtin "nope"`},
+		{`{"version": 1, "env": 0, "environments": [{"outer": null, "bindings": []}], "objects": [{"kind": "channel"}]}`, `unknown object kind "channel"`},
+	}
+	for _, test := range tests {
+		_, err := ctx.Restore([]byte(test.input))
+		assert.EqualError(t, err, test.err, test.input)
+	}
+}
+
+func TestQuoteUnquote(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{`quote(5)`, `5`},
+		{`quote(5 + 8)`, `(5 + 8)`},
+		{`quote(foobar + barfoo)`, `(foobar + barfoo)`},
+		{`quote(unquote(4 + 4))`, `8`},
+		{`quote(8 + unquote(4 + 4))`, `(8 + 8)`},
+		{`quote(unquote(true == false))`, `false`},
+		{`quote(unquote("a" + "b"))`, `ab`},
+		{`quote(unquote([1, 2 + 3]))`, `[1, 5]`},
+		{`quote(unquote(quote(4 + 4)))`, `(4 + 4)`},
+		{`let foobar = 8; quote(foobar + unquote(foobar))`, `(foobar + 8)`},
+		{`let q = quote(4 + 4); quote(unquote(4 + 4) + unquote(q))`, `(8 + (4 + 4))`},
+	}
+
+	for _, test := range tests {
+		program := parser.New(lexer.New(test.input)).ParseProgram()
+		eval := Eval(program, object.NewEnvironment())
+
+		quote, ok := eval.(*object.Quote)
+		if assert.True(t, ok, "%s: got %T", test.input, eval) {
+			assert.Equal(t, test.exp, quote.Node.String(), test.input)
+		}
+	}
+}
+
+func TestQuoteDoesNotModifyFunctionBody(t *testing.T) {
+	input := `let f = fn(x) { quote(unquote(x) + 1) }; f(1); f(2)`
+	program := parser.New(lexer.New(input)).ParseProgram()
+	eval := Eval(program, object.NewEnvironment())
+
+	quote, ok := eval.(*object.Quote)
+	assert.True(t, ok)
+	assert.Equal(t, "(2 + 1)", quote.Node.String())
+}
+
+func TestQuoteErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{`quote()`, "wrong number of arguments. got=0, want=1"},
+		{`quote(unquote(1, 2))`, "wrong number of arguments. got=2, want=1"},
+		{`quote(unquote(fn(x) { x }))`, "cannot unquote FUNCTION"},
+		{`quote(unquote(foo))`, "identifier not found: foo"},
+		{`unquote(1)`, "identifier not found: unquote"},
+		{`macro(x) { x }`, "macro literals can only be bound by a top level let statement"},
+	}
+
+	for _, test := range tests {
+		program := parser.New(lexer.New(test.input)).ParseProgram()
+		eval := Eval(program, object.NewEnvironment())
+
+		errObj, ok := eval.(*object.Error)
+		if assert.True(t, ok, "%s: got %T", test.input, eval) {
+			assert.Equal(t, test.exp, errObj.Message)
+		}
+	}
+}
+
+func TestDefineMacros(t *testing.T) {
+	input := `
+	let number = 1;
+	let function = fn(x, y) { x + y };
+	let mymacro = macro(x, y) { x + y; };
+	`
+	program := parser.New(lexer.New(input)).ParseProgram()
+	env := object.NewEnvironment()
+	DefineMacros(program, env)
+
+	assert.Equal(t, 2, len(program.Statements))
+	_, ok := env.Get("number")
+	assert.False(t, ok)
+	_, ok = env.Get("function")
+	assert.False(t, ok)
+
+	obj, ok := env.Get("mymacro")
+	assert.True(t, ok)
+	macro, ok := obj.(*object.Macro)
+	assert.True(t, ok)
+	assert.Equal(t, 2, len(macro.Parameters))
+	assert.Equal(t, "x", macro.Parameters[0].String())
+	assert.Equal(t, "y", macro.Parameters[1].String())
+	assert.Equal(t, "(x + y)", macro.Body.String())
+}
+
+func TestExpandMacros(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{
+			`let infixExpression = macro() { quote(1 + 2); }; infixExpression();`,
+			`(1 + 2)`,
+		},
+		{
+			`let reverse = macro(a, b) { quote(unquote(b) - unquote(a)); }; reverse(2 + 2, 10 - 5);`,
+			`(10 - 5) - (2 + 2)`,
+		},
+		{
+			`let unless = macro(cond, cons, alt) {
+				quote(if (!(unquote(cond))) { unquote(cons); } else { unquote(alt); });
+			};
+			unless(10 > 5, puts("not greater"), puts("greater"));`,
+			`if (!(10 > 5)) { puts("not greater") } else { puts("greater") }`,
+		},
+	}
+
+	for _, test := range tests {
+		program := parser.New(lexer.New(test.input)).ParseProgram()
+		expected := parser.New(lexer.New(test.exp)).ParseProgram()
+
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+		expanded, err := ExpandMacros(program, env)
+		assert.NoError(t, err)
+		assert.Equal(t, expected.String(), expanded.String())
+	}
+}
+
+func TestExpandMacrosEvaluation(t *testing.T) {
+	input := `
+	let unless = macro(cond, cons, alt) {
+		quote(if (!(unquote(cond))) { unquote(cons) } else { unquote(alt) });
+	};
+	unless(10 > 5, 1 / 0, "greater");
+	`
+	program := parser.New(lexer.New(input)).ParseProgram()
+	macroEnv := object.NewEnvironment()
+	DefineMacros(program, macroEnv)
+	expanded, err := ExpandMacros(program, macroEnv)
+	assert.NoError(t, err)
+
+	eval := Eval(expanded, object.NewEnvironment())
+	assert.Equal(t, "greater", eval.Inspect())
+}
+
+func TestExpandMacrosResolvedPerUse(t *testing.T) {
+	// The expansions of m share the quoted y, which is resolved at a different depth in f
+	input := `let y = 10; let m = macro(a) { quote(y + unquote(a)) }; puts(m(1)); let f = fn() { let z = 1; m(z) }; puts(f());`
+	program := parser.New(lexer.New(input)).ParseProgram()
+	var out bytes.Buffer
+	ctx := NewContext(strings.NewReader(""), &out, &out)
+	macroEnv := object.NewEnvironment()
+	DefineMacros(program, macroEnv)
+	expanded, err := ctx.ExpandMacros(program, macroEnv)
+	assert.NoError(t, err)
+
+	env := object.NewEnvironment()
+	assert.Empty(t, ctx.Resolve(expanded.(*ast.Program), env))
+	ctx.Eval(expanded, env)
+	assert.Equal(t, "11\n11\n", out.String())
+}
+
+func TestGlobalOutsideResolvedScope(t *testing.T) {
+	ident := &ast.Identifier{Value: "x", Resolution: ast.Resolution{Kind: ast.Global, Depth: 2}}
+	env := object.NewEnvironment()
+	env.Set("x", &object.Integer{Value: 1})
+	assert.Equal(t, "Error: identifier not found: x", Eval(ident, env).Inspect())
+}
+
+func TestExpandMacrosErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{`let m = macro(a) { quote(a) }; m()`, "wrong number of arguments. got=0, want=1"},
+		{`let m = macro() { 1 }; m()`, "macro must return a QUOTE, got INTEGER"},
+		{`let m = macro() { foo }; m()`, "identifier not found: foo"},
+	}
+
+	for _, test := range tests {
+		program := parser.New(lexer.New(test.input)).ParseProgram()
+		env := object.NewEnvironment()
+		DefineMacros(program, env)
+		_, err := ExpandMacros(program, env)
+		assert.EqualError(t, err, test.exp, test.input)
+	}
+}
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(src), 0644))
+	}
+	return dir
+}
+
+func TestImport(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.mk": `
+			import "lib/math.mk" as math;
+			import "./lib/math.mk" as again;
+			import "strings.mk" as strs;
+			[math.add(1, 2), math.twice(5), again.calls, strs.greet("bob")]`,
+		"lib/math.mk": `
+			import "../counter.mk" as counter;
+			let calls = counter.next();
+			let add = fn(a, b) { a + b };
+			let twice = fn(x) { add(x, x) };`,
+		"counter.mk": `
+			let count = [];
+			let next = fn() { len(count) + 1 };`,
+		"path/strings.mk": `let greet = fn(name) { "hello " + name };`,
+	})
+
+	ctx := NewContext(strings.NewReader(""), &bytes.Buffer{}, &bytes.Buffer{})
+	ctx.SearchPath = []string{filepath.Join(dir, "path")}
+	eval := ctx.EvalFile(filepath.Join(dir, "main.mk"), object.NewEnvironment())
+
+	assert.Equal(t, `[3, 10, 1, hello bob]`, eval.Inspect())
+	assert.Equal(t, 3, len(ctx.shared.modules))
+}
+
+func TestImportEvaluatesModuleOnce(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.mk": `
+			import "a.mk" as a;
+			import "b.mk" as b;
+			import "a.mk" as c;`,
+		"a.mk": `puts("loading a"); import "b.mk" as b;`,
+		"b.mk": `puts("loading b");`,
+	})
+
+	var stdout bytes.Buffer
+	ctx := NewContext(strings.NewReader(""), &stdout, &bytes.Buffer{})
+	eval := ctx.EvalFile(filepath.Join(dir, "main.mk"), object.NewEnvironment())
+
+	assert.False(t, isError(eval), "%v", eval)
+	assert.Equal(t, "loading a\nloading b\n", stdout.String())
+}
+
+func TestImportErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"cycle.mk":     `import "cycle2.mk" as c;`,
+		"cycle2.mk":    `import "cycle.mk" as c;`,
+		"missing.mk":   `import "nope.mk" as n;`,
+		"explicit.mk":  `import "./lib.mk" as lib;`,
+		"member.mk":    `import "lib/lib.mk" as lib; lib.missing`,
+		"parse.mk":     `import "bad.mk" as bad;`,
+		"bad.mk":       `let = 1;`,
+		"nested.mk":    `if (true) { import "lib/lib.mk" as lib; }`,
+		"notmod.mk":    `let x = 1; x.y`,
+		"lib/lib.mk":   `let x = 1;`,
+		"undefined.mk": "let f = fn() {\n  nope\n};\nf()",
+	})
+	tests := []struct {
+		file string
+		exp  string
+	}{
+		{"cycle.mk", "import cycle: " + filepath.Join(dir, "cycle.mk") + " -> " + filepath.Join(dir, "cycle2.mk") + " -> " + filepath.Join(dir, "cycle.mk")},
+		{"missing.mk", `cannot find module "nope.mk"`},
+		{"explicit.mk", `cannot find module "./lib.mk"`},
+		{"member.mk", "module lib.mk has no member missing"},
+		{"parse.mk", filepath.Join(dir, "bad.mk") + ": Expected next token to be 'IDENT' - got '=' instead; Missing prefixParseFn for token ="},
+		{"nested.mk", "import is only allowed at the top level of a file"},
+		{"notmod.mk", "member access not supported: INTEGER.y"},
+		{"undefined.mk", filepath.Join(dir, "undefined.mk") + ":2:3: identifier not found: nope"},
+	}
+
+	for _, test := range tests {
+		ctx := NewContext(strings.NewReader(""), &bytes.Buffer{}, &bytes.Buffer{})
+		ctx.SearchPath = []string{filepath.Join(dir, "lib")}
+		eval := ctx.EvalFile(filepath.Join(dir, test.file), object.NewEnvironment())
+
+		errObj, ok := eval.(*object.Error)
+		if assert.True(t, ok, "%s: got %v", test.file, eval) {
+			assert.Equal(t, test.exp, errObj.Message, test.file)
+		}
+		assert.Empty(t, ctx.files)
+	}
+}
+
+func testResolvedEval(t *testing.T, input string) object.Object {
+	program := parser.New(lexer.New(input)).ParseProgram()
+	env := object.NewEnvironment()
+	ctx := NewContext(strings.NewReader(""), &bytes.Buffer{}, &bytes.Buffer{})
+	assert.Empty(t, ctx.Resolve(program, env), input)
+	return ctx.Eval(program, env)
+}
+
+func TestResolvedEvaluation(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{"let fib = fn(n) { if (n < 2) { return n; } fib(n - 1) + fib(n - 2) }; fib(15)", "610"},
+		{"let adder = fn(x) { fn(y) { fn(z) { x + y + z } } }; adder(1)(2)(3)", "6"},
+		{"let f = fn() { g() }; let g = fn() { 7 }; f()", "7"},
+		{"let x = 1; let f = fn(x) { let x = x * 10; x }; [f(2), x]", "[20, 1]"},
+		{"let f = fn() { if (true) { let y = 3 } y }; f()", "3"},
+		{"let f = fn(a, a) { a }; f(1, 2)", "2"},
+		{"if (true) { let top = 4 }; let f = fn() { top }; f()", "4"},
+		{"let f = fn(xs) { map(xs, fn(x) { x + len(xs) }) }; f([1, 2])", "[3, 4]"},
+		{"let f = fn(x) { quote(unquote(x) + x) }; f(2)", "QUOTE((2 + x))"},
+		{"let f = fn() { let y = x; let x = 1; y }; let x = 2; f()", "2"},
+		{"let x = 1; let f = fn() { let y = x; let x = 2; y }; f()", "1"},
+		{"let x = 1; let f = fn() { let g = fn() { x }; let a = g(); let x = 2; [a, g()] }; f()", "[1, 2]"},
+		{"let f = fn() { let g = fn(n) { if (n > 0) { g(n - 1) } else { h() } }; let h = fn() { 5 }; g(2) }; f()", "5"},
+		{"let f = fn(c) { if (c) { let y = 1 }; y }; let y = 9; [f(true), f(false)]", "[1, 9]"},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.exp, testResolvedEval(t, test.input).Inspect(), test.input)
+	}
+}
+
+// recordingTracer records the calls it is told about and the depth of nodes being evaluated
+type recordingTracer struct {
+	events   []string
+	depth    int
+	maxDepth int
+}
+
+func (r *recordingTracer) Enter(ast.Node) {
+	r.depth++
+	if r.depth > r.maxDepth {
+		r.maxDepth = r.depth
+	}
+}
+
+func (r *recordingTracer) Exit(ast.Node, object.Object) {
+	r.depth--
+}
+
+func (r *recordingTracer) Call(name string, _ *object.Function, args []object.Object) {
+	r.events = append(r.events, fmt.Sprintf("call %s %d", name, len(args)))
+}
+
+func (r *recordingTracer) Return(name string, _ *object.Function, result object.Object) {
+	r.events = append(r.events, "return "+name+" "+result.Inspect())
+}
+
+func (r *recordingTracer) BuiltinCall(name string, args []object.Object) {
+	r.events = append(r.events, fmt.Sprintf("builtin %s %d", name, len(args)))
+}
+
+func (r *recordingTracer) BuiltinReturn(name string, result object.Object) {
+	r.events = append(r.events, "builtin return "+name+" "+result.Inspect())
+}
+
+func TestTracer(t *testing.T) {
+	input := `let double = fn(x) { x * 2 }; let f = fn(xs) { map(xs, double) }; f([1, len("ab")]); double(true)`
+	tracer := &recordingTracer{}
+	ctx := NewContext(strings.NewReader(""), &bytes.Buffer{}, &bytes.Buffer{})
+	ctx.Tracer = tracer
+	res := ctx.Eval(parser.New(lexer.New(input)).ParseProgram(), object.NewEnvironment())
+
+	assert.Equal(t, "Error: type mismatch: BOOLEAN * INTEGER", res.Inspect())
+	assert.Equal(t, []string{
+		"builtin len 1",
+		"builtin return len 2",
+		"call f 1",
+		"builtin map 2",
+		"call fn 1",
+		"return fn 2",
+		"call fn 1",
+		"return fn 4",
+		"builtin return map [2, 4]",
+		"return f [2, 4]",
+		"call double 1",
+		"return double Error: type mismatch: BOOLEAN * INTEGER",
+	}, tracer.events)
+	assert.Equal(t, 0, tracer.depth)
+	assert.Greater(t, tracer.maxDepth, 5)
+}
@@ -13,10 +13,15 @@ func booleanToNativeBoolean(val bool) object.Object {
 	return FALSE
 }
 
-func evalProgram(program *ast.Program, env *object.Environment) object.Object {
+func (c *Context) evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 	for _, statement := range program.Statements {
-		result = Eval(statement, env)
+		if c.Debugger != nil {
+			if err := c.debugStatement(statement, env); err != nil {
+				return err
+			}
+		}
+		result = c.Eval(statement, env)
 
 		switch result := result.(type) {
 		case *object.Error:
@@ -29,10 +34,18 @@ func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	return result
 }
 
-func evalBlockStatement(node *ast.BlockStatement, env *object.Environment) object.Object {
+func (c *Context) evalBlockStatement(node *ast.BlockStatement, env *object.Environment) object.Object {
 	var result object.Object
 	for _, statement := range node.Statements {
-		result = Eval(statement, env)
+		if _, ok := statement.(*ast.ImportStatement); ok {
+			return newError("import is only allowed at the top level of a file")
+		}
+		if c.Debugger != nil {
+			if err := c.debugStatement(statement, env); err != nil {
+				return err
+			}
+		}
+		result = c.Eval(statement, env)
 
 		if result != nil && (result.Type() == object.ReturnValueObj || result.Type() == object.ErrorObj) {
 			return result
@@ -125,20 +138,24 @@ func evalStringInfixExpression(left object.Object, operator string, right object
 	switch operator {
 	case "+":
 		return &object.String{Value: leftVal + rightVal}
+	case "<":
+		return booleanToNativeBoolean(leftVal < rightVal)
+	case ">":
+		return booleanToNativeBoolean(leftVal > rightVal)
 	default:
 		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
 	}
 }
 
-func evalIfExpression(node *ast.IfExpression, env *object.Environment) object.Object {
-	condition := Eval(node.Condition, env)
+func (c *Context) evalIfExpression(node *ast.IfExpression, env *object.Environment) object.Object {
+	condition := c.Eval(node.Condition, env)
 	if isError(condition) {
 		return condition
 	}
 	if isTruthy(condition) {
-		return Eval(node.Consequence, env)
+		return c.Eval(node.Consequence, env)
 	} else if node.Alternative != nil {
-		return Eval(node.Alternative, env)
+		return c.Eval(node.Alternative, env)
 	} else {
 		return NULL
 	}
@@ -161,6 +178,11 @@ func newError(format string, a ...interface{}) *object.Error {
 	return &object.Error{Message: fmt.Sprintf(format, a...)}
 }
 
+// frozenError is the error of a let or an import evaluated in a frozen environment
+func frozenError(name string) *object.Error {
+	return newError("cannot bind %s, the environment is frozen", name)
+}
+
 func isError(obj object.Object) bool {
 	if obj != nil {
 		return obj.Type() == object.ErrorObj
@@ -168,13 +190,30 @@ func isError(obj object.Object) bool {
 	return false
 }
 
-func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
-	val, ok := env.Get(node.Value)
+func (c *Context) evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
+	var val object.Object
+	var ok bool
+	switch node.Resolution.Kind {
+	case ast.Local:
+		// Until its let runs, the name refers to the variables further out like a lookup by name
+		if val, ok = env.GetSlot(node.Resolution.Depth, node.Resolution.Slot); !ok {
+			if outer := env.Outer(node.Resolution.Depth + 1); outer != nil {
+				val, ok = outer.Get(node.Value)
+			}
+		}
+	case ast.Global:
+		// The chain is shorter than the resolver expected if the node is evaluated in another scope
+		if global := env.Outer(node.Resolution.Depth); global != nil {
+			val, ok = global.Get(node.Value)
+		}
+	default:
+		val, ok = env.Get(node.Value)
+	}
 	if ok {
 		return val
 	}
 
-	builtin, ok := builtins[node.Value]
+	builtin, ok := c.builtins[node.Value]
 	if ok {
 		return builtin
 	}
@@ -182,11 +221,11 @@ func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object
 	return newError("identifier not found: %s", node.Value)
 }
 
-func evalExpressions(args []ast.Expression, env *object.Environment) []object.Object {
+func (c *Context) evalExpressions(args []ast.Expression, env *object.Environment) []object.Object {
 	var evals []object.Object
 
 	for _, arg := range args {
-		eval := Eval(arg, env)
+		eval := c.Eval(arg, env)
 		if isError(eval) {
 			return []object.Object{eval}
 		}
@@ -195,20 +234,51 @@ func evalExpressions(args []ast.Expression, env *object.Environment) []object.Ob
 	return evals
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+func (c *Context) applyFunction(fn object.Object, args []object.Object) object.Object {
+	// Functions called back by a builtin have no call expression
+	call := c.call
+	c.call = nil
+
 	switch fn := fn.(type) {
 	case *object.Function:
+		// Extra arguments are ignored, but a missing one would leave its parameter unbound
+		if len(args) < len(fn.Parameters) {
+			return newError("wrong number of arguments. got=%d, want=%d", len(args), len(fn.Parameters))
+		}
 		extendedEnv := extendedFunctionEnv(fn, args)
-		evaluated := Eval(fn.Body, extendedEnv)
-		return unwrapValue(evaluated)
+		if c.Debugger != nil {
+			c.pushCallFrame(call, fn, extendedEnv)
+			defer c.popFrame()
+		}
+		if c.Tracer == nil {
+			return unwrapValue(c.Eval(fn.Body, extendedEnv))
+		}
+		name := callName(call)
+		c.Tracer.Call(name, fn, args)
+		res := unwrapValue(c.Eval(fn.Body, extendedEnv))
+		c.Tracer.Return(name, fn, res)
+		return res
 	case *object.BuiltIn:
-		return fn.Fn(args...)
+		if c.Tracer == nil {
+			return fn.Fn(args...)
+		}
+		c.Tracer.BuiltinCall(fn.Name, args)
+		res := fn.Fn(args...)
+		c.Tracer.BuiltinReturn(fn.Name, res)
+		return res
 	default:
 		return newError("not a function: %s", fn.Type())
 	}
 }
 
 func extendedFunctionEnv(function *object.Function, args []object.Object) *object.Environment {
+	if function.Locals != nil {
+		env := object.NewFrame(function.Env, function.Locals)
+		for i, param := range function.Parameters {
+			env.SetSlot(param.Resolution.Slot, args[i])
+		}
+		return env
+	}
 	env := object.NewEnclosedEnvironment(function.Env)
 	for i, param := range function.Parameters {
 		env.Set(param.Value, args[i])
@@ -216,9 +286,14 @@ func extendedFunctionEnv(function *object.Function, args []object.Object) *objec
 	return env
 }
 
+// unwrapValue returns the value of a function body, null if it ends with a statement like let
+// that has none
 func unwrapValue(obj object.Object) object.Object {
 	if returnValue, ok := obj.(*object.ReturnValue); ok {
-		return returnValue
+		obj = returnValue.Value
+	}
+	if obj == nil {
+		return NULL
 	}
 	return obj
 }
@@ -227,6 +302,8 @@ func evalIndexExpression(left, index object.Object) object.Object {
 	switch {
 	case left.Type() == object.ArrayObj && index.Type() == object.IntegerObj:
 		return evalIntegerIndexExpression(left, index)
+	case left.Type() == object.StringObj && index.Type() == object.IntegerObj:
+		return evalStringIndexExpression(left, index)
 	case left.Type() == object.HashObj:
 		return evalHashIndexExpression(left, index)
 	default:
@@ -236,20 +313,146 @@ func evalIndexExpression(left, index object.Object) object.Object {
 
 func evalIntegerIndexExpression(array, index object.Object) object.Object {
 	arr := array.(*object.Array)
-	idx := index.(*object.Integer).Value
-
-	maxIdx := int64(len(arr.Elements) - 1)
-	if idx < 0 || idx > maxIdx {
+	idx, ok := normaliseIndex(index.(*object.Integer).Value, len(arr.Elements))
+	if !ok {
 		return NULL
 	}
 	return arr.Elements[idx]
 }
 
+func evalStringIndexExpression(str, index object.Object) object.Object {
+	s := str.(*object.String).Value
+	idx, ok := normaliseIndex(index.(*object.Integer).Value, len(s))
+	if !ok {
+		return NULL
+	}
+	return &object.String{Value: s[idx : idx+1]}
+}
+
+// normaliseIndex resolves negative indices counting from the end and reports whether idx is in range
+func normaliseIndex(idx int64, length int) (int64, bool) {
+	if idx < 0 {
+		idx += int64(length)
+	}
+	if idx < 0 || idx >= int64(length) {
+		return 0, false
+	}
+	return idx, true
+}
+
+func (c *Context) evalSliceExpression(node *ast.SliceExpression, env *object.Environment) object.Object {
+	left := c.Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	var bounds [3]object.Object
+	for i, exp := range []ast.Expression{node.Start, node.End, node.Step} {
+		if exp == nil {
+			continue
+		}
+		bound := c.Eval(exp, env)
+		if isError(bound) {
+			return bound
+		}
+		if bound != NULL && bound.Type() != object.IntegerObj {
+			return newError("slice index must be INTEGER, got %s", bound.Type())
+		}
+		bounds[i] = bound
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		start, step, n, err := sliceIndices(len(left.Elements), bounds[0], bounds[1], bounds[2])
+		if err != nil {
+			return err
+		}
+		elems := make([]object.Object, 0, n)
+		for i := int64(0); i < n; i++ {
+			elems = append(elems, left.Elements[start+i*step])
+		}
+		return &object.Array{Elements: elems}
+	case *object.String:
+		start, step, n, err := sliceIndices(len(left.Value), bounds[0], bounds[1], bounds[2])
+		if err != nil {
+			return err
+		}
+		out := make([]byte, 0, n)
+		for i := int64(0); i < n; i++ {
+			out = append(out, left.Value[start+i*step])
+		}
+		return &object.String{Value: string(out)}
+	default:
+		return newError("slice operator not supported: %s", left.Type())
+	}
+}
+
+// sliceIndices follows Python slice semantics - it clamps start and end into the sequence
+// and returns the first index, the step and the number of elements selected
+func sliceIndices(length int, startObj, endObj, stepObj object.Object) (int64, int64, int64, *object.Error) {
+	size := int64(length)
+
+	step := int64(1)
+	if s, ok := stepObj.(*object.Integer); ok {
+		step = s.Value
+	}
+	if step == 0 {
+		return 0, 0, 0, newError("slice step cannot be zero")
+	}
+
+	lower, upper := int64(0), size
+	if step < 0 {
+		lower, upper = -1, size-1
+	}
+
+	clamp := func(bound object.Object, def int64) int64 {
+		b, ok := bound.(*object.Integer)
+		if !ok {
+			return def
+		}
+		idx := b.Value
+		if idx < 0 {
+			idx += size
+			if idx < lower {
+				idx = lower
+			}
+		} else if idx > upper {
+			idx = upper
+		}
+		return idx
+	}
+
+	var start, end int64
+	if step > 0 {
+		start, end = clamp(startObj, lower), clamp(endObj, upper)
+	} else {
+		start, end = clamp(startObj, upper), clamp(endObj, lower)
+	}
+
+	// A step longer than the sequence selects at most its start, clamping it keeps the arithmetic
+	// from overflowing
+	if limit := size + 1; step > limit {
+		step = limit
+	} else if step < -limit {
+		step = -limit
+	}
+	var n int64
+	if step > 0 && start < end {
+		n = (end-start-1)/step + 1
+	} else if step < 0 && start > end {
+		n = (start-end-1)/-step + 1
+	}
+	return start, step, n, nil
+}
+
 func evalHashIndexExpression(hash, index object.Object) object.Object {
 	h := hash.(*object.Hash)
-	idx := index.(object.Hashable)
+	key, err := hashKey(index)
+	if err != nil {
+		return err
+	}
 
-	pair, ok := h.Pairs[idx.HashKey()]
+	pair, ok := h.Get(key)
 	if ok {
 		return pair.Value
 	} else {
@@ -257,25 +460,36 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 	}
 }
 
-func evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
-	for keyLit, valLit := range node.Pairs {
-		keyEval := Eval(keyLit, env)
+func (c *Context) evalHashLiteral(node *ast.HashLiteral, env *object.Environment) object.Object {
+	hash := object.NewHash()
+	for _, pairLit := range node.Pairs {
+		keyEval := c.Eval(pairLit.Key, env)
 		if isError(keyEval) {
-			return nil
+			return keyEval
 		}
 
-		keyHashEval, ok := keyEval.(object.Hashable)
-		if !ok {
-			return newError("key is not hashable")
+		key, err := hashKey(keyEval)
+		if err != nil {
+			return err
 		}
 
-		valEval := Eval(valLit, env)
+		valEval := c.Eval(pairLit.Value, env)
+		if isError(valEval) {
+			return valEval
+		}
 
-		pairs[keyHashEval.HashKey()] = object.HashPair{
+		hash.Set(key, object.HashPair{
 			Key:   keyEval,
 			Value: valEval,
-		}
+		})
+	}
+	return hash
+}
+
+func hashKey(obj object.Object) (object.HashKey, *object.Error) {
+	hashable, ok := obj.(object.Hashable)
+	if !ok {
+		return object.HashKey{}, newError("unusable as hash key: %s", obj.Type())
 	}
-	return &object.Hash{Pairs: pairs}
+	return hashable.HashKey(), nil
 }
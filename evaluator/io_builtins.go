@@ -0,0 +1,93 @@
+package evaluator
+
+import (
+	"io"
+	"monkey_interpreter/object"
+	"strings"
+)
+
+// ioBuiltins read from and write to the streams of the Context instead of the process ones
+func (c *Context) ioBuiltins() map[string]*object.BuiltIn {
+	return map[string]*object.BuiltIn{
+		"puts": {
+			Fn: func(args ...object.Object) object.Object {
+				for _, arg := range args {
+					if err := c.write(c.Stdout, arg.Inspect()+"\n"); err != nil {
+						return err
+					}
+				}
+				return NULL
+			},
+		},
+		"print": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := c.write(c.Stdout, joinInspected(args)); err != nil {
+					return err
+				}
+				return NULL
+			},
+		},
+		"eprint": {
+			Fn: func(args ...object.Object) object.Object {
+				if err := c.write(c.Stderr, joinInspected(args)); err != nil {
+					return err
+				}
+				return NULL
+			},
+		},
+		"readline": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) != 0 {
+					return newError("wrong number of arguments. got=%d, want=0", len(args))
+				}
+				return c.readLine()
+			},
+		},
+		"input": {
+			Fn: func(args ...object.Object) object.Object {
+				if len(args) > 1 {
+					return newError("wrong number of arguments. got=%d, want=0 or 1", len(args))
+				}
+				if len(args) == 1 {
+					if err := c.write(c.Stdout, args[0].Inspect()); err != nil {
+						return err
+					}
+				}
+				return c.readLine()
+			},
+		},
+	}
+}
+
+// readLine returns the next line of Stdin without its line ending, or NULL once the input is exhausted
+func (c *Context) readLine() object.Object {
+	c.shared.input.Lock()
+	line, err := c.Stdin.ReadString('\n')
+	c.shared.input.Unlock()
+	if err != nil && (err != io.EOF || line == "") {
+		if err == io.EOF {
+			return NULL
+		}
+		return newError("could not read input: %s", err)
+	}
+	line = strings.TrimSuffix(line, "\n")
+	line = strings.TrimSuffix(line, "\r")
+	return &object.String{Value: line}
+}
+
+func joinInspected(args []object.Object) string {
+	values := make([]string, 0, len(args))
+	for _, arg := range args {
+		values = append(values, arg.Inspect())
+	}
+	return strings.Join(values, " ")
+}
+
+func (c *Context) write(w io.Writer, s string) *object.Error {
+	c.shared.output.Lock()
+	defer c.shared.output.Unlock()
+	if _, err := io.WriteString(w, s); err != nil {
+		return newError("could not write output: %s", err)
+	}
+	return nil
+}
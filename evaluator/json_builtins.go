@@ -0,0 +1,186 @@
+package evaluator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"monkey_interpreter/object"
+	"strconv"
+	"strings"
+)
+
+// jsonBuiltins convert values to and from JSON. Hashes keep the order of their keys both ways, so
+// encoding is deterministic and a decoded document encodes back the same. Monkey has no floats,
+// only integral numbers can be decoded
+// maxJSONIndent bounds the spaces of an integer indent
+const maxJSONIndent = 16
+
+var jsonBuiltins = map[string]*object.BuiltIn{
+	"json_encode": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 && len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=1 or 2", len(args))
+			}
+			var b bytes.Buffer
+			if err := encodeJSON(&b, args[0]); err != nil {
+				return err
+			}
+			if len(args) == 1 {
+				return &object.String{Value: b.String()}
+			}
+
+			var indent string
+			switch arg := args[1].(type) {
+			case *object.Integer:
+				if arg.Value < 0 || arg.Value > maxJSONIndent {
+					return newError("indent of `json_encode` must be between 0 and %d, got %d", maxJSONIndent, arg.Value)
+				}
+				indent = strings.Repeat(" ", int(arg.Value))
+			case *object.String:
+				indent = arg.Value
+			default:
+				return newError("indent of `json_encode` must be INTEGER or STRING, got %s", arg.Type())
+			}
+			var out bytes.Buffer
+			if err := json.Indent(&out, b.Bytes(), "", indent); err != nil {
+				return newError("json_encode: %s", err)
+			}
+			return &object.String{Value: out.String()}
+		},
+	},
+	"json_decode": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError("wrong number of arguments. got=%d, want=1", len(args))
+			}
+			str, ok := args[0].(*object.String)
+			if !ok {
+				return newError("argument to `json_decode` must be STRING, got %s", args[0].Type())
+			}
+			// Validating the whole document first reports syntax errors where they are
+			var raw json.RawMessage
+			if err := json.Unmarshal([]byte(str.Value), &raw); err != nil {
+				return jsonError(err)
+			}
+			dec := json.NewDecoder(bytes.NewReader(raw))
+			dec.UseNumber()
+			return decodeJSON(dec)
+		},
+	},
+}
+
+func encodeJSON(b *bytes.Buffer, obj object.Object) *object.Error {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		b.WriteString(strconv.FormatInt(obj.Value, 10))
+	case *object.String:
+		encodeJSONString(b, obj.Value)
+	case *object.Boolean:
+		b.WriteString(strconv.FormatBool(obj.Value))
+	case *object.Null, nil:
+		b.WriteString("null")
+	case *object.Array:
+		b.WriteByte('[')
+		for i, elem := range obj.Elements {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := encodeJSON(b, elem); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	case *object.Hash:
+		b.WriteByte('{')
+		for i, pair := range obj.Pairs() {
+			key, ok := pair.Key.(*object.String)
+			if !ok {
+				return newError("cannot encode hash key %s as JSON, keys must be STRING, got %s", pair.Key.Inspect(), pair.Key.Type())
+			}
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			encodeJSONString(b, key.Value)
+			b.WriteByte(':')
+			if err := encodeJSON(b, pair.Value); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+	default:
+		return newError("cannot encode %s as JSON", obj.Type())
+	}
+	return nil
+}
+
+// encodeJSONString writes s as a JSON string, leaving <, > and & unescaped
+func encodeJSONString(b *bytes.Buffer, s string) {
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	_ = enc.Encode(s)
+	// Encode terminates the value with a newline
+	b.Truncate(b.Len() - 1)
+}
+
+// decodeJSON reads the next value of a validated document, walking its tokens so the keys of
+// objects keep their order
+func decodeJSON(dec *json.Decoder) object.Object {
+	tok, err := dec.Token()
+	if err != nil {
+		return jsonError(err)
+	}
+	switch tok := tok.(type) {
+	case json.Delim:
+		if tok == '[' {
+			arr := &object.Array{Elements: []object.Object{}}
+			for dec.More() {
+				elem := decodeJSON(dec)
+				if isError(elem) {
+					return elem
+				}
+				arr.Elements = append(arr.Elements, elem)
+			}
+			if _, err := dec.Token(); err != nil {
+				return jsonError(err)
+			}
+			return arr
+		}
+		hash := object.NewHash()
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return jsonError(err)
+			}
+			key := &object.String{Value: keyTok.(string)}
+			value := decodeJSON(dec)
+			if isError(value) {
+				return value
+			}
+			hash.Set(key.HashKey(), object.HashPair{Key: key, Value: value})
+		}
+		if _, err := dec.Token(); err != nil {
+			return jsonError(err)
+		}
+		return hash
+	case json.Number:
+		n, err := strconv.ParseInt(string(tok), 10, 64)
+		if err != nil {
+			return newError("cannot decode JSON number %s, only 64-bit integers are supported", tok)
+		}
+		return &object.Integer{Value: n}
+	case string:
+		return &object.String{Value: tok}
+	case bool:
+		return booleanToNativeBoolean(tok)
+	default:
+		return NULL
+	}
+}
+
+func jsonError(err error) *object.Error {
+	var syntax *json.SyntaxError
+	if errors.As(err, &syntax) {
+		return newError("invalid JSON: %s at offset %d", syntax, syntax.Offset)
+	}
+	return newError("invalid JSON: %s", err)
+}
@@ -0,0 +1,93 @@
+package evaluator
+
+import (
+	"errors"
+	"fmt"
+	"monkey_interpreter/ast"
+	"monkey_interpreter/object"
+	"os"
+)
+
+// DefineMacros moves the top level `let name = macro(...) {...}` statements out of the program and
+// binds them in env
+func DefineMacros(program *ast.Program, env *object.Environment) {
+	statements := program.Statements[:0]
+	for _, stmt := range program.Statements {
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok {
+			statements = append(statements, stmt)
+			continue
+		}
+		macroLiteral, ok := let.Value.(*ast.MacroLiteral)
+		if !ok {
+			statements = append(statements, stmt)
+			continue
+		}
+		env.Set(let.Name.Value, &object.Macro{
+			Parameters: macroLiteral.Parameters,
+			Body:       macroLiteral.Body,
+			Env:        env,
+		})
+	}
+	program.Statements = statements
+}
+
+// ExpandMacros expands the macro calls in a new Context bound to the process standard streams
+func ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, error) {
+	return NewContext(os.Stdin, os.Stdout, os.Stderr).ExpandMacros(program, env)
+}
+
+// ExpandMacros replaces every call to a macro defined in env with the quoted node the macro returns.
+// The arguments are passed to the macro unevaluated, as quotes. The program itself is not modified
+func (c *Context) ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, error) {
+	var err error
+	expanded := ast.Modify(program, func(node ast.Node) ast.Node {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || err != nil {
+			return node
+		}
+		macro, ok := macroFor(call, env)
+		if !ok {
+			return node
+		}
+		if len(call.Arguments) != len(macro.Parameters) {
+			err = fmt.Errorf("wrong number of arguments. got=%d, want=%d", len(call.Arguments), len(macro.Parameters))
+			return node
+		}
+
+		macroEnv := object.NewEnclosedEnvironment(macro.Env)
+		for i, param := range macro.Parameters {
+			macroEnv.Set(param.Value, &object.Quote{Node: call.Arguments[i]})
+		}
+		evaluated := unwrapValue(c.Eval(macro.Body, macroEnv))
+		switch evaluated := evaluated.(type) {
+		case *object.Quote:
+			// The quoted nodes are shared by every expansion, and resolving annotates them
+			return ast.Copy(evaluated.Node)
+		case *object.Error:
+			err = errors.New(evaluated.Message)
+		case nil:
+			err = errors.New("macro must return a QUOTE, got nothing")
+		default:
+			err = fmt.Errorf("macro must return a QUOTE, got %s", evaluated.Type())
+		}
+		return node
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expanded, nil
+}
+
+func macroFor(call *ast.CallExpression, env *object.Environment) (*object.Macro, bool) {
+	ident, ok := call.Function.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+	obj, ok := env.Get(ident.Value)
+	if !ok {
+		return nil, false
+	}
+	macro, ok := obj.(*object.Macro)
+	return macro, ok
+}
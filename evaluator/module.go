@@ -0,0 +1,141 @@
+package evaluator
+
+import (
+	"fmt"
+	"monkey_interpreter/ast"
+	"monkey_interpreter/lexer"
+	"monkey_interpreter/object"
+	"monkey_interpreter/parser"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// File returns the absolute path of the innermost file being evaluated, or an empty string
+// outside of EvalFile
+func (c *Context) File() string {
+	if len(c.files) == 0 {
+		return ""
+	}
+	return c.files[len(c.files)-1]
+}
+
+// EvalFile parses and evaluates the file at path in env. Imports inside it are resolved relative
+// to the directory of the file
+func (c *Context) EvalFile(path string, env *object.Environment) object.Object {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return newError("%s", err)
+	}
+	for i, file := range c.files {
+		if file == abs {
+			cycle := append(append([]string{}, c.files[i:]...), abs)
+			return newError("import cycle: %s", strings.Join(cycle, " -> "))
+		}
+	}
+
+	src, err := os.ReadFile(abs)
+	if err != nil {
+		return newError("%s", err)
+	}
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Error()) > 0 {
+		return newError("%s: %s", path, strings.Join(p.Error(), "; "))
+	}
+	macroEnv := object.NewEnvironment()
+	DefineMacros(program, macroEnv)
+	expanded, err := c.ExpandMacros(program, macroEnv)
+	if err != nil {
+		return newError("%s: %s", path, err)
+	}
+	if errs := c.Resolve(expanded.(*ast.Program), env); len(errs) > 0 {
+		msgs := make([]string, 0, len(errs))
+		for _, err := range errs {
+			msgs = append(msgs, fmt.Sprintf("%s:%s", path, err))
+		}
+		return newError("%s", strings.Join(msgs, "\n"))
+	}
+
+	c.files = append(c.files, abs)
+	defer func() {
+		c.files = c.files[:len(c.files)-1]
+	}()
+	if c.Debugger != nil {
+		c.pushFileFrame(abs, env)
+		defer c.popFrame()
+	}
+	return c.Eval(expanded, env)
+}
+
+// evalImportStatement binds the module named by the import, evaluating the file on first use only
+func (c *Context) evalImportStatement(node *ast.ImportStatement, env *object.Environment) object.Object {
+	path, err := c.resolveImport(node.Path.Value)
+	if err != nil {
+		return err
+	}
+
+	c.shared.mu.Lock()
+	module, ok := c.shared.modules[path]
+	c.shared.mu.Unlock()
+	if !ok {
+		moduleEnv := object.NewEnvironment()
+		if res := c.EvalFile(path, moduleEnv); isError(res) {
+			return res
+		}
+		module = &object.Module{Path: path, Env: moduleEnv}
+		c.shared.mu.Lock()
+		c.shared.modules[path] = module
+		c.shared.mu.Unlock()
+	}
+	if env.Frozen() {
+		return frozenError(node.Name.Value)
+	}
+	env.Set(node.Name.Value, module)
+	return nil
+}
+
+// resolveImport finds the file an import refers to. Paths starting with ./ or ../ are relative to
+// the importing file only, other relative paths are then looked up in every SearchPath directory
+func (c *Context) resolveImport(path string) (string, *object.Error) {
+	candidates := []string{path}
+	if !filepath.IsAbs(path) {
+		dir := "."
+		if len(c.files) > 0 {
+			dir = filepath.Dir(c.files[len(c.files)-1])
+		}
+		candidates = []string{filepath.Join(dir, path)}
+
+		explicit := strings.HasPrefix(path, "./") || strings.HasPrefix(path, "../")
+		if !explicit {
+			for _, dir := range c.SearchPath {
+				candidates = append(candidates, filepath.Join(dir, path))
+			}
+		}
+	}
+
+	for _, candidate := range candidates {
+		info, err := os.Stat(candidate)
+		if err != nil || info.IsDir() {
+			continue
+		}
+		abs, err := filepath.Abs(candidate)
+		if err != nil {
+			return "", newError("%s", err)
+		}
+		return abs, nil
+	}
+	return "", newError("cannot find module %q", path)
+}
+
+func evalMemberExpression(obj object.Object, member *ast.Identifier) object.Object {
+	module, ok := obj.(*object.Module)
+	if !ok {
+		return newError("member access not supported: %s.%s", obj.Type(), member.Value)
+	}
+	val, ok := module.Env.Get(member.Value)
+	if !ok {
+		return newError("module %s has no member %s", filepath.Base(module.Path), member.Value)
+	}
+	return val
+}
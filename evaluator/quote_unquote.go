@@ -0,0 +1,85 @@
+package evaluator
+
+import (
+	"fmt"
+	"monkey_interpreter/ast"
+	"monkey_interpreter/object"
+	"monkey_interpreter/token"
+)
+
+// quote returns its argument unevaluated, except for the unquote calls inside it which are
+// evaluated and spliced back into the tree
+func (c *Context) quote(args []ast.Expression, env *object.Environment) object.Object {
+	if len(args) != 1 {
+		return newError("wrong number of arguments. got=%d, want=1", len(args))
+	}
+	node, err := c.evalUnquoteCalls(args[0], env)
+	if err != nil {
+		return err
+	}
+	return &object.Quote{Node: node}
+}
+
+func (c *Context) evalUnquoteCalls(quoted ast.Node, env *object.Environment) (ast.Node, *object.Error) {
+	var err *object.Error
+	// Modify copies the nodes it changes, so the quoted function body can be evaluated again
+	node := ast.Modify(quoted, func(node ast.Node) ast.Node {
+		call, ok := node.(*ast.CallExpression)
+		if !ok || err != nil || !isCallTo(call, "unquote") {
+			return node
+		}
+		if len(call.Arguments) != 1 {
+			err = newError("wrong number of arguments. got=%d, want=1", len(call.Arguments))
+			return node
+		}
+		unquoted := c.Eval(call.Arguments[0], env)
+		if isError(unquoted) {
+			err = unquoted.(*object.Error)
+			return node
+		}
+		converted, ok := objectToASTNode(unquoted, call.Token)
+		if !ok {
+			err = newError("cannot unquote %s", unquoted.Type())
+			return node
+		}
+		return converted
+	})
+	return node, err
+}
+
+func isCallTo(call *ast.CallExpression, name string) bool {
+	ident, ok := call.Function.(*ast.Identifier)
+	return ok && ident.Value == name
+}
+
+// objectToASTNode turns an evaluated value back into a literal, positioned at the unquote call
+func objectToASTNode(obj object.Object, at token.Token) (ast.Node, bool) {
+	positioned := func(tokenType token.Type, literal string) token.Token {
+		return token.Token{Type: tokenType, Literal: literal, Line: at.Line, Column: at.Column}
+	}
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return &ast.IntegerLiteral{Token: positioned(token.INT, fmt.Sprintf("%d", obj.Value)), Value: obj.Value}, true
+	case *object.Boolean:
+		if obj.Value {
+			return &ast.Boolean{Token: positioned(token.TRUE, "true"), Value: true}, true
+		}
+		return &ast.Boolean{Token: positioned(token.FALSE, "false"), Value: false}, true
+	case *object.String:
+		return &ast.StringLiteral{Token: positioned(token.STRING, obj.Value), Value: obj.Value}, true
+	case *object.Array:
+		elems := make([]ast.Expression, 0, len(obj.Elements))
+		for _, elem := range obj.Elements {
+			node, ok := objectToASTNode(elem, at)
+			if !ok {
+				return nil, false
+			}
+			elems = append(elems, node.(ast.Expression))
+		}
+		return &ast.ArrayLiteral{Token: positioned(token.LBRACKET, "["), Elements: elems}, true
+	case *object.Quote:
+		return obj.Node, true
+	default:
+		return nil, false
+	}
+}
@@ -0,0 +1,379 @@
+package evaluator
+
+import (
+	"encoding/json"
+	"fmt"
+	"monkey_interpreter/ast"
+	"monkey_interpreter/astjson"
+	"monkey_interpreter/object"
+	"monkey_interpreter/token"
+)
+
+// SnapshotVersion of the snapshot format, bumped on any incompatible change
+const SnapshotVersion = 1
+
+// A snapshot is a JSON document {"version", "env", "environments", "objects", "bodies"}. The
+// environments and the arrays, hashes, functions, builtins and modules reachable from the chain
+// are stored once in tables and referred to by index, so values shared between variables stay
+// shared and closures can capture the environment binding them. Integers, strings, booleans and
+// null are stored inline. Function bodies are astjson documents of the function literal, shared by
+// the closures created from the same literal
+type snapshot struct {
+	Version      int               `json:"version"`
+	Env          int               `json:"env"`
+	Environments []snapshotEnv     `json:"environments"`
+	Objects      []snapshotObject  `json:"objects"`
+	Bodies       []json.RawMessage `json:"bodies"`
+}
+
+type snapshotEnv struct {
+	Outer    *int              `json:"outer"`
+	Frozen   bool              `json:"frozen,omitempty"`
+	Bindings []snapshotBinding `json:"bindings"`
+}
+
+type snapshotBinding struct {
+	Name  string        `json:"name"`
+	Value snapshotValue `json:"value"`
+}
+
+// snapshotValue holds exactly one of its fields
+type snapshotValue struct {
+	Integer *int64  `json:"integer,omitempty"`
+	String  *string `json:"string,omitempty"`
+	Boolean *bool   `json:"boolean,omitempty"`
+	Null    bool    `json:"null,omitempty"`
+	Ref     *int    `json:"ref,omitempty"`
+}
+
+type snapshotPair struct {
+	Key   snapshotValue `json:"key"`
+	Value snapshotValue `json:"value"`
+}
+
+// snapshotObject is an "array" of elements, a "hash" of pairs, a "function" with its body and
+// environment, a "builtin" or a "module" with its path and environment
+type snapshotObject struct {
+	Kind     string          `json:"kind"`
+	Elements []snapshotValue `json:"elements,omitempty"`
+	Pairs    []snapshotPair  `json:"pairs,omitempty"`
+	Body     *int            `json:"body,omitempty"`
+	Env      *int            `json:"env,omitempty"`
+	Name     string          `json:"name,omitempty"`
+	File     string          `json:"file,omitempty"`
+}
+
+// Snapshot serialises env and the environments enclosing it. Channels, errors and the other
+// values tied to a running evaluation cannot be stored
+func (c *Context) Snapshot(env *object.Environment) ([]byte, error) {
+	s := &snapshotter{
+		doc:     snapshot{Version: SnapshotVersion},
+		envs:    make(map[*object.Environment]int),
+		objects: make(map[object.Object]int),
+		bodies:  make(map[*ast.BlockStatement]int),
+	}
+	s.doc.Env = s.env(env)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return json.Marshal(s.doc)
+}
+
+// snapshotter keeps the first error so the encode functions can be written without error checks
+type snapshotter struct {
+	doc     snapshot
+	envs    map[*object.Environment]int
+	objects map[object.Object]int
+	bodies  map[*ast.BlockStatement]int
+	err     error
+}
+
+func (s *snapshotter) fail(format string, a ...interface{}) {
+	if s.err == nil {
+		s.err = fmt.Errorf(format, a...)
+	}
+}
+
+// env returns the index of env in the table, adding it first. The index is taken before the
+// bindings are encoded so closures bound in env can refer back to it
+func (s *snapshotter) env(env *object.Environment) int {
+	if i, ok := s.envs[env]; ok {
+		return i
+	}
+	i := len(s.doc.Environments)
+	s.envs[env] = i
+	s.doc.Environments = append(s.doc.Environments, snapshotEnv{})
+
+	encoded := snapshotEnv{Frozen: env.Frozen(), Bindings: []snapshotBinding{}}
+	if outer := env.Outer(1); outer != nil {
+		o := s.env(outer)
+		encoded.Outer = &o
+	}
+	for _, name := range env.Names() {
+		obj, _ := env.Get(name)
+		encoded.Bindings = append(encoded.Bindings, snapshotBinding{Name: name, Value: s.value(obj)})
+	}
+	s.doc.Environments[i] = encoded
+	return i
+}
+
+func (s *snapshotter) value(obj object.Object) snapshotValue {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return snapshotValue{Integer: &obj.Value}
+	case *object.String:
+		return snapshotValue{String: &obj.Value}
+	case *object.Boolean:
+		return snapshotValue{Boolean: &obj.Value}
+	case *object.Null, nil:
+		// A binding can hold no value at all, it reads as null
+		return snapshotValue{Null: true}
+	case *object.Array, *object.Hash, *object.Function, *object.BuiltIn, *object.Module:
+		i := s.object(obj)
+		return snapshotValue{Ref: &i}
+	default:
+		s.fail("cannot snapshot a value of type %s", obj.Type())
+		return snapshotValue{Null: true}
+	}
+}
+
+func (s *snapshotter) object(obj object.Object) int {
+	if i, ok := s.objects[obj]; ok {
+		return i
+	}
+	i := len(s.doc.Objects)
+	s.objects[obj] = i
+	s.doc.Objects = append(s.doc.Objects, snapshotObject{})
+
+	var encoded snapshotObject
+	switch obj := obj.(type) {
+	case *object.Array:
+		encoded.Kind = "array"
+		for _, elem := range obj.Elements {
+			encoded.Elements = append(encoded.Elements, s.value(elem))
+		}
+	case *object.Hash:
+		encoded.Kind = "hash"
+		for _, pair := range obj.Pairs() {
+			encoded.Pairs = append(encoded.Pairs, snapshotPair{Key: s.value(pair.Key), Value: s.value(pair.Value)})
+		}
+	case *object.Function:
+		body, env := s.body(obj), s.env(obj.Env)
+		encoded = snapshotObject{Kind: "function", Body: &body, Env: &env, File: obj.File}
+	case *object.BuiltIn:
+		encoded = snapshotObject{Kind: "builtin", Name: obj.Name}
+	case *object.Module:
+		env := s.env(obj.Env)
+		encoded = snapshotObject{Kind: "module", Name: obj.Path, Env: &env}
+	}
+	s.doc.Objects[i] = encoded
+	return i
+}
+
+// body encodes the function literal fn was created from, wrapped in a program for astjson
+func (s *snapshotter) body(fn *object.Function) int {
+	if i, ok := s.bodies[fn.Body]; ok {
+		return i
+	}
+	literal := &ast.FunctionLiteral{
+		Token:      token.Token{Type: token.FUNCTION, Literal: "fn", Line: fn.Body.Token.Line},
+		Parameters: fn.Parameters,
+		Body:       fn.Body,
+	}
+	program := &ast.Program{Statements: []ast.Statement{&ast.ExpressionStatement{Token: literal.Token, Expression: literal}}}
+	data, err := astjson.Marshal(program)
+	if err != nil {
+		s.fail("cannot snapshot function: %s", err)
+	}
+	i := len(s.doc.Bodies)
+	s.bodies[fn.Body] = i
+	s.doc.Bodies = append(s.doc.Bodies, data)
+	return i
+}
+
+// Restore rebuilds the environment chain of a snapshot, binding the builtins it refers to to c.
+// Restored functions look their variables up by name, like code that was not resolved
+func (c *Context) Restore(data []byte) (*object.Environment, error) {
+	var doc snapshot
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	if doc.Version != SnapshotVersion {
+		return nil, fmt.Errorf("unsupported snapshot version %d, want %d", doc.Version, SnapshotVersion)
+	}
+
+	r := &restorer{ctx: c, doc: &doc}
+	r.allocate()
+	if r.err != nil {
+		return nil, r.err
+	}
+	for i, encoded := range doc.Objects {
+		r.fill(r.objects[i], encoded)
+	}
+	for i, encoded := range doc.Environments {
+		for _, binding := range encoded.Bindings {
+			r.envs[i].Set(binding.Name, r.value(binding.Value))
+		}
+	}
+	// Only freeze once every environment is filled in, a cycle can lead back to a frozen one
+	for i, encoded := range doc.Environments {
+		if encoded.Frozen {
+			r.envs[i].Freeze()
+		}
+	}
+	env := r.env(&doc.Env)
+	if r.err != nil {
+		return nil, r.err
+	}
+	return env, nil
+}
+
+// restorer keeps the first error so the decode functions can be written without error checks
+type restorer struct {
+	ctx     *Context
+	doc     *snapshot
+	envs    []*object.Environment
+	objects []object.Object
+	bodies  []*ast.FunctionLiteral
+	err     error
+}
+
+func (r *restorer) fail(format string, a ...interface{}) {
+	if r.err == nil {
+		r.err = fmt.Errorf(format, a...)
+	}
+}
+
+// allocate creates the environments, the function bodies and empty objects for every entry of
+// the tables, so references can be followed whatever order they appear in
+func (r *restorer) allocate() {
+	r.envs = make([]*object.Environment, len(r.doc.Environments))
+	for i := range r.envs {
+		r.allocateEnv(i, map[int]bool{})
+	}
+	for _, raw := range r.doc.Bodies {
+		r.bodies = append(r.bodies, r.body(raw))
+	}
+	for _, encoded := range r.doc.Objects {
+		var obj object.Object
+		switch encoded.Kind {
+		case "array":
+			obj = &object.Array{}
+		case "hash":
+			obj = object.NewHash()
+		case "function":
+			obj = &object.Function{}
+		case "builtin":
+			builtin, ok := r.ctx.builtins[encoded.Name]
+			if !ok {
+				r.fail("unknown builtin %q", encoded.Name)
+			}
+			obj = builtin
+		case "module":
+			obj = &object.Module{}
+		default:
+			r.fail("unknown object kind %q", encoded.Kind)
+		}
+		r.objects = append(r.objects, obj)
+	}
+}
+
+// allocateEnv creates environment i after the ones enclosing it
+func (r *restorer) allocateEnv(i int, visiting map[int]bool) *object.Environment {
+	if r.envs[i] != nil {
+		return r.envs[i]
+	}
+	if visiting[i] {
+		r.fail("environment %d encloses itself", i)
+		return object.NewEnvironment()
+	}
+	visiting[i] = true
+	outer := r.doc.Environments[i].Outer
+	switch {
+	case outer == nil:
+		r.envs[i] = object.NewEnvironment()
+	case *outer < 0 || *outer >= len(r.envs):
+		r.fail("invalid environment %d", *outer)
+		r.envs[i] = object.NewEnvironment()
+	default:
+		r.envs[i] = object.NewEnclosedEnvironment(r.allocateEnv(*outer, visiting))
+	}
+	return r.envs[i]
+}
+
+func (r *restorer) body(raw json.RawMessage) *ast.FunctionLiteral {
+	program, err := astjson.Unmarshal(raw)
+	if err != nil {
+		r.fail("invalid function body: %s", err)
+		return nil
+	}
+	if len(program.Statements) == 1 {
+		if stmt, ok := program.Statements[0].(*ast.ExpressionStatement); ok {
+			if literal, ok := stmt.Expression.(*ast.FunctionLiteral); ok {
+				return literal
+			}
+		}
+	}
+	r.fail("function body must be a single function literal, got %s", program.String())
+	return nil
+}
+
+func (r *restorer) fill(obj object.Object, encoded snapshotObject) {
+	switch obj := obj.(type) {
+	case *object.Array:
+		obj.Elements = make([]object.Object, 0, len(encoded.Elements))
+		for _, elem := range encoded.Elements {
+			obj.Elements = append(obj.Elements, r.value(elem))
+		}
+	case *object.Hash:
+		for _, pair := range encoded.Pairs {
+			key := r.value(pair.Key)
+			hashed, err := hashKey(key)
+			if err != nil {
+				r.fail("%s", err.Message)
+				continue
+			}
+			obj.Set(hashed, object.HashPair{Key: key, Value: r.value(pair.Value)})
+		}
+	case *object.Function:
+		literal := r.bodyAt(encoded.Body)
+		obj.Parameters, obj.Body = literal.Parameters, literal.Body
+		obj.Env, obj.File = r.env(encoded.Env), encoded.File
+	case *object.Module:
+		obj.Path, obj.Env = encoded.Name, r.env(encoded.Env)
+	}
+}
+
+func (r *restorer) bodyAt(i *int) *ast.FunctionLiteral {
+	if i == nil || *i < 0 || *i >= len(r.bodies) {
+		r.fail("invalid function body reference")
+		return &ast.FunctionLiteral{Body: &ast.BlockStatement{}}
+	}
+	return r.bodies[*i]
+}
+
+func (r *restorer) env(i *int) *object.Environment {
+	if i == nil || *i < 0 || *i >= len(r.envs) {
+		r.fail("invalid environment reference")
+		return object.NewEnvironment()
+	}
+	return r.envs[*i]
+}
+
+func (r *restorer) value(v snapshotValue) object.Object {
+	switch {
+	case v.Integer != nil:
+		return &object.Integer{Value: *v.Integer}
+	case v.String != nil:
+		return &object.String{Value: *v.String}
+	case v.Boolean != nil:
+		return booleanToNativeBoolean(*v.Boolean)
+	case v.Null:
+		return NULL
+	case v.Ref != nil && *v.Ref >= 0 && *v.Ref < len(r.objects):
+		return r.objects[*v.Ref]
+	default:
+		r.fail("invalid value")
+		return NULL
+	}
+}
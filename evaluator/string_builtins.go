@@ -0,0 +1,288 @@
+package evaluator
+
+import (
+	"fmt"
+	"monkey_interpreter/object"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// stringBuiltins process text. Indices are byte offsets like those of the index and slice
+// expressions, while chars, pad and format count characters
+// maxStringLength bounds the strings built by repeat and pad, in bytes and characters
+const maxStringLength = 64 << 20
+
+var stringBuiltins = map[string]*object.BuiltIn{
+	"split": {
+		Fn: func(args ...object.Object) object.Object {
+			strs, err := stringArgs("split", args, 2)
+			if err != nil {
+				return err
+			}
+			return stringArray(strings.Split(strs[0], strs[1]))
+		},
+	},
+	"join": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			arr, ok := args[0].(*object.Array)
+			if !ok {
+				return newError("argument to `join` must be ARRAY, got %s", args[0].Type())
+			}
+			sep, err := stringArg("join", args[1])
+			if err != nil {
+				return err
+			}
+			parts := make([]string, 0, len(arr.Elements))
+			for _, elem := range arr.Elements {
+				str, ok := elem.(*object.String)
+				if !ok {
+					return newError("elements of the array passed to `join` must be STRING, got %s", elem.Type())
+				}
+				parts = append(parts, str.Value)
+			}
+			return &object.String{Value: strings.Join(parts, sep)}
+		},
+	},
+	"trim":        stringFunction("trim", strings.TrimSpace),
+	"trim_left":   stringFunction("trim_left", func(s string) string { return strings.TrimLeft(s, " \t\r\n\v\f") }),
+	"trim_right":  stringFunction("trim_right", func(s string) string { return strings.TrimRight(s, " \t\r\n\v\f") }),
+	"upper":       stringFunction("upper", strings.ToUpper),
+	"lower":       stringFunction("lower", strings.ToLower),
+	"contains":    stringPredicate("contains", strings.Contains),
+	"starts_with": stringPredicate("starts_with", strings.HasPrefix),
+	"ends_with":   stringPredicate("ends_with", strings.HasSuffix),
+	"index_of": {
+		Fn: func(args ...object.Object) object.Object {
+			strs, err := stringArgs("index_of", args, 2)
+			if err != nil {
+				return err
+			}
+			return &object.Integer{Value: int64(strings.Index(strs[0], strs[1]))}
+		},
+	},
+	"replace": {
+		Fn: func(args ...object.Object) object.Object {
+			strs, err := stringArgs("replace", args, 3)
+			if err != nil {
+				return err
+			}
+			return &object.String{Value: strings.ReplaceAll(strs[0], strs[1], strs[2])}
+		},
+	},
+	"repeat": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 {
+				return newError("wrong number of arguments. got=%d, want=2", len(args))
+			}
+			str, err := stringArg("repeat", args[0])
+			if err != nil {
+				return err
+			}
+			count, ok := args[1].(*object.Integer)
+			if !ok {
+				return newError("count of `repeat` must be INTEGER, got %s", args[1].Type())
+			}
+			if count.Value < 0 {
+				return newError("count of `repeat` cannot be negative, got %d", count.Value)
+			}
+			// Dividing cannot overflow like the length of the result could
+			if count.Value > 0 && int64(len(str)) > maxStringLength/count.Value {
+				return newError("result of `repeat` would be longer than %d bytes", maxStringLength)
+			}
+			return &object.String{Value: strings.Repeat(str, int(count.Value))}
+		},
+	},
+	"pad": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 2 && len(args) != 3 {
+				return newError("wrong number of arguments. got=%d, want=2 or 3", len(args))
+			}
+			str, err := stringArg("pad", args[0])
+			if err != nil {
+				return err
+			}
+			width, ok := args[1].(*object.Integer)
+			if !ok {
+				return newError("width of `pad` must be INTEGER, got %s", args[1].Type())
+			}
+			// The bound keeps the width negatable, unlike the smallest integer
+			if width.Value < -maxStringLength || width.Value > maxStringLength {
+				return newError("width of `pad` must be between %d and %d, got %d", -maxStringLength, maxStringLength, width.Value)
+			}
+			fill := " "
+			if len(args) == 3 {
+				if fill, err = stringArg("pad", args[2]); err != nil {
+					return err
+				}
+				if utf8.RuneCountInString(fill) != 1 {
+					return newError("fill of `pad` must be a single character, got %q", fill)
+				}
+			}
+			return &object.String{Value: pad(str, width.Value, fill)}
+		},
+	},
+	"format": {
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) == 0 {
+				return newError("wrong number of arguments. got=0, want at least 1")
+			}
+			layout, err := stringArg("format", args[0])
+			if err != nil {
+				return err
+			}
+			return format(layout, args[1:])
+		},
+	},
+	"chars": {
+		Fn: func(args ...object.Object) object.Object {
+			strs, err := stringArgs("chars", args, 1)
+			if err != nil {
+				return err
+			}
+			return stringArray(strings.Split(strs[0], ""))
+		},
+	},
+}
+
+func stringArg(name string, arg object.Object) (string, *object.Error) {
+	str, ok := arg.(*object.String)
+	if !ok {
+		return "", newError("argument to `%s` must be STRING, got %s", name, arg.Type())
+	}
+	return str.Value, nil
+}
+
+// stringArgs checks that there are n arguments, all of them strings
+func stringArgs(name string, args []object.Object, n int) ([]string, *object.Error) {
+	if len(args) != n {
+		return nil, newError("wrong number of arguments. got=%d, want=%d", len(args), n)
+	}
+	strs := make([]string, 0, n)
+	for _, arg := range args {
+		str, err := stringArg(name, arg)
+		if err != nil {
+			return nil, err
+		}
+		strs = append(strs, str)
+	}
+	return strs, nil
+}
+
+func stringArray(strs []string) *object.Array {
+	elems := make([]object.Object, 0, len(strs))
+	for _, s := range strs {
+		elems = append(elems, &object.String{Value: s})
+	}
+	return &object.Array{Elements: elems}
+}
+
+// stringFunction is a builtin mapping a string to another
+func stringFunction(name string, f func(string) string) *object.BuiltIn {
+	return &object.BuiltIn{
+		Fn: func(args ...object.Object) object.Object {
+			strs, err := stringArgs(name, args, 1)
+			if err != nil {
+				return err
+			}
+			return &object.String{Value: f(strs[0])}
+		},
+	}
+}
+
+// stringPredicate is a builtin testing a string against another
+func stringPredicate(name string, f func(s, sub string) bool) *object.BuiltIn {
+	return &object.BuiltIn{
+		Fn: func(args ...object.Object) object.Object {
+			strs, err := stringArgs(name, args, 2)
+			if err != nil {
+				return err
+			}
+			return booleanToNativeBoolean(f(strs[0], strs[1]))
+		},
+	}
+}
+
+// pad fills s up to width characters on the left, or on the right for a negative width
+func pad(s string, width int64, fill string) string {
+	left := width >= 0
+	if !left {
+		width = -width
+	}
+	n := width - int64(utf8.RuneCountInString(s))
+	if n <= 0 {
+		return s
+	}
+	if left {
+		return strings.Repeat(fill, int(n)) + s
+	}
+	return s + strings.Repeat(fill, int(n))
+}
+
+// format substitutes the args for the verbs of layout like fmt.Sprintf. %s and %v take any value,
+// strings without quotes, %q a quoted string, and %d, %x, %X, %o and %b an integer. Flags and
+// widths are those of fmt
+func format(layout string, args []object.Object) object.Object {
+	var b strings.Builder
+	next := 0
+	for i := 0; i < len(layout); i++ {
+		if layout[i] != '%' {
+			b.WriteByte(layout[i])
+			continue
+		}
+		// The flags and width run up to the verb
+		start := i
+		i++
+		for i < len(layout) && strings.IndexByte("+-# 0123456789", layout[i]) >= 0 {
+			i++
+		}
+		if i == len(layout) {
+			return newError("format: missing verb at the end of %q", layout)
+		}
+		verb := layout[i]
+		if verb == '%' {
+			b.WriteByte('%')
+			continue
+		}
+		if next == len(args) {
+			return newError("format: missing argument for %s", layout[start:i+1])
+		}
+		arg := args[next]
+		next++
+
+		var value interface{}
+		switch verb {
+		case 's', 'v':
+			if str, ok := arg.(*object.String); ok {
+				value = str.Value
+			} else {
+				value = arg.Inspect()
+			}
+		case 'q':
+			str, ok := arg.(*object.String)
+			if !ok {
+				return newError("format: %s needs STRING, got %s", layout[start:i+1], arg.Type())
+			}
+			value = str.Value
+		case 'd', 'x', 'X', 'o', 'b':
+			n, ok := arg.(*object.Integer)
+			if !ok {
+				return newError("format: %s needs INTEGER, got %s", layout[start:i+1], arg.Type())
+			}
+			value = n.Value
+		default:
+			return newError("format: unknown verb %s", strconv.QuoteRune(rune(verb)))
+		}
+		if verb == 'v' {
+			verb = 's'
+		}
+		fmt.Fprintf(&b, layout[start:i]+string(verb), value)
+	}
+	if next < len(args) {
+		return newError("format: too many arguments. got=%d, want=%d", len(args), next)
+	}
+	return &object.String{Value: b.String()}
+}
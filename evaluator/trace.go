@@ -0,0 +1,23 @@
+package evaluator
+
+import (
+	"monkey_interpreter/ast"
+	"monkey_interpreter/object"
+)
+
+// Tracer observes an evaluation. Its methods are called by the Context it is set on, in the
+// goroutine evaluating, so they should return quickly
+type Tracer interface {
+	// Enter is called before a node is evaluated and Exit after it, with its result
+	Enter(node ast.Node)
+	Exit(node ast.Node, result object.Object)
+
+	// Call is called before a user function is applied and Return after it, with its result. The
+	// name is the callee as written at the call site, or fn for functions called by builtins
+	Call(name string, fn *object.Function, args []object.Object)
+	Return(name string, fn *object.Function, result object.Object)
+
+	// BuiltinCall is called before a builtin runs and BuiltinReturn after it, with its result
+	BuiltinCall(name string, args []object.Object)
+	BuiltinReturn(name string, result object.Object)
+}
@@ -0,0 +1,303 @@
+package formatter
+
+import (
+	"errors"
+	"math"
+	"monkey_interpreter/ast"
+	"monkey_interpreter/lexer"
+	"monkey_interpreter/parser"
+	"strings"
+)
+
+const (
+	indentWidth  = 4
+	maxLineWidth = 80
+)
+
+// Format parses src and prints it back in the canonical Monkey style. Formatting is idempotent -
+// formatting already formatted source returns it unchanged
+func Format(src string) (string, error) {
+	p := parser.New(lexer.New(src))
+	program := p.ParseProgram()
+	if len(p.Error()) > 0 {
+		return "", errors.New(strings.Join(p.Error(), "\n"))
+	}
+
+	pr := &printer{
+		lines:    strings.Split(src, "\n"),
+		comments: program.Comments,
+	}
+	out := pr.block(program.Statements, 0, math.MaxInt)
+	if out == "" {
+		return "", nil
+	}
+	return out + "\n", nil
+}
+
+// printer renders the AST, weaving the comments back in by their source lines.
+// Comments inside an expression are moved after the statement that contains them
+type printer struct {
+	lines    []string // source lines, used to keep blank lines and detect trailing comments
+	comments []*ast.Comment
+	next     int // index of the next comment to print
+}
+
+// block renders statements at the given depth together with all the comments placed before closeLine
+func (p *printer) block(statements []ast.Statement, depth, closeLine int) string {
+	var out []string
+	indent := strings.Repeat(" ", depth*indentWidth)
+
+	emit := func(line int, text string) {
+		if len(out) > 0 && p.blankLineBefore(line) {
+			out = append(out, "")
+		}
+		out = append(out, indent+text)
+	}
+
+	for i, stmt := range statements {
+		start := ast.StartToken(stmt).Line
+		for p.hasCommentBefore(start) {
+			comment := p.nextComment()
+			emit(comment.Token.Line, comment.Token.Literal)
+		}
+
+		text := p.statement(stmt, depth)
+
+		bound := closeLine
+		if i+1 < len(statements) {
+			bound = ast.StartToken(statements[i+1]).Line
+		}
+		for p.hasCommentBefore(bound) && p.isTrailing(p.comments[p.next]) {
+			text += " " + p.nextComment().Token.Literal
+		}
+		emit(start, text)
+	}
+
+	for p.hasCommentBefore(closeLine) {
+		comment := p.nextComment()
+		emit(comment.Token.Line, comment.Token.Literal)
+	}
+
+	return strings.Join(out, "\n")
+}
+
+func (p *printer) statement(stmt ast.Statement, depth int) string {
+	col := depth * indentWidth
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		prefix := "let " + declaration(stmt.Name) + " = "
+		return prefix + p.expression(stmt.Value, depth, col+len(prefix)) + ";"
+	case *ast.ReturnStatement:
+		prefix := "return "
+		return prefix + p.expression(stmt.ReturnValue, depth, col+len(prefix)) + ";"
+	case *ast.ImportStatement:
+		return "import " + `"` + stmt.Path.Value + `"` + " as " + stmt.Name.Value + ";"
+	case *ast.ExpressionStatement:
+		exp := p.expression(stmt.Expression, depth, col)
+		if _, ok := stmt.Expression.(*ast.IfExpression); ok {
+			return exp
+		}
+		return exp + ";"
+	case *ast.BlockStatement:
+		return p.blockStatement(stmt, depth)
+	default:
+		return stmt.String()
+	}
+}
+
+func (p *printer) blockStatement(block *ast.BlockStatement, depth int) string {
+	inner := p.block(block.Statements, depth+1, block.EndToken.Line)
+	if inner == "" {
+		return "{}"
+	}
+	return "{\n" + inner + "\n" + strings.Repeat(" ", depth*indentWidth) + "}"
+}
+
+// expression renders exp assuming its first character is printed at column col
+func (p *printer) expression(exp ast.Expression, depth, col int) string {
+	switch exp := exp.(type) {
+	case *ast.Identifier:
+		return exp.Value
+	case *ast.IntegerLiteral:
+		return exp.Token.Literal
+	case *ast.Boolean:
+		return exp.Token.Literal
+	case *ast.StringLiteral:
+		return `"` + exp.Value + `"`
+	case *ast.PrefixExpression:
+		return exp.Operator + p.operand(exp.Right, parser.PREFIX, depth, col+len(exp.Operator))
+	case *ast.InfixExpression:
+		precedence := infixPrecedence(exp.Operator)
+		left := p.operand(exp.LeftValue, precedence, depth, col)
+		left += " " + exp.Operator + " "
+		// Infix operators are left associative so an equal precedence on the right needs parentheses
+		return left + p.operand(exp.RightValue, precedence+1, depth, column(col, left))
+	case *ast.CallExpression:
+		// Calls, indexes and member accesses chain left to right, so none needs parentheses inside another
+		fn := p.operand(exp.Function, parser.CALL, depth, col)
+		return fn + p.list("(", ")", p.expressionItems(exp.Arguments), depth, column(col, fn))
+	case *ast.IndexExpression:
+		left := p.operand(exp.Left, parser.CALL, depth, col) + "["
+		return left + p.expression(exp.Index, depth, column(col, left)) + "]"
+	case *ast.MemberExpression:
+		return p.operand(exp.Object, parser.CALL, depth, col) + "." + exp.Member.Value
+	case *ast.SliceExpression:
+		out := p.operand(exp.Left, parser.CALL, depth, col) + "["
+		if exp.Start != nil {
+			out += p.expression(exp.Start, depth, column(col, out))
+		}
+		out += ":"
+		if exp.End != nil {
+			out += p.expression(exp.End, depth, column(col, out))
+		}
+		if exp.Step != nil {
+			out += ":"
+			out += p.expression(exp.Step, depth, column(col, out))
+		}
+		return out + "]"
+	case *ast.ArrayLiteral:
+		return p.list("[", "]", p.expressionItems(exp.Elements), depth, col)
+	case *ast.HashLiteral:
+		items := make([]func(depth, col int) string, 0, len(exp.Pairs))
+		for _, pair := range exp.Pairs {
+			pair := pair
+			items = append(items, func(depth, col int) string {
+				key := p.expression(pair.Key, depth, col) + ": "
+				return key + p.expression(pair.Value, depth, column(col, key))
+			})
+		}
+		return p.list("{", "}", items, depth, col)
+	case *ast.FunctionLiteral:
+		out := "fn(" + parameters(exp.Parameters) + ") "
+		if exp.ReturnType != nil {
+			out += "-> " + exp.ReturnType.String() + " "
+		}
+		return out + p.blockStatement(exp.Body, depth)
+	case *ast.MacroLiteral:
+		return "macro(" + parameters(exp.Parameters) + ") " + p.blockStatement(exp.Body, depth)
+	case *ast.IfExpression:
+		out := "if ("
+		out += p.expression(exp.Condition, depth, col+len(out)) + ") "
+		out += p.blockStatement(exp.Consequence, depth)
+		if exp.Alternative != nil {
+			out += " else " + p.blockStatement(exp.Alternative, depth)
+		}
+		return out
+	case nil:
+		return ""
+	default:
+		return exp.String()
+	}
+}
+
+// operand renders exp wrapped in parentheses when it binds looser than the surrounding operator
+func (p *printer) operand(exp ast.Expression, precedence, depth, col int) string {
+	if expressionPrecedence(exp) < precedence {
+		return "(" + p.expression(exp, depth, col+1) + ")"
+	}
+	return p.expression(exp, depth, col)
+}
+
+func (p *printer) expressionItems(exps []ast.Expression) []func(depth, col int) string {
+	items := make([]func(depth, col int) string, 0, len(exps))
+	for _, exp := range exps {
+		exp := exp
+		items = append(items, func(depth, col int) string {
+			return p.expression(exp, depth, col)
+		})
+	}
+	return items
+}
+
+// list renders items on one line when they fit in maxLineWidth, otherwise puts every item on its
+// own line
+func (p *printer) list(open, close string, items []func(depth, col int) string, depth, col int) string {
+	mark := p.next
+
+	out := open
+	multiline := false
+	for i, item := range items {
+		if i > 0 {
+			out += ", "
+		}
+		text := item(depth, column(col, out))
+		// Only the last item may span several lines, e.g. a function literal passed as the last argument
+		multiline = multiline || (i < len(items)-1 && strings.Contains(text, "\n"))
+		out += text
+	}
+	out += close
+
+	if len(items) == 0 || (!multiline && column(col, firstLine(out)) <= maxLineWidth) {
+		return out
+	}
+
+	// Render the items again one level deeper, the comments they consumed have to be printed again
+	p.next = mark
+	indent := strings.Repeat(" ", (depth+1)*indentWidth)
+	lines := make([]string, 0, len(items))
+	for _, item := range items {
+		lines = append(lines, indent+item(depth+1, len(indent)))
+	}
+	return open + "\n" + strings.Join(lines, ",\n") + "\n" + strings.Repeat(" ", depth*indentWidth) + close
+}
+
+func (p *printer) hasCommentBefore(line int) bool {
+	return p.next < len(p.comments) && p.comments[p.next].Token.Line < line
+}
+
+func (p *printer) nextComment() *ast.Comment {
+	comment := p.comments[p.next]
+	p.next++
+	return comment
+}
+
+// isTrailing reports whether the comment follows code on its line
+func (p *printer) isTrailing(comment *ast.Comment) bool {
+	line := p.sourceLine(comment.Token.Line)
+	if comment.Token.Column-1 > len(line) {
+		return false
+	}
+	return strings.TrimSpace(line[:comment.Token.Column-1]) != ""
+}
+
+func (p *printer) blankLineBefore(line int) bool {
+	return line > 1 && strings.TrimSpace(p.sourceLine(line-1)) == ""
+}
+
+func (p *printer) sourceLine(line int) string {
+	if line < 1 || line > len(p.lines) {
+		return ""
+	}
+	return p.lines[line-1]
+}
+
+func parameters(params []*ast.Identifier) string {
+	names := make([]string, 0, len(params))
+	for _, param := range params {
+		names = append(names, declaration(param))
+	}
+	return strings.Join(names, ", ")
+}
+
+// declaration renders a declared name with its type annotation
+func declaration(ident *ast.Identifier) string {
+	if ident.Annotation == nil {
+		return ident.Value
+	}
+	return ident.Value + ": " + ident.Annotation.String()
+}
+
+// column returns the column following text printed from column col
+func column(col int, text string) int {
+	if i := strings.LastIndexByte(text, '\n'); i >= 0 {
+		return len(text) - i - 1
+	}
+	return col + len(text)
+}
+
+func firstLine(text string) string {
+	if i := strings.IndexByte(text, '\n'); i >= 0 {
+		return text[:i]
+	}
+	return text
+}
@@ -0,0 +1,93 @@
+package formatter
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			"let x=5",
+			"let x = 5;\n",
+		},
+		{
+			"let x:int=5;let f=fn(a:string,b:[int])->{string:bool}{a}",
+			"let x: int = 5;\nlet f = fn(a: string, b: [int]) -> {string: bool} {\n    a;\n};\n",
+		},
+		{
+			`import "lib.mk" as lib
+lib.add(1,(-lib.two)).x`,
+			"import \"lib.mk\" as lib;\nlib.add(1, -lib.two).x;\n",
+		},
+		{
+			"let m=macro(a,b){quote(unquote(b)-unquote(a))}",
+			"let m = macro(a, b) {\n    quote(unquote(b) - unquote(a));\n};\n",
+		},
+		{
+			"let add=fn(x,y){x+y};add(1,2)",
+			"let add = fn(x, y) {\n    x + y;\n};\nadd(1, 2);\n",
+		},
+		{
+			"(a + b) * (c - (d - e)) / -(f * g)",
+			"(a + b) * (c - (d - e)) / -(f * g);\n",
+		},
+		{
+			"(-a)[1]; (a + b)(1); a[1:]; a[:2]; a[::-1]",
+			"(-a)[1];\n(a + b)(1);\na[1:];\na[:2];\na[::-1];\n",
+		},
+		{
+			`if (x > 1) { "yes" } else { return "no"; }`,
+			"if (x > 1) {\n    \"yes\";\n} else {\n    return \"no\";\n}\n",
+		},
+		{
+			`{"b": 2, "a": [1, 2]}; fn() {}; {}`,
+			"{\"b\": 2, \"a\": [1, 2]};\nfn() {};\n{};\n",
+		},
+		{
+			"let x = 1;\n\n\n\nlet y = 2;\nlet z = 3;",
+			"let x = 1;\n\nlet y = 2;\nlet z = 3;\n",
+		},
+		{
+			"// header\nlet x = 1; // one\n\n// about y\nlet y = fn() { // opening\n    // inside\n    y\n    // end of body\n}; // after\n// final",
+			"// header\nlet x = 1; // one\n\n// about y\nlet y = fn() {\n    // opening\n    // inside\n    y;\n    // end of body\n}; // after\n// final\n",
+		},
+		{
+			`let list = [100000000, 200000000, 300000000, 400000000, 500000000, 600000000, 700000000];`,
+			"let list = [\n    100000000,\n    200000000,\n    300000000,\n    400000000,\n    500000000,\n    600000000,\n    700000000\n];\n",
+		},
+		{
+			`map(values, fn(x) { x * 2 })`,
+			"map(values, fn(x) {\n    x * 2;\n});\n",
+		},
+		{
+			`call(fn() { 1 }, 2)`,
+			"call(\n    fn() {\n        1;\n    },\n    2\n);\n",
+		},
+		{
+			"let a = 1;\n// the math module\nimport \"math.mk\" as math;",
+			"let a = 1;\n// the math module\nimport \"math.mk\" as math;\n",
+		},
+		{
+			"",
+			"",
+		},
+	}
+	for _, test := range tests {
+		actual, err := Format(test.input)
+		assert.NoError(t, err)
+		assert.Equal(t, test.expected, actual)
+
+		again, err := Format(actual)
+		assert.NoError(t, err)
+		assert.Equal(t, actual, again, "formatting is not idempotent")
+	}
+}
+
+func TestFormatParseError(t *testing.T) {
+	_, err := Format("let = 5;")
+	assert.Error(t, err)
+}
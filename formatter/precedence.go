@@ -0,0 +1,40 @@
+package formatter
+
+import (
+	"monkey_interpreter/ast"
+	"monkey_interpreter/parser"
+)
+
+var infixPrecedences = map[string]int{
+	"==": parser.EQUALS,
+	"!=": parser.EQUALS,
+	"<":  parser.LESS_GREATER,
+	">":  parser.LESS_GREATER,
+	"+":  parser.SUM,
+	"-":  parser.SUM,
+	"*":  parser.PRODUCT,
+	"/":  parser.PRODUCT,
+}
+
+func infixPrecedence(operator string) int {
+	if precedence, ok := infixPrecedences[operator]; ok {
+		return precedence
+	}
+	return parser.LOWEST
+}
+
+// expressionPrecedence returns how tightly exp binds - literals never need parentheses
+func expressionPrecedence(exp ast.Expression) int {
+	switch exp := exp.(type) {
+	case *ast.InfixExpression:
+		return infixPrecedence(exp.Operator)
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.CallExpression:
+		return parser.CALL
+	case *ast.IndexExpression, *ast.SliceExpression, *ast.MemberExpression:
+		return parser.INDEX
+	default:
+		return parser.INDEX + 1
+	}
+}
@@ -10,6 +10,9 @@ var keywords = map[string]token.Type{
 	"return": token.RETURN,
 	"true":   token.TRUE,
 	"false":  token.FALSE,
+	"macro":  token.MACRO,
+	"import": token.IMPORT,
+	"as":     token.AS,
 }
 
 func lookupIdent(ident string) token.Type {
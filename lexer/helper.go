@@ -1,6 +1,12 @@
 package lexer
 
+import "strings"
+
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
 	} else {
@@ -8,6 +14,7 @@ func (l *Lexer) readChar() {
 	}
 	l.position = l.readPosition
 	l.readPosition++
+	l.column++
 }
 
 func (l *Lexer) peekChar() byte {
@@ -32,12 +39,12 @@ func (l *Lexer) readIdent() string {
 	return l.input[sPos:l.position]
 }
 
-func (l *Lexer) readLogicOp() string {
+// readTwoCharOp consumes the first character of an operator such as ==, the second one is consumed
+// like the last character of any other token
+func (l *Lexer) readTwoCharOp() string {
 	sPos := l.position
-	for l.ch == '!' || l.ch == '=' {
-		l.readChar()
-	}
-	return l.input[sPos:l.position]
+	l.readChar()
+	return l.input[sPos:l.readPosition]
 }
 
 func (l *Lexer) readNum() string {
@@ -66,3 +73,11 @@ func (l *Lexer) readString() string {
 	}
 	return l.input[sPost:l.position]
 }
+
+func (l *Lexer) readComment() string {
+	sPos := l.position
+	for l.ch != '\n' && l.ch != 0 {
+		l.readChar()
+	}
+	return strings.TrimRight(l.input[sPos:l.position], " \t\r")
+}
@@ -9,24 +9,32 @@ type Lexer struct {
 	position     int    // position current, parsed position - corresponds to ch value
 	readPosition int    // readPosition next position that should be parsed
 	ch           byte   // ch value of index position from input string
-
+	line         int    // line line of the ch value, starting from 1
+	column       int    // column column of the ch value, starting from 1
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	l := &Lexer{input: input, line: 1}
 	l.readChar()
 	return l
 }
 
 func (l *Lexer) NextToken() token.Token {
-	var tk token.Token
 	l.skipWhitespace()
+	line, column := l.line, l.column
+	tk := l.readToken()
+	tk.Line = line
+	tk.Column = column
+	return tk
+}
+
+func (l *Lexer) readToken() token.Token {
+	var tk token.Token
 	ch := l.ch
 	switch ch {
 	case '=':
-		if l.input[l.readPosition] == '=' {
-			logicOp := l.readLogicOp()
-			tk = token.Token{Type: token.EQ, Literal: logicOp}
+		if l.peekChar() == '=' {
+			tk = token.Token{Type: token.EQ, Literal: l.readTwoCharOp()}
 		} else {
 			tk = token.Token{Type: token.ASSIGN, Literal: string(ch)}
 		}
@@ -48,15 +56,23 @@ func (l *Lexer) NextToken() token.Token {
 		value := l.readString()
 		tk = token.Token{Type: token.STRING, Literal: value}
 	case '!':
-		if l.input[l.readPosition] == '=' {
-			logicOp := l.readLogicOp()
-			tk = token.Token{Type: token.NEQ, Literal: logicOp}
+		if l.peekChar() == '=' {
+			tk = token.Token{Type: token.NEQ, Literal: l.readTwoCharOp()}
 		} else {
 			tk = token.Token{Type: token.BANG, Literal: string(ch)}
 		}
 	case '-':
-		tk = token.Token{Type: token.MINUS, Literal: string(ch)}
+		if l.peekChar() == '>' {
+			tk = token.Token{Type: token.ARROW, Literal: l.readTwoCharOp()}
+		} else {
+			tk = token.Token{Type: token.MINUS, Literal: string(ch)}
+		}
 	case '/':
+		if l.peekChar() == '/' {
+			comment := l.readComment()
+			tk = token.Token{Type: token.COMMENT, Literal: comment}
+			return tk
+		}
 		tk = token.Token{Type: token.SLASH, Literal: string(ch)}
 	case '*':
 		tk = token.Token{Type: token.ASTERISK, Literal: string(ch)}
@@ -70,6 +86,8 @@ func (l *Lexer) NextToken() token.Token {
 		tk = token.Token{Type: token.RBRACKET, Literal: string(ch)}
 	case ':':
 		tk = token.Token{Type: token.COLON, Literal: string(ch)}
+	case '.':
+		tk = token.Token{Type: token.DOT, Literal: string(ch)}
 	case 0:
 		tk = token.Token{Type: token.EOF, Literal: ""}
 	default:
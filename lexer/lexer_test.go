@@ -1,6 +1,7 @@
 package lexer
 
 import (
+	"github.com/stretchr/testify/assert"
 	"monkey_interpreter/token"
 	"testing"
 )
@@ -28,6 +29,7 @@ func TestLexer_NextToken_LogicOps(t *testing.T) {
 	input := `
 		10 == 10;
 		10 != 9;
+		a==b!=c->d
 	`
 	tests := []struct {
 		ExpectedType    token.Type
@@ -43,6 +45,14 @@ func TestLexer_NextToken_LogicOps(t *testing.T) {
 		{ExpectedType: token.INT, ExpectedLiteral: "9"},
 		{ExpectedType: token.SEMICOLON, ExpectedLiteral: ";"},
 
+		{ExpectedType: token.IDENT, ExpectedLiteral: "a"},
+		{ExpectedType: token.EQ, ExpectedLiteral: "=="},
+		{ExpectedType: token.IDENT, ExpectedLiteral: "b"},
+		{ExpectedType: token.NEQ, ExpectedLiteral: "!="},
+		{ExpectedType: token.IDENT, ExpectedLiteral: "c"},
+		{ExpectedType: token.ARROW, ExpectedLiteral: "->"},
+		{ExpectedType: token.IDENT, ExpectedLiteral: "d"},
+
 		{ExpectedType: token.EOF, ExpectedLiteral: ""},
 	}
 	runT(t, input, tests)
@@ -183,6 +193,55 @@ func TestLexer_NextToken_Full(t *testing.T) {
 	runT(t, input, tests)
 }
 
+func TestLexer_NextToken_Comments(t *testing.T) {
+	input := `// leading
+		let x = 10 / 2; // trailing
+		//`
+	tests := []struct {
+		ExpectedType    token.Type
+		ExpectedLiteral string
+	}{
+		{ExpectedType: token.COMMENT, ExpectedLiteral: "// leading"},
+		{ExpectedType: token.LET, ExpectedLiteral: "let"},
+		{ExpectedType: token.IDENT, ExpectedLiteral: "x"},
+		{ExpectedType: token.ASSIGN, ExpectedLiteral: "="},
+		{ExpectedType: token.INT, ExpectedLiteral: "10"},
+		{ExpectedType: token.SLASH, ExpectedLiteral: "/"},
+		{ExpectedType: token.INT, ExpectedLiteral: "2"},
+		{ExpectedType: token.SEMICOLON, ExpectedLiteral: ";"},
+		{ExpectedType: token.COMMENT, ExpectedLiteral: "// trailing"},
+		{ExpectedType: token.COMMENT, ExpectedLiteral: "//"},
+		{ExpectedType: token.EOF, ExpectedLiteral: ""},
+	}
+	runT(t, input, tests)
+}
+
+func TestLexer_NextToken_Position(t *testing.T) {
+	input := "let x = 5;\n  x ==\n\"a b\""
+	tests := []struct {
+		ExpectedLiteral string
+		ExpectedLine    int
+		ExpectedColumn  int
+	}{
+		{"let", 1, 1},
+		{"x", 1, 5},
+		{"=", 1, 7},
+		{"5", 1, 9},
+		{";", 1, 10},
+		{"x", 2, 3},
+		{"==", 2, 5},
+		{"a b", 3, 1},
+		{"", 3, 6},
+	}
+	lexer := New(input)
+	for _, tt := range tests {
+		tk := lexer.NextToken()
+		assert.Equal(t, tt.ExpectedLiteral, tk.Literal)
+		assert.Equal(t, tt.ExpectedLine, tk.Line, tk.Literal)
+		assert.Equal(t, tt.ExpectedColumn, tk.Column, tk.Literal)
+	}
+}
+
 func runT(t *testing.T, input string, tests []struct {
 	ExpectedType    token.Type
 	ExpectedLiteral string
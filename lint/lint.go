@@ -0,0 +1,296 @@
+// Package lint reports code that is valid Monkey but most likely a mistake: bindings that are never
+// used, builtins hidden by a variable, statements after a return, calls with the wrong number of
+// arguments and if conditions that are always the same.
+//
+// The linter works on the parser output, before macros are expanded. Unused bindings are only
+// reported inside functions, since the top level bindings of a file can be used by its importers.
+// Names starting with an underscore are never reported as unused.
+//
+// A diagnostic is suppressed by a comment on its line, or on the line before it:
+//
+//	let len = 3; // lint:ignore shadowed-builtin
+//	// lint:ignore-next-line unused-let, shadowed-builtin
+//	let first = 1;
+//
+// Without rule IDs the comment suppresses every rule.
+package lint
+
+import (
+	"fmt"
+	"monkey_interpreter/ast"
+	"monkey_interpreter/resolver"
+	"monkey_interpreter/token"
+	"sort"
+	"strings"
+)
+
+// The IDs of the rules, used in diagnostics and suppression comments
+const (
+	UnusedLet         = "unused-let"
+	ShadowedBuiltin   = "shadowed-builtin"
+	UnreachableCode   = "unreachable-code"
+	ArgumentCount     = "argument-count"
+	ConstantCondition = "constant-condition"
+)
+
+// Rules lists the IDs of all rules
+var Rules = []string{UnusedLet, ShadowedBuiltin, UnreachableCode, ArgumentCount, ConstantCondition}
+
+// Diagnostic is a finding of a rule at a position of the source
+type Diagnostic struct {
+	Line    int
+	Column  int
+	Rule    string
+	Message string
+}
+
+func (d *Diagnostic) String() string {
+	return fmt.Sprintf("%d:%d: %s (%s)", d.Line, d.Column, d.Message, d.Rule)
+}
+
+// declaration is a name bound in a scope by a let, a parameter or an import
+type declaration struct {
+	ident *ast.Identifier // the first binding of the name
+	let   bool            // bound by let, the only declarations reported when unused
+	lets  int             // number of lets binding the name in the scope
+	fn    *ast.FunctionLiteral
+	used  bool
+}
+
+type scope struct {
+	decls map[string]*declaration
+	names []string // in declaration order
+	outer *scope
+}
+
+func (s *scope) lookup(name string) *declaration {
+	for ; s != nil; s = s.outer {
+		if d, ok := s.decls[name]; ok {
+			return d
+		}
+	}
+	return nil
+}
+
+type linter struct {
+	builtins    map[string]bool
+	values      map[*ast.Identifier]ast.Expression // the values of the lets by the name they bind
+	scope       *scope
+	defining    []*declaration // lets whose value is being linted, a reference from it is no use
+	diagnostics []*Diagnostic
+}
+
+// Lint returns the diagnostics of program sorted by position, leaving out the suppressed ones.
+// builtins are the names of the builtin functions
+func Lint(program *ast.Program, builtins []string) []*Diagnostic {
+	l := &linter{
+		builtins: make(map[string]bool),
+		values:   make(map[*ast.Identifier]ast.Expression),
+	}
+	for _, name := range builtins {
+		l.builtins[name] = true
+	}
+	ast.Inspect(program, func(node ast.Node) bool {
+		if let, ok := node.(*ast.LetStatement); ok {
+			l.values[let.Name] = let.Value
+		}
+		return true
+	})
+
+	l.enterScope(nil, program.Statements)
+	l.lint(program)
+
+	suppressed := suppressions(program.Comments)
+	diagnostics := make([]*Diagnostic, 0, len(l.diagnostics))
+	for _, d := range l.diagnostics {
+		if !suppressed.matches(d) {
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		a, b := diagnostics[i], diagnostics[j]
+		return a.Line < b.Line || (a.Line == b.Line && a.Column < b.Column)
+	})
+	return diagnostics
+}
+
+func (l *linter) report(tk token.Token, rule, format string, a ...interface{}) {
+	l.diagnostics = append(l.diagnostics, &Diagnostic{
+		Line:    tk.Line,
+		Column:  tk.Column,
+		Rule:    rule,
+		Message: fmt.Sprintf(format, a...),
+	})
+}
+
+// enterScope opens the scope of a program or function body and declares its bindings
+func (l *linter) enterScope(params []*ast.Identifier, statements []ast.Statement) {
+	l.scope = &scope{decls: make(map[string]*declaration), outer: l.scope}
+	for _, param := range params {
+		l.declare(param, false)
+	}
+	for _, stmt := range statements {
+		for _, ident := range resolver.Declarations(stmt) {
+			_, isLet := l.values[ident]
+			l.declare(ident, isLet)
+		}
+	}
+}
+
+func (l *linter) declare(ident *ast.Identifier, let bool) {
+	if l.builtins[ident.Value] {
+		l.report(ident.Token, ShadowedBuiltin, "%s shadows the builtin %s", ident.Value, ident.Value)
+	}
+
+	d, ok := l.scope.decls[ident.Value]
+	if !ok {
+		d = &declaration{ident: ident, let: let}
+		l.scope.decls[ident.Value] = d
+		l.scope.names = append(l.scope.names, ident.Value)
+	}
+	if let {
+		d.lets++
+		d.fn, _ = l.values[ident].(*ast.FunctionLiteral)
+	}
+}
+
+// leaveScope closes a function scope and reports the lets that were never used
+func (l *linter) leaveScope() {
+	for _, name := range l.scope.names {
+		d := l.scope.decls[name]
+		if d.let && !d.used && !strings.HasPrefix(name, "_") {
+			l.report(d.ident.Token, UnusedLet, "let %s is never used", name)
+		}
+	}
+	l.scope = l.scope.outer
+}
+
+func (l *linter) lint(node ast.Node) {
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Program:
+			l.unreachable(node.Statements)
+		case *ast.BlockStatement:
+			l.unreachable(node.Statements)
+		case *ast.Identifier:
+			l.reference(node)
+		case *ast.LetStatement:
+			d := l.scope.decls[node.Name.Value]
+			l.defining = append(l.defining, d)
+			l.lint(node.Value)
+			l.defining = l.defining[:len(l.defining)-1]
+			return false
+		case *ast.ImportStatement:
+			return false
+		case *ast.MemberExpression:
+			// The member is looked up in the module, not in scope
+			l.lint(node.Object)
+			return false
+		case *ast.FunctionLiteral:
+			l.function(node.Parameters, node.Body)
+			return false
+		case *ast.MacroLiteral:
+			l.function(node.Parameters, node.Body)
+			return false
+		case *ast.IfExpression:
+			if isConstant(node.Condition) {
+				l.report(node.Token, ConstantCondition, "if condition %s is constant", node.Condition)
+			}
+		case *ast.CallExpression:
+			if isCallTo(node, "quote") {
+				for _, arg := range resolver.UnquotedArguments(node) {
+					l.lint(arg)
+				}
+				return false
+			}
+			l.call(node)
+		}
+		return true
+	})
+}
+
+func (l *linter) function(params []*ast.Identifier, body *ast.BlockStatement) {
+	l.enterScope(params, body.Statements)
+	l.lint(body)
+	l.leaveScope()
+}
+
+func (l *linter) reference(ident *ast.Identifier) {
+	d := l.scope.lookup(ident.Value)
+	if d == nil {
+		return
+	}
+	for _, defining := range l.defining {
+		if d == defining {
+			// A function calling itself is not a use
+			return
+		}
+	}
+	d.used = true
+}
+
+// unreachable reports the first statement after a return
+func (l *linter) unreachable(statements []ast.Statement) {
+	for i := 0; i+1 < len(statements); i++ {
+		if _, ok := statements[i].(*ast.ReturnStatement); ok {
+			l.report(ast.StartToken(statements[i+1]), UnreachableCode, "unreachable code after return")
+			return
+		}
+	}
+}
+
+// call checks the number of arguments of calls to function literals and to functions bound once
+// by let
+func (l *linter) call(call *ast.CallExpression) {
+	var fn *ast.FunctionLiteral
+	var tk token.Token
+	name := "fn"
+	switch f := call.Function.(type) {
+	case *ast.FunctionLiteral:
+		fn, tk = f, f.Token
+	case *ast.Identifier:
+		if d := l.scope.lookup(f.Value); d != nil && d.lets == 1 {
+			fn, tk, name = d.fn, f.Token, f.Value
+		}
+	}
+	if fn == nil || len(call.Arguments) == len(fn.Parameters) {
+		return
+	}
+	l.report(tk, ArgumentCount, "wrong number of arguments to %s. got=%d, want=%d",
+		name, len(call.Arguments), len(fn.Parameters))
+}
+
+func isCallTo(call *ast.CallExpression, name string) bool {
+	ident, ok := call.Function.(*ast.Identifier)
+	return ok && ident.Value == name
+}
+
+// isConstant reports whether the truthiness of a condition is known without running it
+func isConstant(exp ast.Expression) bool {
+	switch exp := exp.(type) {
+	case *ast.FunctionLiteral, *ast.ArrayLiteral, *ast.HashLiteral:
+		// Anything but false and null is truthy
+		return true
+	case *ast.PrefixExpression:
+		if exp.Operator == "!" {
+			return isConstant(exp.Right)
+		}
+		return isConstantValue(exp)
+	default:
+		return isConstantValue(exp)
+	}
+}
+
+// isConstantValue reports whether the value of an expression only depends on literals
+func isConstantValue(exp ast.Expression) bool {
+	switch exp := exp.(type) {
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.Boolean:
+		return true
+	case *ast.PrefixExpression:
+		return isConstantValue(exp.Right)
+	case *ast.InfixExpression:
+		return isConstantValue(exp.LeftValue) && isConstantValue(exp.RightValue)
+	default:
+		return false
+	}
+}
@@ -0,0 +1,77 @@
+package lint
+
+import (
+	"github.com/stretchr/testify/assert"
+	"monkey_interpreter/lexer"
+	"monkey_interpreter/parser"
+	"testing"
+)
+
+func lint(t *testing.T, input string) []string {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	assert.Empty(t, p.Error(), input)
+
+	msgs := make([]string, 0)
+	for _, d := range Lint(program, []string{"len", "puts", "first"}) {
+		msgs = append(msgs, d.String())
+	}
+	return msgs
+}
+
+func TestLint(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{`let x = 1; let f = fn(a) { a + x }; f(2)`, []string{}},
+		{`let f = fn() { let unused = 1; let used = 2; used }`, []string{"1:20: let unused is never used (unused-let)"}},
+		{`let f = fn() { let _ignored = 1; 2 }`, []string{}},
+		{`let f = fn() { let g = fn(n) { g(n - 1) }; 1 }`, []string{"1:20: let g is never used (unused-let)"}},
+		{`let f = fn() { let g = fn(n) { g(n - 1) }; g(1) }`, []string{}},
+		{`let f = fn() { if (true) { let y = 1; } 2 }`, []string{
+			"1:16: if condition true is constant (constant-condition)",
+			"1:32: let y is never used (unused-let)",
+		}},
+		{`let f = fn() { let x = 1; fn() { x } }`, []string{}},
+		{`let f = fn(x) { let x = 2; x }`, []string{}},
+		{`let len = fn(s) { 0 }; let g = fn(puts) { puts }`, []string{
+			"1:5: len shadows the builtin len (shadowed-builtin)",
+			"1:35: puts shadows the builtin puts (shadowed-builtin)",
+		}},
+		{"let f = fn() {\n  return 1;\n  puts(2);\n  puts(3)\n}", []string{"3:3: unreachable code after return (unreachable-code)"}},
+		{`return 1; let x = 2;`, []string{"1:11: unreachable code after return (unreachable-code)"}},
+		{`let add = fn(a, b) { a + b }; add(1); add(1, 2); add(1, 2, 3)`, []string{
+			"1:31: wrong number of arguments to add. got=1, want=2 (argument-count)",
+			"1:50: wrong number of arguments to add. got=3, want=2 (argument-count)",
+		}},
+		{`let f = fn() { g(1) }; let g = fn() { 1 }`, []string{"1:16: wrong number of arguments to g. got=1, want=0 (argument-count)"}},
+		{`fn(x) { x }()`, []string{"1:1: wrong number of arguments to fn. got=0, want=1 (argument-count)"}},
+		{`let f = fn(a) { a }; let f = fn() { 1 }; f()`, []string{}},
+		{`let add = fn(a, b) { a + b }; let g = fn(add) { add(1) }`, []string{}},
+		{`let x = 1; if (x) { 1 }; if (1 < 2) { 1 }; if (!fn() { 1 }) { 1 }; if (-x) { 1 }`, []string{
+			"1:26: if condition (1 < 2) is constant (constant-condition)",
+			"1:44: if condition (!fn(){1}) is constant (constant-condition)",
+		}},
+		{`let m = macro(a, b) { quote(unquote(a)) }`, []string{}},
+		{`import "lib.mk" as lib; let f = fn() { let first = 1; lib.first }`, []string{
+			"1:44: first shadows the builtin first (shadowed-builtin)",
+			"1:44: let first is never used (unused-let)",
+		}},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, lint(t, tt.input), tt.input)
+	}
+}
+
+func TestLintSuppression(t *testing.T) {
+	input := `let len = 1; // lint:ignore shadowed-builtin
+let first = 2; // lint:ignore unused-let
+// lint:ignore-next-line
+let puts = fn() { let x = 1; 2 };
+// lint:ignore-next-line argument-count, shadowed-builtin
+let f = fn(len) { 1 }; f()`
+
+	assert.Equal(t, []string{"2:5: first shadows the builtin first (shadowed-builtin)"}, lint(t, input))
+}
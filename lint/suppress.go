@@ -0,0 +1,51 @@
+package lint
+
+import (
+	"monkey_interpreter/ast"
+	"strings"
+)
+
+// suppression lists the rules ignored on a line
+type suppression struct {
+	all   bool
+	rules map[string]bool
+}
+
+type suppressionSet map[int]*suppression
+
+// suppressions collects the lint:ignore and lint:ignore-next-line comments by the line they apply to
+func suppressions(comments []*ast.Comment) suppressionSet {
+	set := make(suppressionSet)
+	for _, comment := range comments {
+		fields := strings.Fields(strings.ReplaceAll(strings.TrimPrefix(comment.Token.Literal, "//"), ",", " "))
+		if len(fields) == 0 {
+			continue
+		}
+		line := comment.Token.Line
+		switch fields[0] {
+		case "lint:ignore":
+		case "lint:ignore-next-line":
+			line++
+		default:
+			continue
+		}
+
+		s, ok := set[line]
+		if !ok {
+			s = &suppression{rules: make(map[string]bool)}
+			set[line] = s
+		}
+		if len(fields) == 1 {
+			s.all = true
+		}
+		for _, rule := range fields[1:] {
+			s.rules[rule] = true
+		}
+	}
+	return set
+}
+
+func (set suppressionSet) matches(d *Diagnostic) bool {
+	s, ok := set[d.Line]
+	return ok && (s.all || s.rules[d.Rule])
+}
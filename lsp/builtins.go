@@ -0,0 +1,65 @@
+package lsp
+
+// builtinDoc documents a builtin function for hover and completion
+type builtinDoc struct {
+	signature string
+	doc       string
+}
+
+var builtinDocs = map[string]builtinDoc{
+	"len":           {"len(value)", "Returns the number of characters of a string or elements of an array."},
+	"first":         {"first(array)", "Returns the first element of an array, or null if it is empty."},
+	"last":          {"last(array)", "Returns the last element of an array, or null if it is empty."},
+	"rest":          {"rest(array)", "Returns a new array without the first element, or null if the array is empty."},
+	"push":          {"push(array, value)", "Returns a new array with value appended."},
+	"keys":          {"keys(hash)", "Returns the keys of a hash."},
+	"values":        {"values(hash)", "Returns the values of a hash."},
+	"entries":       {"entries(hash)", "Returns the [key, value] pairs of a hash."},
+	"has":           {"has(hash, key)", "Reports whether a hash contains key."},
+	"delete":        {"delete(hash, key)", "Returns a new hash without key."},
+	"put":           {"put(hash, key, value)", "Returns a new hash with key set to value."},
+	"merge":         {"merge(hash, hash...)", "Returns a new hash with the pairs of all hashes, later ones win."},
+	"map":           {"map(array, fn)", "Returns the results of calling fn with every element."},
+	"filter":        {"filter(array, fn)", "Returns the elements for which fn returns a truthy value."},
+	"reduce":        {"reduce(array, fn, initial?)", "Folds the elements with fn(accumulator, element), starting from initial or the first element."},
+	"any":           {"any(array, fn)", "Reports whether fn returns a truthy value for some element."},
+	"all":           {"all(array, fn)", "Reports whether fn returns a truthy value for every element."},
+	"find":          {"find(array, fn)", "Returns the first element for which fn returns a truthy value, or null."},
+	"sort":          {"sort(array, less?)", "Returns the elements in order. less(a, b) returns a boolean, or an integer that is negative when a comes first."},
+	"reverse":       {"reverse(value)", "Returns an array or string in reverse order."},
+	"zip":           {"zip(array, array...)", "Returns arrays of the elements at the same index, as long as the shortest array."},
+	"range":         {"range(end) | range(start, end, step?)", "Returns the integers from start (0) up to but not including end."},
+	"flatten":       {"flatten(array, depth?)", "Inlines nested arrays up to depth levels deep, completely without a depth."},
+	"puts":          {"puts(value...)", "Prints every value on its own line."},
+	"print":         {"print(value...)", "Prints the values separated by spaces, without a newline."},
+	"eprint":        {"eprint(value...)", "Prints the values to standard error."},
+	"readline":      {"readline()", "Returns the next line of standard input, or null at the end of the input."},
+	"input":         {"input(prompt?)", "Prints prompt and returns the next line of standard input."},
+	"spawn":         {"spawn(fn, args...)", "Calls fn with args on another goroutine. Returns a channel receiving its result, or the error it failed with."},
+	"channel":       {"channel(capacity?)", "Returns a channel buffering up to capacity (0) values."},
+	"send":          {"send(channel, value)", "Sends value on a channel, waiting for a receiver or room in the buffer."},
+	"recv":          {"recv(channel)", "Returns the next value sent on a channel, waiting for one, or null once it is closed and drained."},
+	"close":         {"close(channel)", "Closes a channel. Receivers get null once it is drained, senders fail."},
+	"select":        {"select(cases, default?)", "Waits for the first case to proceed and returns what its function does. A case is [channel, fn(value)] to receive or [channel, value, fn()] to send. default() is called instead of waiting."},
+	"split":         {"split(string, separator)", "Returns the parts of string between the separators, or its characters for an empty separator."},
+	"join":          {"join(array, separator)", "Returns the strings of an array joined by separator."},
+	"trim":          {"trim(string)", "Returns string without leading and trailing whitespace."},
+	"trim_left":     {"trim_left(string)", "Returns string without leading whitespace."},
+	"trim_right":    {"trim_right(string)", "Returns string without trailing whitespace."},
+	"upper":         {"upper(string)", "Returns string in upper case."},
+	"lower":         {"lower(string)", "Returns string in lower case."},
+	"contains":      {"contains(string, substring)", "Reports whether substring is within string."},
+	"starts_with":   {"starts_with(string, prefix)", "Reports whether string begins with prefix."},
+	"ends_with":     {"ends_with(string, suffix)", "Reports whether string ends with suffix."},
+	"index_of":      {"index_of(string, substring)", "Returns the byte index of the first substring in string, or -1 if there is none."},
+	"replace":       {"replace(string, old, new)", "Returns string with every old replaced by new."},
+	"repeat":        {"repeat(string, count)", "Returns count copies of string concatenated, at most 64 MiB."},
+	"pad":           {"pad(string, width, fill?)", "Pads string with fill (a space) on the left up to width characters, on the right for a negative width. The width is at most 64 Mi characters."},
+	"format":        {"format(layout, args...)", "Returns layout with the args substituted for its verbs like printf: %s and %v for any value, %q for a quoted string, %d, %x, %o and %b for integers, %% for a percent sign."},
+	"chars":         {"chars(string)", "Returns the characters of string."},
+	"json_encode":   {"json_encode(value, indent?)", "Returns the JSON encoding of a hash, array, string, integer, boolean or null, indented by indent spaces, at most 16, or string. Hash keys must be strings and keep their order."},
+	"json_decode":   {"json_decode(string)", "Parses a JSON document into hashes, arrays, strings, integers, booleans and null. Numbers must be integers."},
+	"assert":        {"assert(value, message?)", "Fails with an error unless value is truthy."},
+	"assert_eq":     {"assert_eq(actual, expected, message?)", "Fails with an error showing both values unless they are equal, comparing arrays and hashes element by element."},
+	"assert_throws": {"assert_throws(fn, substring?)", "Calls fn and fails unless it returns an error containing substring. Returns the error message."},
+}
@@ -0,0 +1,261 @@
+package lsp
+
+import (
+	"monkey_interpreter/ast"
+	"monkey_interpreter/checker"
+	"monkey_interpreter/lexer"
+	"monkey_interpreter/parser"
+	"monkey_interpreter/resolver"
+	"monkey_interpreter/token"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// document is an open text document. While it is being edited it often does not parse, so the
+// features work on the analysis of the last version that did
+type document struct {
+	text     string
+	errors   []*parser.Error
+	analysis *analysis // nil until a version parsed
+}
+
+func newDocument(text string) *document {
+	p := parser.New(lexer.New(text))
+	program := p.ParseProgram()
+	d := &document{text: text, errors: p.Errors()}
+	if len(d.errors) == 0 {
+		d.analysis = analyze(text, program)
+	}
+	return d
+}
+
+// update replaces the text of the document, keeping the last analysis if the new text does not parse
+func (d *document) update(text string) {
+	analysis := d.analysis
+	*d = *newDocument(text)
+	if d.analysis == nil {
+		d.analysis = analysis
+	}
+}
+
+type declarationKind int
+
+const (
+	letDeclaration declarationKind = iota
+	parameterDeclaration
+	importDeclaration
+)
+
+// declaration is a name bound by a let, a parameter or an import
+type declaration struct {
+	ident *ast.Identifier
+	kind  declarationKind
+	value ast.Expression // the value of a let
+}
+
+// scope is the top level of the document or a function body, with the source range it covers
+type scope struct {
+	start, end token.Token
+	decls      map[string][]*declaration // by name, in source order
+	names      []string
+	outer      *scope
+}
+
+func (s *scope) contains(line, column int) bool {
+	return !before(line, column, s.start.Line, s.start.Column) && !before(s.end.Line, s.end.Column, line, column)
+}
+
+// before reports whether the position line:column comes before otherLine:otherColumn
+func before(line, column, otherLine, otherColumn int) bool {
+	return line < otherLine || (line == otherLine && column < otherColumn)
+}
+
+// lookup returns the declaration a name used at line:column refers to. Lets are visible in their
+// whole scope, the closest one before the use is the one that ran last
+func (s *scope) lookup(name string, line, column int) *declaration {
+	for ; s != nil; s = s.outer {
+		decls := s.decls[name]
+		if len(decls) == 0 {
+			continue
+		}
+		found := decls[0]
+		for _, decl := range decls[1:] {
+			if before(decl.ident.Token.Line, decl.ident.Token.Column, line, column) {
+				found = decl
+			}
+		}
+		return found
+	}
+	return nil
+}
+
+// analysis holds what the features need to know about a document that parsed
+type analysis struct {
+	lines   []string
+	program *ast.Program
+	types   map[*ast.Identifier]checker.Type
+	idents  []*ast.Identifier                // every identifier, in the order they were visited
+	decls   map[*ast.Identifier]*declaration // the declaration of every identifier in idents
+	scopes  []*scope                         // outer scopes come before the scopes they contain
+	scope   *scope                           // the scope being analyzed
+}
+
+func analyze(text string, program *ast.Program) *analysis {
+	info, _ := checker.Infer(program)
+	a := &analysis{
+		lines:   strings.Split(text, "\n"),
+		program: program,
+		types:   info.Types,
+		decls:   make(map[*ast.Identifier]*declaration),
+	}
+	end := token.Token{Line: len(a.lines), Column: len(a.lines[len(a.lines)-1]) + 1}
+	a.enterScope(token.Token{Line: 1, Column: 1}, end, nil, program.Statements)
+	a.walk(program)
+	return a
+}
+
+func (a *analysis) enterScope(start, end token.Token, params []*ast.Identifier, statements []ast.Statement) {
+	a.scope = &scope{start: start, end: end, decls: make(map[string][]*declaration), outer: a.scope}
+	a.scopes = append(a.scopes, a.scope)
+	for _, param := range params {
+		a.declare(&declaration{ident: param, kind: parameterDeclaration})
+	}
+	lets := make(map[*ast.Identifier]ast.Expression)
+	for _, stmt := range statements {
+		ast.Inspect(stmt, func(node ast.Node) bool {
+			switch node := node.(type) {
+			case *ast.LetStatement:
+				lets[node.Name] = node.Value
+			case *ast.FunctionLiteral, *ast.MacroLiteral:
+				return false
+			}
+			return true
+		})
+		for _, ident := range resolver.Declarations(stmt) {
+			decl := &declaration{ident: ident, kind: importDeclaration}
+			if value, ok := lets[ident]; ok {
+				decl.kind, decl.value = letDeclaration, value
+			}
+			a.declare(decl)
+		}
+	}
+}
+
+func (a *analysis) declare(decl *declaration) {
+	name := decl.ident.Value
+	if _, ok := a.scope.decls[name]; !ok {
+		a.scope.names = append(a.scope.names, name)
+	}
+	a.scope.decls[name] = append(a.scope.decls[name], decl)
+	a.idents = append(a.idents, decl.ident)
+	a.decls[decl.ident] = decl
+}
+
+func (a *analysis) walk(node ast.Node) {
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Identifier:
+			if decl := a.scope.lookup(node.Value, node.Token.Line, node.Token.Column); decl != nil {
+				a.decls[node] = decl
+			}
+			a.idents = append(a.idents, node)
+		case *ast.LetStatement:
+			a.walk(node.Value)
+			return false
+		case *ast.ImportStatement:
+			return false
+		case *ast.MemberExpression:
+			// The member is looked up in the module, not in scope
+			a.walk(node.Object)
+			return false
+		case *ast.FunctionLiteral:
+			a.function(node.Token, node.Parameters, node.Body)
+			return false
+		case *ast.MacroLiteral:
+			a.function(node.Token, node.Parameters, node.Body)
+			return false
+		case *ast.CallExpression:
+			if ident, ok := node.Function.(*ast.Identifier); ok && ident.Value == "quote" {
+				for _, arg := range resolver.UnquotedArguments(node) {
+					a.walk(arg)
+				}
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func (a *analysis) function(start token.Token, params []*ast.Identifier, body *ast.BlockStatement) {
+	a.enterScope(start, body.EndToken, params, body.Statements)
+	a.walk(body)
+	a.scope = a.scope.outer
+}
+
+// identifierAt returns the identifier under or right after the cursor
+func (a *analysis) identifierAt(line, column int) *ast.Identifier {
+	for _, ident := range a.idents {
+		start := ident.Token.Column
+		if ident.Token.Line == line && start <= column && column <= start+len(ident.Value) {
+			return ident
+		}
+	}
+	return nil
+}
+
+// scopeAt returns the innermost scope containing line:column
+func (a *analysis) scopeAt(line, column int) *scope {
+	found := a.scopes[0]
+	for _, s := range a.scopes[1:] {
+		if s.contains(line, column) {
+			found = s
+		}
+	}
+	return found
+}
+
+// position converts a one based line and byte column of the lexer to a protocol position
+func position(lines []string, line, column int) Position {
+	if line < 1 {
+		return Position{}
+	}
+	if line > len(lines) {
+		return Position{Line: line - 1}
+	}
+	text := lines[line-1]
+	if column < 1 {
+		column = 1
+	}
+	if column-1 < len(text) {
+		text = text[:column-1]
+	}
+	return Position{Line: line - 1, Character: utf16Length(text)}
+}
+
+// offset converts a protocol position to a one based line and byte column
+func offset(lines []string, pos Position) (int, int) {
+	if pos.Line >= len(lines) {
+		return pos.Line + 1, 1
+	}
+	text := lines[pos.Line]
+	units, column := 0, 0
+	for column < len(text) && units < pos.Character {
+		r, size := utf8.DecodeRuneInString(text[column:])
+		units += len(utf16.Encode([]rune{r}))
+		column += size
+	}
+	return pos.Line + 1, column + 1
+}
+
+func utf16Length(s string) int {
+	return len(utf16.Encode([]rune(s)))
+}
+
+// identifierRange is the range of the name of an identifier
+func identifierRange(lines []string, ident *ast.Identifier) Range {
+	return Range{
+		Start: position(lines, ident.Token.Line, ident.Token.Column),
+		End:   position(lines, ident.Token.Line, ident.Token.Column+len(ident.Value)),
+	}
+}
@@ -0,0 +1,415 @@
+// Package lsp implements a Language Server Protocol server for Monkey over the lexer and parser.
+// It provides diagnostics for syntax errors, hover with the inferred types of variables and the
+// documentation of builtins, go to definition for lets and parameters, completion of the names in
+// scope, document symbols and formatting.
+//
+// Documents are synchronized by sending their whole text on every change.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"monkey_interpreter/ast"
+	"monkey_interpreter/formatter"
+	"monkey_interpreter/wire"
+	"sort"
+	"strings"
+)
+
+// Server answers the requests of a single client
+type Server struct {
+	in        *bufio.Reader
+	out       io.Writer
+	documents map[string]*document // by URI
+	builtins  []string
+
+	initialized bool
+	shutdown    bool
+}
+
+type handler func(s *Server, params json.RawMessage) (interface{}, error)
+
+var handlers = map[string]handler{
+	"initialize":                  (*Server).initialize,
+	"initialized":                 ignore,
+	"shutdown":                    (*Server).shutdownRequest,
+	"textDocument/didOpen":        (*Server).didOpen,
+	"textDocument/didChange":      (*Server).didChange,
+	"textDocument/didClose":       (*Server).didClose,
+	"textDocument/hover":          (*Server).hover,
+	"textDocument/definition":     (*Server).definition,
+	"textDocument/completion":     (*Server).completion,
+	"textDocument/documentSymbol": (*Server).documentSymbol,
+	"textDocument/formatting":     (*Server).formatting,
+}
+
+// NewServer creates a server reading messages from in and writing to out. builtins are the names
+// of the builtin functions
+func NewServer(in io.Reader, out io.Writer, builtins []string) *Server {
+	return &Server{
+		in:        bufio.NewReader(in),
+		out:       out,
+		documents: make(map[string]*document),
+		builtins:  builtins,
+	}
+}
+
+// Run serves requests until the client sends exit or closes the input. The result is nil if the
+// server was shut down before it exited
+func (s *Server) Run() error {
+	for {
+		body, err := wire.ReadMessage(s.in)
+		if err != nil {
+			if err == io.EOF && s.shutdown {
+				return nil
+			}
+			return err
+		}
+
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			if err := s.reply(nil, nil, &responseError{Code: parseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if req.Method == "exit" {
+			if !s.shutdown {
+				return fmt.Errorf("exit without shutdown")
+			}
+			return nil
+		}
+
+		result, err := s.handle(&req)
+		if req.ID == nil {
+			// Notifications have no response, not even an error
+			continue
+		}
+		if err := s.reply(req.ID, result, err); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *Server) handle(req *request) (result interface{}, err error) {
+	h, ok := handlers[req.Method]
+	if !ok {
+		return nil, &responseError{Code: methodNotFound, Message: "method not supported: " + req.Method}
+	}
+	if !s.initialized && req.Method != "initialize" {
+		return nil, &responseError{Code: serverNotInitialized, Message: "server not initialized"}
+	}
+	defer func() {
+		// A bug in a feature should not take the editor integration down
+		if r := recover(); r != nil {
+			result, err = nil, &responseError{Code: internalError, Message: fmt.Sprint(r)}
+		}
+	}()
+	return h(s, req.Params)
+}
+
+func (s *Server) reply(id *json.RawMessage, result interface{}, err error) error {
+	resp := response{JSONRPC: "2.0", ID: id, Result: result}
+	if err != nil {
+		respErr, ok := err.(*responseError)
+		if !ok {
+			respErr = &responseError{Code: invalidParams, Message: err.Error()}
+		}
+		resp.Result, resp.Error = nil, respErr
+	}
+	return wire.WriteMessage(s.out, resp)
+}
+
+func (s *Server) notify(method string, params interface{}) error {
+	return wire.WriteMessage(s.out, notification{JSONRPC: "2.0", Method: method, Params: params})
+}
+
+func ignore(*Server, json.RawMessage) (interface{}, error) {
+	return nil, nil
+}
+
+func (s *Server) initialize(json.RawMessage) (interface{}, error) {
+	s.initialized = true
+	return InitializeResult{
+		Capabilities: ServerCapabilities{
+			TextDocumentSync:           TextDocumentSyncOptions{OpenClose: true, Change: textDocumentSyncFull},
+			HoverProvider:              true,
+			DefinitionProvider:         true,
+			CompletionProvider:         CompletionOptions{},
+			DocumentSymbolProvider:     true,
+			DocumentFormattingProvider: true,
+		},
+		ServerInfo: ServerInfo{Name: "monkey"},
+	}, nil
+}
+
+func (s *Server) shutdownRequest(json.RawMessage) (interface{}, error) {
+	s.shutdown = true
+	return nil, nil
+}
+
+func (s *Server) didOpen(raw json.RawMessage) (interface{}, error) {
+	var params DidOpenTextDocumentParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, err
+	}
+	doc := newDocument(params.TextDocument.Text)
+	s.documents[params.TextDocument.URI] = doc
+	return nil, s.publishDiagnostics(params.TextDocument.URI, doc)
+}
+
+func (s *Server) didChange(raw json.RawMessage) (interface{}, error) {
+	var params DidChangeTextDocumentParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, err
+	}
+	doc, ok := s.documents[params.TextDocument.URI]
+	if !ok || len(params.ContentChanges) == 0 {
+		return nil, nil
+	}
+	doc.update(params.ContentChanges[len(params.ContentChanges)-1].Text)
+	return nil, s.publishDiagnostics(params.TextDocument.URI, doc)
+}
+
+func (s *Server) didClose(raw json.RawMessage) (interface{}, error) {
+	var params DidCloseTextDocumentParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, err
+	}
+	delete(s.documents, params.TextDocument.URI)
+	// Clear the diagnostics of the closed document
+	return nil, s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+		URI:         params.TextDocument.URI,
+		Diagnostics: []Diagnostic{},
+	})
+}
+
+func (s *Server) publishDiagnostics(uri string, doc *document) error {
+	lines := strings.Split(doc.text, "\n")
+	diagnostics := make([]Diagnostic, 0, len(doc.errors))
+	for _, err := range doc.errors {
+		start := position(lines, err.Line, err.Column)
+		diagnostics = append(diagnostics, Diagnostic{
+			Range:    Range{Start: start, End: Position{Line: start.Line, Character: start.Character + 1}},
+			Severity: severityError,
+			Source:   "monkey",
+			Message:  err.Message,
+		})
+	}
+	return s.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: diagnostics})
+}
+
+// analysisAt returns the analysis of a document and the lexer position of a protocol position
+func (s *Server) analysisAt(raw json.RawMessage) (*analysis, int, int, error) {
+	var params TextDocumentPositionParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, 0, 0, err
+	}
+	doc, ok := s.documents[params.TextDocument.URI]
+	if !ok || doc.analysis == nil {
+		return nil, 0, 0, nil
+	}
+	line, column := offset(doc.analysis.lines, params.Position)
+	return doc.analysis, line, column, nil
+}
+
+func (s *Server) hover(raw json.RawMessage) (interface{}, error) {
+	a, line, column, err := s.analysisAt(raw)
+	if a == nil || err != nil {
+		return nil, err
+	}
+	ident := a.identifierAt(line, column)
+	if ident == nil {
+		return nil, nil
+	}
+
+	var value string
+	decl, ok := a.decls[ident]
+	switch {
+	case ok:
+		value = "```monkey\n" + describe(a, decl) + "\n```"
+	case isBuiltin(s.builtins, ident.Value):
+		doc := builtinDocs[ident.Value]
+		value = "```monkey\n" + doc.signature + "\n```\n" + doc.doc
+	default:
+		return nil, nil
+	}
+	r := identifierRange(a.lines, ident)
+	return Hover{Contents: MarkupContent{Kind: "markdown", Value: value}, Range: &r}, nil
+}
+
+// describe returns how a declaration is shown in hovers, like let x: int
+func describe(a *analysis, decl *declaration) string {
+	name := decl.ident.Value
+	switch decl.kind {
+	case importDeclaration:
+		return "import " + name
+	case parameterDeclaration:
+		name = "parameter " + name
+	default:
+		name = "let " + name
+	}
+	if t, ok := a.types[decl.ident]; ok {
+		return name + ": " + t.String()
+	}
+	return name
+}
+
+func isBuiltin(builtins []string, name string) bool {
+	for _, builtin := range builtins {
+		if builtin == name {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) definition(raw json.RawMessage) (interface{}, error) {
+	var params TextDocumentPositionParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, err
+	}
+	a, line, column, err := s.analysisAt(raw)
+	if a == nil || err != nil {
+		return nil, err
+	}
+	ident := a.identifierAt(line, column)
+	if ident == nil {
+		return nil, nil
+	}
+	decl, ok := a.decls[ident]
+	if !ok {
+		return nil, nil
+	}
+	return Location{URI: params.TextDocument.URI, Range: identifierRange(a.lines, decl.ident)}, nil
+}
+
+func (s *Server) completion(raw json.RawMessage) (interface{}, error) {
+	a, line, column, err := s.analysisAt(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]CompletionItem, 0)
+	seen := make(map[string]bool)
+	if a != nil {
+		for sc := a.scopeAt(line, column); sc != nil; sc = sc.outer {
+			for _, name := range sc.names {
+				if seen[name] {
+					// Shadowed by an inner scope
+					continue
+				}
+				seen[name] = true
+				decl := sc.lookup(name, line, column)
+				items = append(items, CompletionItem{
+					Label:  name,
+					Kind:   completionKind(decl),
+					Detail: describe(a, decl),
+				})
+			}
+		}
+	}
+	for _, name := range s.builtins {
+		if seen[name] {
+			continue
+		}
+		doc := builtinDocs[name]
+		items = append(items, CompletionItem{
+			Label:         name,
+			Kind:          completionFunction,
+			Detail:        doc.signature,
+			Documentation: &MarkupContent{Kind: "markdown", Value: doc.doc},
+		})
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+	return items, nil
+}
+
+func completionKind(decl *declaration) int {
+	switch {
+	case decl.kind == importDeclaration:
+		return completionModule
+	case isFunction(decl.value):
+		return completionFunction
+	default:
+		return completionVariable
+	}
+}
+
+func isFunction(exp ast.Expression) bool {
+	_, ok := exp.(*ast.FunctionLiteral)
+	return ok
+}
+
+func (s *Server) documentSymbol(raw json.RawMessage) (interface{}, error) {
+	var params DocumentSymbolParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, err
+	}
+	doc, ok := s.documents[params.TextDocument.URI]
+	if !ok || doc.analysis == nil {
+		return []DocumentSymbol{}, nil
+	}
+	return symbols(doc.analysis, doc.analysis.program.Statements), nil
+}
+
+// symbols returns the lets and imports of a block, with the lets of the functions they bind as
+// children
+func symbols(a *analysis, statements []ast.Statement) []DocumentSymbol {
+	syms := make([]DocumentSymbol, 0)
+	for _, stmt := range statements {
+		switch stmt := stmt.(type) {
+		case *ast.LetStatement:
+			sym := DocumentSymbol{
+				Name:           stmt.Name.Value,
+				Detail:         describe(a, a.decls[stmt.Name]),
+				Kind:           symbolVariable,
+				Range:          identifierRange(a.lines, stmt.Name),
+				SelectionRange: identifierRange(a.lines, stmt.Name),
+			}
+			sym.Range.Start = position(a.lines, stmt.Token.Line, stmt.Token.Column)
+			if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok {
+				sym.Kind = symbolFunction
+				sym.Range.End = position(a.lines, fn.Body.EndToken.Line, fn.Body.EndToken.Column+1)
+				sym.Children = symbols(a, fn.Body.Statements)
+			}
+			syms = append(syms, sym)
+		case *ast.ImportStatement:
+			start := position(a.lines, stmt.Token.Line, stmt.Token.Column)
+			syms = append(syms, DocumentSymbol{
+				Name:           stmt.Name.Value,
+				Detail:         stmt.Path.Value,
+				Kind:           symbolModule,
+				Range:          Range{Start: start, End: identifierRange(a.lines, stmt.Name).End},
+				SelectionRange: identifierRange(a.lines, stmt.Name),
+			})
+		}
+	}
+	return syms
+}
+
+func (s *Server) formatting(raw json.RawMessage) (interface{}, error) {
+	var params DocumentFormattingParams
+	if err := json.Unmarshal(raw, &params); err != nil {
+		return nil, err
+	}
+	doc, ok := s.documents[params.TextDocument.URI]
+	if !ok {
+		return nil, nil
+	}
+	formatted, err := formatter.Format(doc.text)
+	if err != nil {
+		// The syntax errors are already reported as diagnostics
+		return nil, nil
+	}
+	if formatted == doc.text {
+		return []TextEdit{}, nil
+	}
+	lines := strings.Split(doc.text, "\n")
+	end := Position{Line: len(lines) - 1, Character: utf16Length(lines[len(lines)-1])}
+	return []TextEdit{{Range: Range{End: end}, NewText: formatted}}, nil
+}
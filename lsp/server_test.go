@@ -0,0 +1,222 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"monkey_interpreter/evaluator"
+	"monkey_interpreter/wire"
+	"os"
+	"testing"
+)
+
+const uri = "file:///test.mk"
+
+// session runs a server over the messages and returns what it wrote, responses by ID and
+// notifications by method
+func session(t *testing.T, messages ...interface{}) (map[int]json.RawMessage, map[string][]json.RawMessage) {
+	var in, out bytes.Buffer
+	for _, msg := range messages {
+		assert.NoError(t, wire.WriteMessage(&in, msg))
+	}
+	builtins := evaluator.NewContext(os.Stdin, io.Discard, io.Discard).BuiltinNames()
+	assert.NoError(t, NewServer(&in, &out, builtins).Run())
+
+	responses := make(map[int]json.RawMessage)
+	notifications := make(map[string][]json.RawMessage)
+	r := bufio.NewReader(&out)
+	for {
+		body, err := wire.ReadMessage(r)
+		if err == io.EOF {
+			break
+		}
+		assert.NoError(t, err)
+		var msg struct {
+			ID     *int            `json:"id"`
+			Method string          `json:"method"`
+			Result json.RawMessage `json:"result"`
+			Params json.RawMessage `json:"params"`
+			Error  *responseError  `json:"error"`
+		}
+		assert.NoError(t, json.Unmarshal(body, &msg))
+		switch {
+		case msg.Error != nil:
+			responses[*msg.ID], _ = json.Marshal(msg.Error)
+		case msg.ID != nil:
+			responses[*msg.ID] = msg.Result
+		default:
+			notifications[msg.Method] = append(notifications[msg.Method], msg.Params)
+		}
+	}
+	return responses, notifications
+}
+
+func call(id int, method string, params interface{}) map[string]interface{} {
+	return map[string]interface{}{"jsonrpc": "2.0", "id": id, "method": method, "params": params}
+}
+
+func notice(method string, params interface{}) map[string]interface{} {
+	return map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params}
+}
+
+func open(text string) map[string]interface{} {
+	return notice("textDocument/didOpen", DidOpenTextDocumentParams{
+		TextDocument: TextDocumentItem{URI: uri, LanguageID: "monkey", Version: 1, Text: text},
+	})
+}
+
+func at(line, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{
+		TextDocument: TextDocumentIdentifier{URI: uri},
+		Position:     Position{Line: line, Character: character},
+	}
+}
+
+// serve runs a session opening text and sending a request, returning its response
+func serve(t *testing.T, text, method string, params interface{}) string {
+	responses, _ := session(t,
+		call(1, "initialize", map[string]interface{}{}),
+		open(text),
+		call(2, method, params),
+		call(3, "shutdown", nil),
+		notice("exit", nil),
+	)
+	return string(responses[2])
+}
+
+func TestLifecycle(t *testing.T) {
+	responses, _ := session(t,
+		call(1, "textDocument/hover", at(0, 0)),
+		call(2, "initialize", map[string]interface{}{}),
+		call(3, "textDocument/unknown", nil),
+		call(4, "shutdown", nil),
+		notice("exit", nil),
+	)
+	assert.JSONEq(t, `{"code": -32002, "message": "server not initialized"}`, string(responses[1]))
+	assert.Contains(t, string(responses[2]), `"hoverProvider":true`)
+	assert.JSONEq(t, `{"code": -32601, "message": "method not supported: textDocument/unknown"}`, string(responses[3]))
+	assert.Equal(t, "null", string(responses[4]))
+
+	var in, out bytes.Buffer
+	assert.NoError(t, wire.WriteMessage(&in, notice("exit", nil)))
+	assert.Error(t, NewServer(&in, &out, nil).Run())
+}
+
+func TestDiagnostics(t *testing.T) {
+	_, notifications := session(t,
+		call(1, "initialize", map[string]interface{}{}),
+		open("let x = 1;\nlet = 2;"),
+		notice("textDocument/didChange", DidChangeTextDocumentParams{
+			TextDocument:   TextDocumentIdentifier{URI: uri},
+			ContentChanges: []TextDocumentContentChangeEvent{{Text: "let x = 1;"}},
+		}),
+		call(2, "shutdown", nil),
+		notice("exit", nil),
+	)
+
+	published := notifications["textDocument/publishDiagnostics"]
+	assert.Equal(t, 2, len(published))
+	assert.JSONEq(t, `{"uri": "file:///test.mk", "diagnostics": [
+		{"range": {"start": {"line": 1, "character": 4}, "end": {"line": 1, "character": 5}}, "severity": 1, "source": "monkey",
+		 "message": "Expected next token to be 'IDENT' - got '=' instead"},
+		{"range": {"start": {"line": 1, "character": 4}, "end": {"line": 1, "character": 5}}, "severity": 1, "source": "monkey",
+		 "message": "Missing prefixParseFn for token ="}
+	]}`, string(published[0]))
+	assert.JSONEq(t, `{"uri": "file:///test.mk", "diagnostics": []}`, string(published[1]))
+}
+
+func TestHover(t *testing.T) {
+	text := "let add = fn(a, b) { a + b };\nadd(1, 2);\nlen(\"é\") + add(1, 2);"
+	tests := []struct {
+		position Position
+		expected string
+	}{
+		{Position{Line: 1, Character: 1}, `{"contents": {"kind": "markdown", "value": "` + "```monkey\\nlet add: fn(t2, t2) -> t2\\n```" + `"},
+			"range": {"start": {"line": 1, "character": 0}, "end": {"line": 1, "character": 3}}}`},
+		{Position{Line: 0, Character: 21}, `{"contents": {"kind": "markdown", "value": "` + "```monkey\\nparameter a: t2\\n```" + `"},
+			"range": {"start": {"line": 0, "character": 21}, "end": {"line": 0, "character": 22}}}`},
+		{Position{Line: 2, Character: 0}, `{"contents": {"kind": "markdown", "value": "` + "```monkey\\nlen(value)\\n```\\n" +
+			`Returns the number of characters of a string or elements of an array."},
+			"range": {"start": {"line": 2, "character": 0}, "end": {"line": 2, "character": 3}}}`},
+		// The string is 2 bytes but a single UTF-16 code unit
+		{Position{Line: 2, Character: 11}, `{"contents": {"kind": "markdown", "value": "` + "```monkey\\nlet add: fn(t2, t2) -> t2\\n```" + `"},
+			"range": {"start": {"line": 2, "character": 11}, "end": {"line": 2, "character": 14}}}`},
+		{Position{Line: 1, Character: 5}, `null`},
+	}
+
+	for _, tt := range tests {
+		assert.JSONEq(t, tt.expected, serve(t, text, "textDocument/hover", at(tt.position.Line, tt.position.Character)), tt.position)
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	text := "let x = 1;\nlet f = fn(x) {\n  x + 1\n};\nf(x);"
+	tests := []struct {
+		position Position
+		expected string
+	}{
+		{Position{Line: 2, Character: 2}, `{"uri": "file:///test.mk", "range": {"start": {"line": 1, "character": 11}, "end": {"line": 1, "character": 12}}}`},
+		{Position{Line: 4, Character: 2}, `{"uri": "file:///test.mk", "range": {"start": {"line": 0, "character": 4}, "end": {"line": 0, "character": 5}}}`},
+		{Position{Line: 4, Character: 0}, `{"uri": "file:///test.mk", "range": {"start": {"line": 1, "character": 4}, "end": {"line": 1, "character": 5}}}`},
+		{Position{Line: 3, Character: 0}, `null`},
+	}
+
+	for _, tt := range tests {
+		assert.JSONEq(t, tt.expected, serve(t, text, "textDocument/definition", at(tt.position.Line, tt.position.Character)), tt.position)
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	text := "import \"lib.mk\" as lib;\nlet count = 1;\nlet f = fn(len) {\n  let inner = 2;\n  \n};\n"
+	var items []CompletionItem
+	assert.NoError(t, json.Unmarshal([]byte(serve(t, text, "textDocument/completion", at(4, 2))), &items))
+
+	byLabel := make(map[string]CompletionItem)
+	for _, item := range items {
+		byLabel[item.Label] = item
+	}
+	assert.Equal(t, CompletionItem{Label: "inner", Kind: completionVariable, Detail: "let inner: int"}, byLabel["inner"])
+	assert.Equal(t, CompletionItem{Label: "len", Kind: completionVariable, Detail: "parameter len: t1"}, byLabel["len"])
+	assert.Equal(t, CompletionItem{Label: "f", Kind: completionFunction, Detail: "let f: fn(t1) -> any"}, byLabel["f"])
+	assert.Equal(t, completionModule, byLabel["lib"].Kind)
+	assert.Equal(t, "puts(value...)", byLabel["puts"].Detail)
+
+	assert.NoError(t, json.Unmarshal([]byte(serve(t, text, "textDocument/completion", at(1, 0))), &items))
+	for _, item := range items {
+		assert.NotEqual(t, "inner", item.Label)
+	}
+}
+
+func TestDocumentSymbol(t *testing.T) {
+	text := "import \"lib.mk\" as lib;\nlet add = fn(a, b) {\n  let sum = a + b;\n  sum\n};"
+	expected := `[
+		{"name": "lib", "detail": "lib.mk", "kind": 2,
+		 "range": {"start": {"line": 0, "character": 0}, "end": {"line": 0, "character": 22}},
+		 "selectionRange": {"start": {"line": 0, "character": 19}, "end": {"line": 0, "character": 22}}},
+		{"name": "add", "detail": "let add: fn(t2, t2) -> t2", "kind": 12,
+		 "range": {"start": {"line": 1, "character": 0}, "end": {"line": 4, "character": 1}},
+		 "selectionRange": {"start": {"line": 1, "character": 4}, "end": {"line": 1, "character": 7}},
+		 "children": [
+			{"name": "sum", "detail": "let sum: t2", "kind": 13,
+			 "range": {"start": {"line": 2, "character": 2}, "end": {"line": 2, "character": 9}},
+			 "selectionRange": {"start": {"line": 2, "character": 6}, "end": {"line": 2, "character": 9}}}
+		 ]}
+	]`
+	assert.JSONEq(t, expected, serve(t, text, "textDocument/documentSymbol", DocumentSymbolParams{TextDocument: TextDocumentIdentifier{URI: uri}}))
+}
+
+func TestFormatting(t *testing.T) {
+	params := DocumentFormattingParams{TextDocument: TextDocumentIdentifier{URI: uri}}
+	assert.JSONEq(t, `[{"range": {"start": {"line": 0, "character": 0}, "end": {"line": 1, "character": 4}}, "newText": "let x = 1;\nx;\n"}]`,
+		serve(t, "let x=1;\nx   ", "textDocument/formatting", params))
+	assert.JSONEq(t, `[]`, serve(t, "let x = 1;\n", "textDocument/formatting", params))
+	assert.Equal(t, `null`, serve(t, "let = 1", "textDocument/formatting", params))
+}
+
+func TestBuiltinDocs(t *testing.T) {
+	for _, name := range evaluator.NewContext(os.Stdin, io.Discard, io.Discard).BuiltinNames() {
+		assert.Contains(t, builtinDocs, name)
+	}
+}
@@ -2,12 +2,17 @@ package main
 
 import (
 	"fmt"
+	"monkey_interpreter/cmd"
 	"monkey_interpreter/repl"
 	"os"
 	user2 "os/user"
 )
 
 func main() {
+	if len(os.Args) > 1 {
+		os.Exit(cmd.Run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr))
+	}
+
 	user, err := user2.Current()
 	if err != nil {
 		fmt.Print("Could not find system user", err)
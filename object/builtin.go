@@ -3,7 +3,8 @@ package object
 type BuiltInFunction func(args ...Object) Object
 
 type BuiltIn struct {
-	Fn BuiltInFunction
+	Name string
+	Fn   BuiltInFunction
 }
 
 func (bi *BuiltIn) Type() Type {
@@ -0,0 +1,270 @@
+package object
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Scheduler tracks the goroutines of an evaluation and the channels they communicate through. All
+// channel operations of an evaluation take its lock, so it knows when every goroutine is blocked
+// on a channel and none can ever be woken up again
+type Scheduler struct {
+	mu      sync.Mutex
+	live    int              // goroutines running, the one of the evaluation while it is in it
+	waiting map[*waiter]bool // goroutines blocked in a channel operation
+}
+
+func NewScheduler() *Scheduler {
+	return &Scheduler{waiting: make(map[*waiter]bool)}
+}
+
+// Enter counts the goroutine of the evaluation as running until it calls Leave
+func (s *Scheduler) Enter() {
+	s.mu.Lock()
+	s.live++
+	s.mu.Unlock()
+}
+
+// Leave ends the evaluation. The goroutines still blocked can never be woken up then and fail
+// with a deadlock, instead of leaking once the program is done
+func (s *Scheduler) Leave() {
+	s.mu.Lock()
+	s.live--
+	s.detectDeadlock()
+	s.mu.Unlock()
+}
+
+// Go runs f on a new goroutine of the evaluation
+func (s *Scheduler) Go(f func()) {
+	s.mu.Lock()
+	s.live++
+	s.mu.Unlock()
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			s.live--
+			// The goroutines waiting for this one may never be woken up now
+			s.detectDeadlock()
+			s.mu.Unlock()
+		}()
+		f()
+	}()
+}
+
+// waiter is a goroutine blocked in a channel operation, on several channels for a select
+type waiter struct {
+	ready chan struct{}
+	done  bool // whether an operation completed, the other queued cases of a select are stale then
+	index int  // the case that completed
+	value Object
+	ok    bool // false when a receive completed because the channel was closed
+	err   *Error
+}
+
+// pending is a case of a waiter queued on a channel
+type pending struct {
+	waiter *waiter
+	index  int
+	value  Object // for sends
+}
+
+// wake completes the operation of w, under the lock of the scheduler
+func (s *Scheduler) wake(w *waiter, index int, value Object, ok bool, err *Error) {
+	w.done, w.index, w.value, w.ok, w.err = true, index, value, ok, err
+	delete(s.waiting, w)
+	w.ready <- struct{}{}
+}
+
+// block waits until another goroutine completes an operation of w, called with the lock held and
+// returning without it
+func (s *Scheduler) block(w *waiter) {
+	s.waiting[w] = true
+	s.detectDeadlock()
+	s.mu.Unlock()
+	<-w.ready
+}
+
+// detectDeadlock fails the operations of all the waiting goroutines if every goroutine waits
+func (s *Scheduler) detectDeadlock() {
+	if len(s.waiting) == 0 || len(s.waiting) < s.live {
+		return
+	}
+	for w := range s.waiting {
+		s.wake(w, -1, nil, false, &Error{Message: "all goroutines are asleep - deadlock!"})
+	}
+}
+
+// Channel passes values between goroutines, holding up to its capacity of values sent but not yet
+// received. A send on an unbuffered channel waits for a receiver
+type Channel struct {
+	scheduler *Scheduler
+	capacity  int
+	buffer    []Object
+	closed    bool
+	receivers []*pending
+	senders   []*pending
+}
+
+// NewChannel creates a channel of the goroutines of the scheduler
+func (s *Scheduler) NewChannel(capacity int) *Channel {
+	return &Channel{scheduler: s, capacity: capacity}
+}
+
+func (ch *Channel) Type() Type {
+	return ChannelObj
+}
+
+func (ch *Channel) Inspect() string {
+	return fmt.Sprintf("channel(%d)", ch.capacity)
+}
+
+// pop removes the first waiter of a queue whose operation did not complete yet
+func pop(queue *[]*pending) *pending {
+	for len(*queue) > 0 {
+		p := (*queue)[0]
+		*queue = (*queue)[1:]
+		if !p.waiter.done {
+			return p
+		}
+	}
+	return nil
+}
+
+// trySend sends a value if it can be done without waiting, reporting whether it was
+func (ch *Channel) trySend(value Object) (bool, *Error) {
+	if ch.closed {
+		return false, &Error{Message: "send on closed channel"}
+	}
+	if r := pop(&ch.receivers); r != nil {
+		ch.scheduler.wake(r.waiter, r.index, value, true, nil)
+		return true, nil
+	}
+	if len(ch.buffer) < ch.capacity {
+		ch.buffer = append(ch.buffer, value)
+		return true, nil
+	}
+	return false, nil
+}
+
+// tryRecv receives a value if it can be done without waiting, reporting whether it was. The value
+// is nil and ok false once the channel is closed and drained
+func (ch *Channel) tryRecv() (value Object, ok, done bool) {
+	if len(ch.buffer) > 0 {
+		value, ch.buffer = ch.buffer[0], ch.buffer[1:]
+		// A waiting sender can fill the slot freed
+		if s := pop(&ch.senders); s != nil {
+			ch.buffer = append(ch.buffer, s.value)
+			ch.scheduler.wake(s.waiter, s.index, nil, true, nil)
+		}
+		return value, true, true
+	}
+	if s := pop(&ch.senders); s != nil {
+		ch.scheduler.wake(s.waiter, s.index, nil, true, nil)
+		return s.value, true, true
+	}
+	if ch.closed {
+		return nil, false, true
+	}
+	return nil, false, false
+}
+
+// Send passes a value to a receiver, or to the buffer if it has room, waiting until one of them
+// can take it
+func (ch *Channel) Send(value Object) *Error {
+	_, _, _, err := ch.scheduler.Select([]SelectCase{{Channel: ch, Send: true, Value: value}}, true)
+	return err
+}
+
+// Recv returns the next value sent, waiting until there is one. Once the channel is closed and
+// drained it returns nil and false
+func (ch *Channel) Recv() (Object, bool, *Error) {
+	_, value, ok, err := ch.scheduler.Select([]SelectCase{{Channel: ch}}, true)
+	return value, ok, err
+}
+
+// Close makes receives of a drained channel return at once, waking up the waiting receivers.
+// Waiting senders fail
+func (ch *Channel) Close() *Error {
+	s := ch.scheduler
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if ch.closed {
+		return &Error{Message: "close of closed channel"}
+	}
+	ch.closed = true
+	for r := pop(&ch.receivers); r != nil; r = pop(&ch.receivers) {
+		s.wake(r.waiter, r.index, nil, false, nil)
+	}
+	for w := pop(&ch.senders); w != nil; w = pop(&ch.senders) {
+		s.wake(w.waiter, w.index, nil, false, &Error{Message: "send on closed channel"})
+	}
+	return nil
+}
+
+// SelectCase is a send of Value or a receive on a channel
+type SelectCase struct {
+	Channel *Channel
+	Send    bool
+	Value   Object
+}
+
+// Select completes the first case that can proceed without waiting. Otherwise it waits for one of
+// them to proceed when block is set, or returns -1. Receives return the value and whether it was
+// sent, like Recv
+func (s *Scheduler) Select(cases []SelectCase, block bool) (index int, value Object, ok bool, err *Error) {
+	for _, c := range cases {
+		if c.Channel.scheduler != s {
+			return -1, nil, false, &Error{Message: "channel belongs to another evaluation"}
+		}
+	}
+
+	s.mu.Lock()
+	for i, c := range cases {
+		if c.Send {
+			sent, err := c.Channel.trySend(c.Value)
+			if err != nil || sent {
+				s.mu.Unlock()
+				return i, nil, sent, err
+			}
+			continue
+		}
+		if value, ok, done := c.Channel.tryRecv(); done {
+			s.mu.Unlock()
+			return i, value, ok, nil
+		}
+	}
+	if !block {
+		s.mu.Unlock()
+		return -1, nil, false, nil
+	}
+
+	w := &waiter{ready: make(chan struct{}, 1)}
+	for i, c := range cases {
+		p := &pending{waiter: w, index: i, value: c.Value}
+		if c.Send {
+			c.Channel.senders = append(c.Channel.senders, p)
+		} else {
+			c.Channel.receivers = append(c.Channel.receivers, p)
+		}
+	}
+	s.block(w)
+
+	// Take the cases that did not complete off the queues
+	s.mu.Lock()
+	for _, c := range cases {
+		c.Channel.receivers = remove(c.Channel.receivers, w)
+		c.Channel.senders = remove(c.Channel.senders, w)
+	}
+	s.mu.Unlock()
+	return w.index, w.value, w.ok, w.err
+}
+
+func remove(queue []*pending, w *waiter) []*pending {
+	kept := queue[:0]
+	for _, p := range queue {
+		if p.waiter != w {
+			kept = append(kept, p)
+		}
+	}
+	return kept
+}
@@ -1,13 +1,28 @@
 package object
 
+import (
+	"sort"
+	"sync"
+)
+
+// Environment holds the variables of a scope. Top level and unresolved code bind variables by
+// name in store, while the frame of a resolved function call keeps them in slots addressed by the
+// indices the resolver assigned. Environments are safe for concurrent use, functions spawned on
+// other goroutines share the ones they close over. A frozen environment is read only, so
+// evaluations running concurrently can share it, enclosed in environments of their own, without
+// locking or copying it
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	mu     sync.RWMutex
+	frozen bool
+	store  map[string]Object
+	names  []string // names of the slots
+	slots  []Object
+	outer  *Environment
 }
 
 func NewEnvironment() *Environment {
 	s := make(map[string]Object, 0)
-	return &Environment{s, nil}
+	return &Environment{store: s}
 }
 
 func NewEnclosedEnvironment(env *Environment) *Environment {
@@ -17,15 +32,160 @@ func NewEnclosedEnvironment(env *Environment) *Environment {
 	}
 }
 
+// NewFrame creates the environment of a resolved function call with one empty slot per name
+func NewFrame(outer *Environment, names []string) *Environment {
+	return &Environment{
+		names: names,
+		slots: make([]Object, len(names)),
+		outer: outer,
+	}
+}
+
+// Freeze makes the environment read only. It must happen before the environment is shared, the
+// environments enclosed by it stay writable
+func (e *Environment) Freeze() {
+	e.mu.Lock()
+	e.frozen = true
+	e.mu.Unlock()
+}
+
+// Frozen reports whether the environment is read only
+func (e *Environment) Frozen() bool {
+	e.rlock()
+	defer e.runlock()
+	return e.frozen
+}
+
+// rlock locks the environment for reading unless it is frozen, nothing writes to it then
+func (e *Environment) rlock() {
+	if !e.frozen {
+		e.mu.RLock()
+	}
+}
+
+func (e *Environment) runlock() {
+	if !e.frozen {
+		e.mu.RUnlock()
+	}
+}
+
+// lock locks the environment for writing, panicking if it is frozen
+func (e *Environment) lock() {
+	e.mu.Lock()
+	if e.frozen {
+		e.mu.Unlock()
+		panic("object: write to a frozen environment")
+	}
+}
+
 func (e *Environment) Get(name string) (Object, bool) {
+	e.rlock()
 	obj, ok := e.store[name]
-	for !ok && e.outer != nil {
+	if !ok {
+		obj, ok = e.getSlotByName(name)
+	}
+	e.runlock()
+	if !ok && e.outer != nil {
 		obj, ok = e.outer.Get(name)
 	}
 	return obj, ok
 }
 
-func (e Environment) Set(name string, val Object) Object {
+func (e *Environment) getSlotByName(name string) (Object, bool) {
+	for i, slotName := range e.names {
+		if slotName == name && e.slots[i] != nil {
+			return e.slots[i], true
+		}
+	}
+	return nil, false
+}
+
+// Set binds a variable in the environment, which must not be frozen
+func (e *Environment) Set(name string, val Object) Object {
+	e.lock()
+	defer e.mu.Unlock()
+	for i, slotName := range e.names {
+		if slotName == name {
+			e.slots[i] = val
+			return val
+		}
+	}
+	if e.store == nil {
+		e.store = make(map[string]Object)
+	}
 	e.store[name] = val
 	return val
 }
+
+// Assign replaces the value of a variable in the innermost environment of the chain that binds it,
+// reporting false if none does or that environment is frozen
+func (e *Environment) Assign(name string, val Object) bool {
+	for env := e; env != nil; env = env.outer {
+		if bound, assigned := env.assign(name, val); bound {
+			return assigned
+		}
+	}
+	return false
+}
+
+func (e *Environment) assign(name string, val Object) (bound, assigned bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if _, ok := e.store[name]; ok {
+		if !e.frozen {
+			e.store[name] = val
+		}
+		return true, !e.frozen
+	}
+	for i, slotName := range e.names {
+		if slotName == name && e.slots[i] != nil {
+			if !e.frozen {
+				e.slots[i] = val
+			}
+			return true, !e.frozen
+		}
+	}
+	return false, false
+}
+
+// Outer returns the environment depth levels up the chain, nil if the chain is shorter
+func (e *Environment) Outer(depth int) *Environment {
+	env := e
+	for i := 0; i < depth && env != nil; i++ {
+		env = env.outer
+	}
+	return env
+}
+
+// GetSlot returns the value in slot of the frame depth levels up, it is unset until assigned
+func (e *Environment) GetSlot(depth, slot int) (Object, bool) {
+	env := e.Outer(depth)
+	env.rlock()
+	obj := env.slots[slot]
+	env.runlock()
+	return obj, obj != nil
+}
+
+func (e *Environment) SetSlot(slot int, val Object) Object {
+	e.lock()
+	e.slots[slot] = val
+	e.mu.Unlock()
+	return val
+}
+
+// Names lists the variables bound in the environment in sorted order, not including the outer ones
+func (e *Environment) Names() []string {
+	e.rlock()
+	defer e.runlock()
+	names := make([]string, 0, len(e.store)+len(e.slots))
+	for name := range e.store {
+		names = append(names, name)
+	}
+	for i, name := range e.names {
+		if e.slots[i] != nil {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
@@ -3,7 +3,9 @@ package object
 import "fmt"
 
 type Error struct {
-	Message string
+	Message   string
+	Assertion bool  // raised by a failed assertion rather than a bug of the program
+	Err       error // the Go error it reports, like the one a Debugger stopped the evaluation with
 }
 
 func (e *Error) Type() Type {
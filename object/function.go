@@ -10,6 +10,8 @@ type Function struct {
 	Parameters []*ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
+	Locals     []string // slot names of the call frame, nil if the function was not resolved
+	File       string   // absolute path of the file the function was defined in, only set while debugging
 }
 
 func (f *Function) Type() Type {
@@ -6,8 +6,16 @@ import (
 	"strings"
 )
 
+// Hash keeps its pairs in insertion order, with a HashKey index for constant time lookup
 type Hash struct {
-	Pairs map[HashKey]HashPair
+	pairs map[HashKey]HashPair
+	keys  []HashKey
+}
+
+func NewHash() *Hash {
+	return &Hash{
+		pairs: make(map[HashKey]HashPair),
+	}
 }
 
 func (h *Hash) Type() Type {
@@ -18,7 +26,7 @@ func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 
 	var pairs []string
-	for _, pair := range h.Pairs {
+	for _, pair := range h.Pairs() {
 		pairs = append(pairs, fmt.Sprintf("%s : %s", pair.Key.Inspect(), pair.Value.Inspect()))
 	}
 
@@ -28,3 +36,42 @@ func (h *Hash) Inspect() string {
 
 	return out.String()
 }
+
+// Set stores the pair under the given key. Overwriting an existing key keeps its original position
+func (h *Hash) Set(key HashKey, pair HashPair) {
+	if _, ok := h.pairs[key]; !ok {
+		h.keys = append(h.keys, key)
+	}
+	h.pairs[key] = pair
+}
+
+func (h *Hash) Get(key HashKey) (HashPair, bool) {
+	pair, ok := h.pairs[key]
+	return pair, ok
+}
+
+func (h *Hash) Len() int {
+	return len(h.keys)
+}
+
+// Pairs returns the pairs of the hash in insertion order
+func (h *Hash) Pairs() []HashPair {
+	pairs := make([]HashPair, 0, len(h.keys))
+	for _, key := range h.keys {
+		pairs = append(pairs, h.pairs[key])
+	}
+	return pairs
+}
+
+// Copy returns a shallow copy of the hash that can be modified without affecting the original
+func (h *Hash) Copy() *Hash {
+	hash := &Hash{
+		pairs: make(map[HashKey]HashPair, len(h.pairs)),
+		keys:  make([]HashKey, len(h.keys)),
+	}
+	copy(hash.keys, h.keys)
+	for key, pair := range h.pairs {
+		hash.pairs[key] = pair
+	}
+	return hash
+}
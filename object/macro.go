@@ -0,0 +1,32 @@
+package object
+
+import (
+	"bytes"
+	"monkey_interpreter/ast"
+	"strings"
+)
+
+type Macro struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (m *Macro) Type() Type {
+	return MacroObj
+}
+
+func (m *Macro) Inspect() string {
+	var out bytes.Buffer
+	params := make([]string, 0)
+	for _, param := range m.Parameters {
+		params = append(params, param.String())
+	}
+	out.WriteString("macro")
+	out.WriteByte('(')
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(m.Body.String())
+	out.WriteString("\n}")
+	return out.String()
+}
@@ -0,0 +1,15 @@
+package object
+
+// Module is an imported file - its top level bindings are looked up in Env
+type Module struct {
+	Path string
+	Env  *Environment
+}
+
+func (m *Module) Type() Type {
+	return ModuleObj
+}
+
+func (m *Module) Inspect() string {
+	return "<module " + m.Path + ">"
+}
@@ -1,7 +1,9 @@
 package object
 
 import (
+	"fmt"
 	"github.com/stretchr/testify/assert"
+	"sync"
 	"testing"
 )
 
@@ -17,3 +19,127 @@ func TestHashMethod(t *testing.T) {
 	assert.NotEqual(t, val1.HashKey(), val2.HashKey())
 	assert.NotEqual(t, diff1.HashKey(), diff2.HashKey())
 }
+
+func TestHashInsertionOrder(t *testing.T) {
+	hash := NewHash()
+	keys := []*String{{Value: "zeta"}, {Value: "alpha"}, {Value: "mu"}}
+	for i, key := range keys {
+		hash.Set(key.HashKey(), HashPair{Key: key, Value: &Integer{Value: int64(i)}})
+	}
+	hash.Set(keys[0].HashKey(), HashPair{Key: keys[0], Value: &Integer{Value: 10}})
+
+	assert.Equal(t, 3, hash.Len())
+	assert.Equal(t, "{zeta : 10, alpha : 1, mu : 2}", hash.Inspect())
+
+	pair, ok := hash.Get((&String{Value: "mu"}).HashKey())
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), pair.Value.(*Integer).Value)
+
+	_, ok = hash.Get((&String{Value: "missing"}).HashKey())
+	assert.False(t, ok)
+}
+
+func TestEnclosedEnvironmentGet(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("a", &Integer{Value: 1})
+	inner := NewEnclosedEnvironment(outer)
+
+	val, ok := inner.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), val.(*Integer).Value)
+
+	_, ok = inner.Get("missing")
+	assert.False(t, ok)
+}
+
+func TestFrameEnvironment(t *testing.T) {
+	global := NewEnvironment()
+	global.Set("g", &Integer{Value: 1})
+	outer := NewFrame(global, []string{"a", "b"})
+	outer.SetSlot(0, &Integer{Value: 2})
+	inner := NewFrame(outer, []string{"c"})
+	inner.SetSlot(0, &Integer{Value: 3})
+
+	val, ok := inner.GetSlot(1, 0)
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), val.(*Integer).Value)
+	_, ok = inner.GetSlot(1, 1)
+	assert.False(t, ok)
+	assert.Same(t, global, inner.Outer(2))
+
+	// Lookups by name see the slots too
+	val, ok = inner.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, int64(2), val.(*Integer).Value)
+	_, ok = inner.Get("b")
+	assert.False(t, ok)
+	val, ok = inner.Get("g")
+	assert.True(t, ok)
+	assert.Equal(t, int64(1), val.(*Integer).Value)
+
+	inner.Set("c", &Integer{Value: 4})
+	inner.Set("d", &Integer{Value: 5})
+	val, _ = inner.GetSlot(0, 0)
+	assert.Equal(t, int64(4), val.(*Integer).Value)
+	assert.Equal(t, []string{"c", "d"}, inner.Names())
+	assert.Equal(t, []string{"a"}, outer.Names())
+}
+
+func TestEnvironmentAssign(t *testing.T) {
+	global := NewEnvironment()
+	global.Set("g", &Integer{Value: 1})
+	frame := NewFrame(global, []string{"a", "b"})
+	frame.SetSlot(0, &Integer{Value: 2})
+
+	assert.True(t, frame.Assign("g", &Integer{Value: 10}))
+	assert.True(t, frame.Assign("a", &Integer{Value: 20}))
+	assert.False(t, frame.Assign("b", &Integer{Value: 30}))
+	assert.False(t, frame.Assign("missing", &Integer{Value: 40}))
+
+	val, _ := global.Get("g")
+	assert.Equal(t, int64(10), val.(*Integer).Value)
+	val, _ = frame.GetSlot(0, 0)
+	assert.Equal(t, int64(20), val.(*Integer).Value)
+	assert.Equal(t, []string{"a"}, frame.Names())
+}
+
+func TestFrozenEnvironment(t *testing.T) {
+	base := NewEnvironment()
+	base.Set("x", &Integer{Value: 1})
+	base.Freeze()
+	assert.True(t, base.Frozen())
+
+	env := NewEnclosedEnvironment(base)
+	assert.False(t, env.Frozen())
+	env.Set("y", &Integer{Value: 2})
+	x, ok := env.Get("x")
+	assert.True(t, ok)
+	assert.Equal(t, "1", x.Inspect())
+
+	assert.False(t, env.Assign("x", &Integer{Value: 3}))
+	x, _ = base.Get("x")
+	assert.Equal(t, "1", x.Inspect())
+	assert.True(t, env.Assign("y", &Integer{Value: 4}))
+	assert.Panics(t, func() { base.Set("z", &Integer{Value: 5}) })
+	assert.Equal(t, []string{"x"}, base.Names())
+}
+
+func TestEnvironmentConcurrentAccess(t *testing.T) {
+	env := NewEnvironment()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("v%d", i)
+			for j := 0; j < 100; j++ {
+				env.Set(name, &Integer{Value: int64(j)})
+				env.Get(name)
+				env.Assign(name, &Integer{Value: int64(-j)})
+				env.Names()
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Len(t, env.Names(), 8)
+}
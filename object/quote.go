@@ -0,0 +1,15 @@
+package object
+
+import "monkey_interpreter/ast"
+
+type Quote struct {
+	Node ast.Node
+}
+
+func (q *Quote) Type() Type {
+	return QuoteObj
+}
+
+func (q *Quote) Inspect() string {
+	return "QUOTE(" + q.Node.String() + ")"
+}
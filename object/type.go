@@ -13,4 +13,8 @@ const (
 	BuiltInObj     = "BUILTIN"
 	ArrayObj       = "ARRAY"
 	HashObj        = "HASH"
+	QuoteObj       = "QUOTE"
+	MacroObj       = "MACRO"
+	ModuleObj      = "MODULE"
+	ChannelObj     = "CHANNEL"
 )
@@ -13,6 +13,8 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.IMPORT:
+		return p.parseImportStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -31,6 +33,7 @@ func (p *Parser) parseLetStatement() ast.Statement {
 		Token: p.curToken,
 		Value: p.curToken.Literal,
 	}
+	stmt.Name.Annotation = p.parseOptionalAnnotation()
 
 	if !p.expectPeek(token.ASSIGN) {
 		return nil
@@ -40,7 +43,7 @@ func (p *Parser) parseLetStatement() ast.Statement {
 
 	stmt.Value = p.parseExpression(LOWEST)
 
-	for !p.curTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 
@@ -56,7 +59,38 @@ func (p *Parser) parseReturnStatement() ast.Statement {
 
 	stmt.ReturnValue = p.parseExpression(LOWEST)
 
-	for !p.curTokenIs(token.SEMICOLON) {
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseImportStatement() ast.Statement {
+	stmt := &ast.ImportStatement{
+		Token: p.curToken,
+	}
+
+	if !p.expectPeek(token.STRING) {
+		return nil
+	}
+	stmt.Path = &ast.StringLiteral{
+		Token: p.curToken,
+		Value: p.curToken.Literal,
+	}
+
+	if !p.expectPeek(token.AS) {
+		return nil
+	}
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{
+		Token: p.curToken,
+		Value: p.curToken.Literal,
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
 		p.nextToken()
 	}
 
@@ -108,8 +142,7 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	}
 	val, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
 	if err != nil {
-		msg := fmt.Sprintf("Could not parse %s into int", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, "Could not parse %s into int", p.curToken.Literal)
 		return nil
 	}
 	lit.Value = val
@@ -170,8 +203,7 @@ func (p *Parser) parsePrefixExpression() ast.Expression {
 func (p *Parser) parseBoolean() ast.Expression {
 	parseBool, err := strconv.ParseBool(p.curToken.Literal)
 	if err != nil {
-		msg := fmt.Sprintf("Invalid boolean value %s", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.errorf(p.curToken, "Invalid boolean value %s", p.curToken.Literal)
 	}
 
 	exp := &ast.Boolean{
@@ -218,18 +250,57 @@ func (p *Parser) parseCallExpression(fn ast.Expression) ast.Expression {
 }
 
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
-	idxExp := &ast.IndexExpression{
-		Token: p.curToken,
+	tk := p.curToken
+
+	var idx ast.Expression
+	if !p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		idx = p.parseExpression(LOWEST)
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		return p.parseSliceExpression(tk, left, idx)
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+
+	return &ast.IndexExpression{
+		Token: tk,
+		Left:  left,
+		Index: idx,
+	}
+}
+
+func (p *Parser) parseSliceExpression(tk token.Token, left, start ast.Expression) ast.Expression {
+	sliceExp := &ast.SliceExpression{
+		Token: tk,
 		Left:  left,
+		Start: start,
 	}
+
+	// Move onto the first ':'
 	p.nextToken()
-	idxExp.Index = p.parseExpression(LOWEST)
+
+	if !p.peekTokenIs(token.COLON) && !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+		sliceExp.End = p.parseExpression(LOWEST)
+	}
+
+	if p.peekTokenIs(token.COLON) {
+		p.nextToken()
+		if !p.peekTokenIs(token.RBRACKET) {
+			p.nextToken()
+			sliceExp.Step = p.parseExpression(LOWEST)
+		}
+	}
 
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
 
-	return idxExp
+	return sliceExp
 }
 
 func (p *Parser) parseIfExpression() ast.Expression {
@@ -285,6 +356,7 @@ func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 		}
 		p.nextToken()
 	}
+	block.EndToken = p.curToken
 
 	return block
 }
@@ -301,6 +373,12 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 
 	fnLiteral.Parameters = p.parseFunctionParameters()
 
+	if p.peekTokenIs(token.ARROW) {
+		p.nextToken()
+		p.nextToken()
+		fnLiteral.ReturnType = p.parseType()
+	}
+
 	if !p.expectPeek(token.LBRACE) {
 		return nil
 	}
@@ -323,6 +401,7 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 		Token: p.curToken,
 		Value: p.curToken.Literal,
 	}
+	ident.Annotation = p.parseOptionalAnnotation()
 	identifiers = append(identifiers, ident)
 
 	for p.peekTokenIs(token.COMMA) {
@@ -333,6 +412,7 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 			Token: p.curToken,
 			Value: p.curToken.Literal,
 		}
+		identifier.Annotation = p.parseOptionalAnnotation()
 		identifiers = append(identifiers, identifier)
 	}
 
@@ -344,13 +424,16 @@ func (p *Parser) parseFunctionParameters() []*ast.Identifier {
 }
 
 func (p *Parser) noPrefixParseFnError(t token.Type) {
-	msg := fmt.Sprintf("Missing prefixParseFn for token %s", t)
-	p.errors = append(p.errors, msg)
+	p.errorf(p.curToken, "Missing prefixParseFn for token %s", t)
 }
 
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+	for p.peekToken.Type == token.COMMENT {
+		p.comments = append(p.comments, &ast.Comment{Token: p.peekToken})
+		p.peekToken = p.l.NextToken()
+	}
 }
 
 func (p *Parser) curTokenIs(t token.Type) bool {
@@ -371,9 +454,14 @@ func (p *Parser) expectPeek(t token.Type) bool {
 }
 
 func (p *Parser) peekError(t token.Type) string {
-	msg := fmt.Sprintf("Expected next token to be '%s' - got '%s' instead", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
-	return msg
+	return p.errorf(p.peekToken, "Expected next token to be '%s' - got '%s' instead", t, p.peekToken.Type)
+}
+
+// errorf records a syntax error found at tk and returns its message
+func (p *Parser) errorf(tk token.Token, format string, a ...interface{}) string {
+	err := &Error{Line: tk.Line, Column: tk.Column, Message: fmt.Sprintf(format, a...)}
+	p.errors = append(p.errors, err)
+	return err.Message
 }
 
 func (p *Parser) registerPrefixParseFn(tokenType token.Type, fn prefixParseFn) {
@@ -403,7 +491,7 @@ func (p *Parser) peekPrecedence() int {
 func (p *Parser) parseHashLiteral() ast.Expression {
 	hash := &ast.HashLiteral{
 		Token: p.curToken,
-		Pairs: make(map[ast.Expression]ast.Expression),
+		Pairs: []ast.HashLiteralPair{},
 	}
 
 	for !p.peekTokenIs(token.RBRACE) {
@@ -416,7 +504,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		p.nextToken()
 		val := p.parseExpression(LOWEST)
 
-		hash.Pairs[key] = val
+		hash.Pairs = append(hash.Pairs, ast.HashLiteralPair{Key: key, Value: val})
 
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
@@ -429,3 +517,107 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 
 	return hash
 }
+
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	macroLiteral := &ast.MacroLiteral{
+		Token: p.curToken,
+	}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	macroLiteral.Parameters = p.parseFunctionParameters()
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+	macroLiteral.Body = p.parseBlockStatement()
+
+	return macroLiteral
+}
+
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{
+		Token:  p.curToken,
+		Object: left,
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	exp.Member = &ast.Identifier{
+		Token: p.curToken,
+		Value: p.curToken.Literal,
+	}
+
+	return exp
+}
+
+// parseOptionalAnnotation parses the ": type" following a declared name, if there is one
+func (p *Parser) parseOptionalAnnotation() ast.TypeExpression {
+	if !p.peekTokenIs(token.COLON) {
+		return nil
+	}
+	p.nextToken()
+	p.nextToken()
+	return p.parseType()
+}
+
+// parseType parses a type annotation starting at the current token
+func (p *Parser) parseType() ast.TypeExpression {
+	switch p.curToken.Type {
+	case token.IDENT:
+		return &ast.NamedType{Token: p.curToken, Name: p.curToken.Literal}
+	case token.LBRACKET:
+		arrayType := &ast.ArrayType{Token: p.curToken}
+		p.nextToken()
+		arrayType.Element = p.parseType()
+		if arrayType.Element == nil || !p.expectPeek(token.RBRACKET) {
+			return nil
+		}
+		return arrayType
+	case token.LBRACE:
+		hashType := &ast.HashType{Token: p.curToken}
+		p.nextToken()
+		hashType.Key = p.parseType()
+		if hashType.Key == nil || !p.expectPeek(token.COLON) {
+			return nil
+		}
+		p.nextToken()
+		hashType.Value = p.parseType()
+		if hashType.Value == nil || !p.expectPeek(token.RBRACE) {
+			return nil
+		}
+		return hashType
+	case token.FUNCTION:
+		fnType := &ast.FunctionType{Token: p.curToken}
+		if !p.expectPeek(token.LPAREN) {
+			return nil
+		}
+		for !p.peekTokenIs(token.RPAREN) {
+			if len(fnType.Parameters) > 0 && !p.expectPeek(token.COMMA) {
+				return nil
+			}
+			p.nextToken()
+			param := p.parseType()
+			if param == nil {
+				return nil
+			}
+			fnType.Parameters = append(fnType.Parameters, param)
+		}
+		p.nextToken()
+		if !p.expectPeek(token.ARROW) {
+			return nil
+		}
+		p.nextToken()
+		fnType.Return = p.parseType()
+		if fnType.Return == nil {
+			return nil
+		}
+		return fnType
+	default:
+		p.errorf(p.curToken, "Expected a type, got '%s' instead", p.curToken.Literal)
+		return nil
+	}
+}
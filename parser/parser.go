@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"monkey_interpreter/ast"
 	"monkey_interpreter/lexer"
 	"monkey_interpreter/token"
@@ -9,7 +10,8 @@ import (
 type Parser struct {
 	l *lexer.Lexer
 
-	errors []string
+	errors   []*Error
+	comments []*ast.Comment
 
 	curToken  token.Token
 	peekToken token.Token
@@ -26,7 +28,7 @@ type (
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: []*Error{},
 	}
 
 	p.prefixParseFns = make(map[token.Type]prefixParseFn)
@@ -42,6 +44,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerPrefixParseFn(token.STRING, p.parseStringLiteral)
 	p.registerPrefixParseFn(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefixParseFn(token.LBRACE, p.parseHashLiteral)
+	p.registerPrefixParseFn(token.MACRO, p.parseMacroLiteral)
 
 	p.infixParseFns = make(map[token.Type]infixParseFn)
 	p.registerInfixParseFn(token.EQ, p.parseInfixExpression)
@@ -54,6 +57,7 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfixParseFn(token.ASTERISK, p.parseInfixExpression)
 	p.registerInfixParseFn(token.LPAREN, p.parseCallExpression)
 	p.registerInfixParseFn(token.LBRACKET, p.parseIndexExpression)
+	p.registerInfixParseFn(token.DOT, p.parseMemberExpression)
 
 	// Read 2 consecutive tokens so cur and peek tokens are set
 	p.nextToken()
@@ -73,9 +77,31 @@ func (p *Parser) ParseProgram() *ast.Program {
 		}
 		p.nextToken()
 	}
+	program.Comments = p.comments
 	return program
 }
 
+// Error is a syntax error at the position of the token it was found at
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// Error returns the messages of the syntax errors in the order they were found
 func (p *Parser) Error() []string {
+	msgs := make([]string, 0, len(p.errors))
+	for _, err := range p.errors {
+		msgs = append(msgs, err.Message)
+	}
+	return msgs
+}
+
+// Errors returns the syntax errors with their positions in the order they were found
+func (p *Parser) Errors() []*Error {
 	return p.errors
 }
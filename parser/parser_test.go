@@ -36,6 +36,35 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestImportStatement(t *testing.T) {
+	input := `import "lib/math.mk" as math;`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	assert.Equal(t, 1, len(program.Statements))
+	stmt, ok := program.Statements[0].(*ast.ImportStatement)
+	assert.True(t, ok)
+	assert.Equal(t, "lib/math.mk", stmt.Path.Value)
+	assert.Equal(t, "math", stmt.Name.Value)
+	assert.Equal(t, input, stmt.String())
+}
+
+func TestImportStatementErrors(t *testing.T) {
+	tests := []string{
+		`import math`,
+		`import "math.mk"`,
+		`import "math.mk" as "m"`,
+	}
+
+	for _, input := range tests {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		assert.NotEmpty(t, p.Error(), input)
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -301,6 +330,90 @@ func TestParseFunctionLiteral(t *testing.T) {
 	testInfixExpression(t, bodyExp.Expression, "x", "+", "y")
 }
 
+func TestParseMacroLiteral(t *testing.T) {
+	input := `macro(x, y) { x + y; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	assert.Equal(t, 1, len(program.Statements))
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	assert.True(t, ok)
+
+	macro, ok := stmt.Expression.(*ast.MacroLiteral)
+	assert.True(t, ok)
+
+	assert.Equal(t, 2, len(macro.Parameters))
+	testLiteralExpression(t, macro.Parameters[0], "x")
+	testLiteralExpression(t, macro.Parameters[1], "y")
+
+	assert.Equal(t, 1, len(macro.Body.Statements))
+	bodyExp, ok := macro.Body.Statements[0].(*ast.ExpressionStatement)
+	assert.True(t, ok)
+	testInfixExpression(t, bodyExp.Expression, "x", "+", "y")
+}
+
+func TestParseTypeAnnotations(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{`let x: int = 5;`, `let x: int = 5;`},
+		{`let xs: [string] = [];`, `let xs: [string] = [];`},
+		{`let h: {string: [int]} = {};`, `let h: {string: [int]} = {};`},
+		{`fn(a: string, b: [int]) -> bool { true }`, `fn(a: string,b: [int]) -> bool {true}`},
+		{`fn(f: fn(int, string) -> bool, g: fn() -> any) { f }`, `fn(f: fn(int, string) -> bool,g: fn() -> any){f}`},
+		{`fn(a, b: int) -> {int: bool} { a }`, `fn(a,b: int) -> {int: bool} {a}`},
+	}
+
+	for _, test := range tests {
+		p := New(lexer.New(test.input))
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+		assert.Equal(t, test.exp, program.String())
+	}
+}
+
+func TestParseTypeAnnotationErrors(t *testing.T) {
+	tests := []string{
+		`let x: = 5`,
+		`let x: [int = 5`,
+		`let x: {int} = 5`,
+		`fn(a: fn(int)) { a }`,
+		`fn(a) -> { a }`,
+	}
+
+	for _, input := range tests {
+		p := New(lexer.New(input))
+		p.ParseProgram()
+		assert.NotEmpty(t, p.Error(), input)
+	}
+}
+
+func TestParseErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let x = 1;\nlet = 2;", []string{"2:5: Expected next token to be 'IDENT' - got '=' instead", "2:5: Missing prefixParseFn for token ="}},
+		{"let x: = 5", []string{"1:8: Expected a type, got '=' instead", "1:10: Expected next token to be '=' - got 'INT' instead"}},
+		{"fn(a) {\n  a +\n}", []string{"3:1: Missing prefixParseFn for token }"}},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errs := make([]string, 0)
+		for _, err := range p.Errors() {
+			errs = append(errs, err.Error())
+		}
+		assert.Equal(t, tt.expected, errs, tt.input)
+	}
+}
+
 func TestParseFunctionLiteralParams(t *testing.T) {
 	fnTests := []struct {
 		input     string
@@ -420,10 +533,22 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"a * [1, 2, 3, 4][b * c] * d",
 			"((a * ([1, 2, 3, 4][(b * c)])) * d)",
 		},
+		{
+			"-lib.add(1, 2) * lib.x[0]",
+			"((-(lib.add)(1, 2)) * ((lib.x)[0]))",
+		},
 		{
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"a * b[1:c + 1] * d",
+			"((a * (b[1:(c + 1)])) * d)",
+		},
+		{
+			"b[::-1]",
+			"(b[::(-1)])",
+		},
 	}
 	for i, tt := range tests {
 		l := lexer.New(tt.input)
@@ -474,6 +599,84 @@ func TestIndexExpression(t *testing.T) {
 	testInfixExpression(t, idxExp.Index, 1, "+", 2)
 }
 
+func TestSliceExpression(t *testing.T) {
+	tests := []struct {
+		input string
+		start interface{}
+		end   interface{}
+		step  interface{}
+	}{
+		{"myArray[1:2]", 1, 2, nil},
+		{"myArray[1:]", 1, nil, nil},
+		{"myArray[:2]", nil, 2, nil},
+		{"myArray[:]", nil, nil, nil},
+		{"myArray[1:5:2]", 1, 5, 2},
+		{"myArray[::2]", nil, nil, 2},
+		{"myArray[a::b]", "a", nil, "b"},
+	}
+	for _, test := range tests {
+		l := lexer.New(test.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParseErrors(t, p)
+
+		assert.NotNil(t, program, "ParseProgram() returned nil")
+		assert.Equal(t, 1, len(program.Statements))
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		assert.True(t, ok)
+
+		sliceExp, ok := stmt.Expression.(*ast.SliceExpression)
+		assert.True(t, ok)
+		testIdentifier(t, sliceExp.Left, "myArray")
+
+		testOptionalLiteralExpression(t, sliceExp.Start, test.start)
+		testOptionalLiteralExpression(t, sliceExp.End, test.end)
+		testOptionalLiteralExpression(t, sliceExp.Step, test.step)
+	}
+}
+
+func testOptionalLiteralExpression(t *testing.T, exp ast.Expression, expected interface{}) {
+	if expected == nil {
+		assert.Nil(t, exp)
+		return
+	}
+	testLiteralExpression(t, exp, expected)
+}
+
+func TestParsingComments(t *testing.T) {
+	input := `// header
+	let x = 5; // five
+	fn(a) {
+		// body
+		a
+	}`
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParseErrors(t, p)
+
+	assert.Equal(t, 2, len(program.Statements))
+	assert.Equal(t, 3, len(program.Comments))
+
+	expected := []struct {
+		text string
+		line int
+	}{
+		{"// header", 1},
+		{"// five", 2},
+		{"// body", 4},
+	}
+	for i, exp := range expected {
+		assert.Equal(t, exp.text, program.Comments[i].String())
+		assert.Equal(t, exp.line, program.Comments[i].Token.Line)
+	}
+
+	fnLit := program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	assert.Equal(t, 3, fnLit.Body.Token.Line)
+	assert.Equal(t, 6, fnLit.Body.EndToken.Line)
+}
+
 func testLiteralExpression(t *testing.T, exp ast.Expression, expected interface{}) {
 	switch v := expected.(type) {
 	case int:
@@ -520,9 +723,11 @@ func TestParsingHashLiteralStringKeys(t *testing.T) {
 		"two":   2,
 		"three": 3,
 	}
-	for key, val := range hashLit.Pairs {
-		assert.Equal(t, exp[key.(*ast.StringLiteral).Value], val.(*ast.IntegerLiteral).Value)
+	assert.Equal(t, len(exp), len(hashLit.Pairs))
+	for _, pair := range hashLit.Pairs {
+		assert.Equal(t, exp[pair.Key.(*ast.StringLiteral).Value], pair.Value.(*ast.IntegerLiteral).Value)
 	}
+	assert.Equal(t, "{one : 1, two : 2, three : 3}", hashLit.String())
 }
 
 func TestParsingEmptyHashLiteral(t *testing.T) {
@@ -572,12 +777,12 @@ func TestParsingHashLiteralWithExpressions(t *testing.T) {
 			testInfixExpression(t, exp, 3, "/", 3)
 		},
 	}
-	for key, val := range hashLit.Pairs {
-		keyStmt, ok := key.(*ast.StringLiteral)
+	for _, pair := range hashLit.Pairs {
+		keyStmt, ok := pair.Key.(*ast.StringLiteral)
 		assert.True(t, ok)
 
 		testFn := exp[keyStmt.Value]
-		testFn(val)
+		testFn(pair.Value)
 	}
 }
 
@@ -0,0 +1,192 @@
+package profile
+
+import (
+	"compress/gzip"
+	"io"
+)
+
+// Field numbers of the messages of the pprof profile.proto
+const (
+	profileSampleType        = 1
+	profileSample            = 2
+	profileLocation          = 4
+	profileFunction          = 5
+	profileStringTable       = 6
+	profileDurationNanos     = 10
+	profilePeriodType        = 11
+	profilePeriod            = 12
+	profileDefaultSampleType = 14
+
+	valueTypeType = 1
+	valueTypeUnit = 2
+
+	sampleLocationID = 1
+	sampleValue      = 2
+
+	locationID   = 1
+	locationLine = 4
+
+	lineFunctionID = 1
+	lineLine       = 2
+
+	functionID         = 1
+	functionName       = 2
+	functionSystemName = 3
+	functionFilename   = 4
+	functionStartLine  = 5
+)
+
+// WritePprof writes the profile in the gzipped protocol buffer format of pprof, with the time in
+// nanoseconds and the allocations of each stack as sample values
+func (p *Profile) WritePprof(w io.Writer) error {
+	e := &pprofEncoder{strings: map[string]int{"": 0}, stringTable: []string{""}}
+	functions := make(map[*Function]uint64)
+	locations := make(map[Location]uint64)
+	var functionMessages, locationMessages []*protobuf
+
+	for _, s := range p.Samples {
+		ids := make([]uint64, 0, len(s.Stack))
+		for _, loc := range s.Stack {
+			fid, ok := functions[loc.Function]
+			if !ok {
+				fid = uint64(len(functions) + 1)
+				functions[loc.Function] = fid
+				f := &protobuf{}
+				f.uint(functionID, fid)
+				f.int(functionName, e.string(loc.Function.Name))
+				f.int(functionSystemName, e.string(loc.Function.Name))
+				f.int(functionFilename, e.string(loc.Function.File))
+				f.int(functionStartLine, int64(loc.Function.Line))
+				functionMessages = append(functionMessages, f)
+			}
+
+			lid, ok := locations[loc]
+			if !ok {
+				lid = uint64(len(locations) + 1)
+				locations[loc] = lid
+				line := &protobuf{}
+				line.uint(lineFunctionID, fid)
+				line.int(lineLine, int64(loc.Line))
+				l := &protobuf{}
+				l.uint(locationID, lid)
+				l.message(locationLine, line)
+				locationMessages = append(locationMessages, l)
+			}
+			ids = append(ids, lid)
+		}
+
+		sample := &protobuf{}
+		sample.packedUints(sampleLocationID, ids)
+		sample.packedInts(sampleValue, []int64{int64(s.Time), s.Allocs})
+		e.profile.message(profileSample, sample)
+	}
+
+	timeType := e.valueType("time", "nanoseconds")
+	allocsType := e.valueType("alloc_objects", "count")
+	e.profile.message(profileSampleType, timeType)
+	e.profile.message(profileSampleType, allocsType)
+	for _, l := range locationMessages {
+		e.profile.message(profileLocation, l)
+	}
+	for _, f := range functionMessages {
+		e.profile.message(profileFunction, f)
+	}
+	e.profile.int(profileDurationNanos, int64(p.Duration))
+	e.profile.message(profilePeriodType, e.valueType("time", "nanoseconds"))
+	e.profile.int(profilePeriod, 1)
+	e.profile.int(profileDefaultSampleType, e.string("time"))
+	for _, s := range e.stringTable {
+		e.profile.bytes(profileStringTable, []byte(s))
+	}
+
+	gz := gzip.NewWriter(w)
+	if _, err := gz.Write(e.profile.buf); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+type pprofEncoder struct {
+	profile     protobuf
+	strings     map[string]int
+	stringTable []string
+}
+
+// string returns the index of a string in the string table
+func (e *pprofEncoder) string(s string) int64 {
+	i, ok := e.strings[s]
+	if !ok {
+		i = len(e.stringTable)
+		e.strings[s] = i
+		e.stringTable = append(e.stringTable, s)
+	}
+	return int64(i)
+}
+
+func (e *pprofEncoder) valueType(typ, unit string) *protobuf {
+	m := &protobuf{}
+	m.int(valueTypeType, e.string(typ))
+	m.int(valueTypeUnit, e.string(unit))
+	return m
+}
+
+// protobuf encodes the fields of a protocol buffer message
+type protobuf struct {
+	buf []byte
+}
+
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+func (m *protobuf) varint(v uint64) {
+	for v >= 0x80 {
+		m.buf = append(m.buf, byte(v)|0x80)
+		v >>= 7
+	}
+	m.buf = append(m.buf, byte(v))
+}
+
+func (m *protobuf) key(field, wire int) {
+	m.varint(uint64(field)<<3 | uint64(wire))
+}
+
+// uint writes a field, leaving out zero values like protocol buffers do
+func (m *protobuf) uint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	m.key(field, wireVarint)
+	m.varint(v)
+}
+
+func (m *protobuf) int(field int, v int64) {
+	m.uint(field, uint64(v))
+}
+
+func (m *protobuf) bytes(field int, b []byte) {
+	m.key(field, wireBytes)
+	m.varint(uint64(len(b)))
+	m.buf = append(m.buf, b...)
+}
+
+func (m *protobuf) message(field int, msg *protobuf) {
+	m.bytes(field, msg.buf)
+}
+
+func (m *protobuf) packedUints(field int, vs []uint64) {
+	packed := &protobuf{}
+	for _, v := range vs {
+		packed.varint(v)
+	}
+	m.bytes(field, packed.buf)
+}
+
+func (m *protobuf) packedInts(field int, vs []int64) {
+	packed := &protobuf{}
+	for _, v := range vs {
+		packed.varint(uint64(v))
+	}
+	m.bytes(field, packed.buf)
+}
@@ -0,0 +1,158 @@
+package profile
+
+import (
+	"bytes"
+	"compress/gzip"
+	"github.com/stretchr/testify/assert"
+	"io"
+	"monkey_interpreter/evaluator"
+	"monkey_interpreter/object"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// profileFiles profiles main.mk of files with a clock advancing by a millisecond on every reading
+func profileFiles(t *testing.T, files map[string]string) *Profile {
+	dir := t.TempDir()
+	for name, src := range files {
+		assert.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(src), 0644))
+	}
+	ctx := evaluator.NewContext(strings.NewReader(""), io.Discard, io.Discard)
+	p := Start(ctx)
+	now := p.start
+	p.now = func() time.Time {
+		now = now.Add(time.Millisecond)
+		return now
+	}
+	res := ctx.EvalFile(filepath.Join(dir, "main.mk"), object.NewEnvironment())
+	assert.NotEqual(t, object.ErrorObj, res.Type(), res.Inspect())
+	return p.Stop()
+}
+
+func TestProfile(t *testing.T) {
+	prof := profileFiles(t, map[string]string{
+		"main.mk": "let f = fn(x) {\n  len(x)\n};\nf(\"ab\");\n",
+	})
+
+	var b bytes.Buffer
+	assert.NoError(t, prof.WriteReport(&b, 0))
+	assert.Equal(t, `Duration: 29.00ms, attributed 27.00ms, 4 allocations
+
+      flat   flat%    sum%        cum    cum%   allocs  function
+   14.00ms  51.85%  51.85%    27.00ms 100.00%        3  main.mk
+   12.00ms  44.44%  96.30%    13.00ms  48.15%        1  f main.mk:1
+    1.00ms   3.70% 100.00%     1.00ms   3.70%        0  len
+
+      flat   flat%    sum%   allocs  line
+   11.00ms  40.74%  40.74%        1  main.mk:2
+    9.00ms  33.33%  74.07%        2  main.mk:4
+    7.00ms  25.93% 100.00%        1  main.mk:1
+`, b.String())
+
+	b.Reset()
+	assert.NoError(t, prof.WriteReport(&b, 1))
+	assert.Equal(t, 7, strings.Count(b.String(), "\n"))
+}
+
+func TestProfileStacks(t *testing.T) {
+	prof := profileFiles(t, map[string]string{
+		"lib.mk": "let double = fn(x) { x * 2 };\n",
+		"main.mk": `import "./lib.mk" as lib;
+let count = fn(n) { if (n > 0) { count(n - 1) } else { 0 } };
+count(2);
+map([1], fn(x) { lib.double(x) });
+`,
+	})
+
+	stacks := make(map[string]bool)
+	for _, sample := range prof.Samples {
+		parts := make([]string, 0, len(sample.Stack))
+		for _, loc := range sample.Stack {
+			parts = append(parts, loc.String())
+		}
+		stacks[strings.Join(parts, " < ")] = true
+	}
+	assert.Contains(t, stacks, "lib.mk lib.mk:1 < main.mk main.mk:1")
+	assert.Contains(t, stacks, "count main.mk:2 < count main.mk:2 < count main.mk:2 < main.mk main.mk:3")
+	assert.Contains(t, stacks, "double lib.mk:1 < fn@4 main.mk:4 < map < main.mk main.mk:4")
+
+	// Recursive calls count once in the cumulative time
+	total, _ := prof.Total()
+	for _, e := range prof.functions() {
+		assert.LessOrEqual(t, int64(e.cum), int64(total), e.name)
+	}
+}
+
+func TestWritePprof(t *testing.T) {
+	prof := profileFiles(t, map[string]string{
+		"main.mk": "let f = fn(x) {\n  len(x)\n};\nf(\"ab\");\n",
+	})
+	var b bytes.Buffer
+	assert.NoError(t, prof.WritePprof(&b))
+
+	gz, err := gzip.NewReader(&b)
+	assert.NoError(t, err)
+	data, err := io.ReadAll(gz)
+	assert.NoError(t, err)
+	fields := decodeFields(t, data)
+
+	var stringTable []string
+	for _, s := range fields[profileStringTable] {
+		stringTable = append(stringTable, string(s.([]byte)))
+	}
+	assert.Equal(t, "", stringTable[0])
+	for _, s := range []string{"time", "nanoseconds", "alloc_objects", "count", "f", "len", "main.mk"} {
+		assert.Contains(t, stringTable, s)
+	}
+	assert.Equal(t, len(prof.Samples), len(fields[profileSample]))
+	assert.Equal(t, 2, len(fields[profileSampleType]))
+	assert.Equal(t, 3, len(fields[profileFunction]))
+	assert.Equal(t, []interface{}{uint64(prof.Duration)}, fields[profileDurationNanos])
+
+	sample := decodeFields(t, fields[profileSample][0].([]byte))
+	assert.Equal(t, 1, len(sample[sampleValue]))
+}
+
+// decodeFields decodes a protocol buffer message into the varints and bytes of its fields
+func decodeFields(t *testing.T, data []byte) map[int][]interface{} {
+	fields := make(map[int][]interface{})
+	varint := func() uint64 {
+		var v uint64
+		for shift := 0; ; shift += 7 {
+			b := data[0]
+			data = data[1:]
+			v |= uint64(b&0x7f) << shift
+			if b < 0x80 {
+				return v
+			}
+		}
+	}
+	for len(data) > 0 {
+		key := varint()
+		switch key & 7 {
+		case wireVarint:
+			fields[int(key>>3)] = append(fields[int(key>>3)], varint())
+		case wireBytes:
+			n := varint()
+			fields[int(key>>3)] = append(fields[int(key>>3)], data[:n])
+			data = data[n:]
+		default:
+			t.Fatalf("unexpected wire type %d", key&7)
+		}
+	}
+	return fields
+}
+
+func TestIsNew(t *testing.T) {
+	elem := &object.Integer{Value: 1}
+	arr := &object.Array{Elements: []object.Object{elem}}
+
+	assert.True(t, isNew(&object.Array{}, []object.Object{arr}))
+	assert.True(t, isNew(elem, []object.Object{arr}))
+	assert.False(t, isNew(arr, []object.Object{arr}))
+	assert.False(t, isNew(evaluator.NULL, []object.Object{arr}))
+	assert.False(t, isNew(evaluator.TRUE, nil))
+}
@@ -0,0 +1,280 @@
+// Package profile attributes the time and allocations of a Monkey program to the Monkey functions
+// and source lines it spends them in. The Profiler is an evaluator Tracer timing every node, so it
+// is exact but slows the program down. The resulting Profile is written as a text report or as a
+// pprof profile whose frames are Monkey functions.
+package profile
+
+import (
+	"fmt"
+	"monkey_interpreter/ast"
+	"monkey_interpreter/evaluator"
+	"monkey_interpreter/object"
+	"path/filepath"
+	"time"
+)
+
+// Function is a Monkey function, the top level code of a file or a builtin
+type Function struct {
+	Name string // the let the function is bound to, the base name of a file or the builtin name
+	File string // absolute path, empty for builtins and code outside of files
+	Line int    // where the function starts, zero for files and builtins
+}
+
+// Location is a line of a function
+type Location struct {
+	Function *Function
+	Line     int // zero for builtins
+}
+
+// Sample is the time and allocations spent at the innermost location of a stack
+type Sample struct {
+	Stack  []Location // innermost first
+	Time   time.Duration
+	Allocs int64 // values and environments created
+}
+
+type Profile struct {
+	Duration time.Duration
+	Samples  []*Sample
+}
+
+// node is a location in the tree of the stacks seen, holding what was spent while it was the
+// innermost one
+type node struct {
+	location Location
+	parent   *node
+	children map[Location]*node
+	time     time.Duration
+	allocs   int64
+}
+
+func (n *node) child(location Location) *node {
+	if c, ok := n.children[location]; ok {
+		return c
+	}
+	if n.children == nil {
+		n.children = make(map[Location]*node)
+	}
+	c := &node{location: location, parent: n}
+	n.children[location] = c
+	return c
+}
+
+// frame is a function being evaluated
+type frame struct {
+	function *Function
+	caller   *node           // where the function was called from, the parent of its locations
+	current  *node           // the location being evaluated
+	args     []object.Object // of builtins
+}
+
+// Profiler records the stacks of an evaluation
+type Profiler struct {
+	ctx       *evaluator.Context
+	now       func() time.Time
+	start     time.Time
+	last      time.Time
+	root      node
+	stack     []*frame
+	functions map[*ast.BlockStatement]*Function // by body
+	files     map[string]*Function              // top level code by path
+	builtins  map[string]*Function
+}
+
+// Start profiles the evaluations of ctx until Stop is called
+func Start(ctx *evaluator.Context) *Profiler {
+	p := &Profiler{
+		ctx:       ctx,
+		now:       time.Now,
+		functions: make(map[*ast.BlockStatement]*Function),
+		files:     make(map[string]*Function),
+		builtins:  make(map[string]*Function),
+	}
+	p.start = p.now()
+	p.last = p.start
+	ctx.Tracer = p
+	return p
+}
+
+// Stop ends the profiling and returns what was recorded
+func (p *Profiler) Stop() *Profile {
+	p.tick()
+	p.ctx.Tracer = nil
+
+	profile := &Profile{Duration: p.last.Sub(p.start)}
+	var walk func(n *node)
+	walk = func(n *node) {
+		if n != &p.root && (n.time > 0 || n.allocs > 0) {
+			sample := &Sample{Time: n.time, Allocs: n.allocs}
+			for s := n; s != &p.root; s = s.parent {
+				sample.Stack = append(sample.Stack, s.location)
+			}
+			profile.Samples = append(profile.Samples, sample)
+		}
+		for _, c := range n.children {
+			walk(c)
+		}
+	}
+	walk(&p.root)
+	sortSamples(profile.Samples)
+	return profile
+}
+
+// tick charges the time since the last event to the current location
+func (p *Profiler) tick() {
+	now := p.now()
+	p.current().time += now.Sub(p.last)
+	p.last = now
+}
+
+func (p *Profiler) current() *node {
+	if len(p.stack) == 0 {
+		return &p.root
+	}
+	return p.stack[len(p.stack)-1].current
+}
+
+func (p *Profiler) push(function *Function, line int) {
+	caller := p.current()
+	p.stack = append(p.stack, &frame{
+		function: function,
+		caller:   caller,
+		current:  caller.child(Location{Function: function, Line: line}),
+	})
+}
+
+func (p *Profiler) pop() {
+	if len(p.stack) > 0 {
+		p.stack = p.stack[:len(p.stack)-1]
+	}
+}
+
+func (p *Profiler) Enter(n ast.Node) {
+	p.tick()
+	switch n := n.(type) {
+	case *ast.Program:
+		p.push(p.file(p.ctx.File()), 1)
+	case *ast.LetStatement:
+		p.moveTo(n.Token.Line)
+		if fn, ok := n.Value.(*ast.FunctionLiteral); ok {
+			p.function(fn).Name = n.Name.Value
+		}
+	case *ast.ReturnStatement:
+		p.moveTo(n.Token.Line)
+	case *ast.ImportStatement:
+		p.moveTo(n.Token.Line)
+	case *ast.ExpressionStatement:
+		p.moveTo(n.Token.Line)
+	case *ast.FunctionLiteral:
+		p.function(n)
+	}
+}
+
+// moveTo makes a line of the current function the current location
+func (p *Profiler) moveTo(line int) {
+	if len(p.stack) == 0 || line == 0 {
+		return
+	}
+	f := p.stack[len(p.stack)-1]
+	if f.current.location.Line != line {
+		f.current = f.caller.child(Location{Function: f.function, Line: line})
+	}
+}
+
+func (p *Profiler) Exit(n ast.Node, result object.Object) {
+	p.tick()
+	switch n.(type) {
+	case *ast.Program:
+		p.pop()
+	case *ast.IntegerLiteral, *ast.StringLiteral, *ast.ArrayLiteral, *ast.HashLiteral, *ast.FunctionLiteral, *ast.SliceExpression:
+		if _, ok := result.(*object.Error); !ok {
+			p.current().allocs++
+		}
+	case *ast.InfixExpression, *ast.PrefixExpression:
+		switch result.(type) {
+		case *object.Integer, *object.String:
+			p.current().allocs++
+		}
+	}
+}
+
+func (p *Profiler) Call(_ string, fn *object.Function, _ []object.Object) {
+	p.tick()
+	// The environment of the call
+	p.current().allocs++
+	function, ok := p.functions[fn.Body]
+	if !ok {
+		// Functions defined before profiling started
+		function = &Function{Name: "fn", File: fn.File, Line: fn.Body.Token.Line}
+		p.functions[fn.Body] = function
+	}
+	p.push(function, function.Line)
+}
+
+func (p *Profiler) Return(string, *object.Function, object.Object) {
+	p.tick()
+	p.pop()
+}
+
+func (p *Profiler) BuiltinCall(name string, args []object.Object) {
+	p.tick()
+	function, ok := p.builtins[name]
+	if !ok {
+		function = &Function{Name: name}
+		p.builtins[name] = function
+	}
+	p.push(function, 0)
+	p.stack[len(p.stack)-1].args = args
+}
+
+func (p *Profiler) BuiltinReturn(_ string, result object.Object) {
+	p.tick()
+	args := p.stack[len(p.stack)-1].args
+	p.pop()
+	if isNew(result, args) {
+		p.current().allocs++
+	}
+}
+
+// isNew reports whether the result of a builtin is a value it created. Errors, null, booleans and
+// the arguments are not. An element of an argument, like the result of first, is counted as new:
+// looking for it would walk the arguments on every call
+func isNew(result object.Object, args []object.Object) bool {
+	switch result.(type) {
+	case *object.Error, *object.Null, *object.Boolean:
+		return false
+	}
+	for _, arg := range args {
+		if arg == result {
+			return false
+		}
+	}
+	return true
+}
+
+// function returns the function of a literal, named after its location until a let binds it
+func (p *Profiler) function(fn *ast.FunctionLiteral) *Function {
+	if function, ok := p.functions[fn.Body]; ok {
+		return function
+	}
+	file := ""
+	if len(p.stack) > 0 {
+		file = p.stack[len(p.stack)-1].function.File
+	}
+	function := &Function{Name: fmt.Sprintf("fn@%d", fn.Token.Line), File: file, Line: fn.Token.Line}
+	p.functions[fn.Body] = function
+	return function
+}
+
+func (p *Profiler) file(path string) *Function {
+	if function, ok := p.files[path]; ok {
+		return function
+	}
+	name := "<input>"
+	if path != "" {
+		name = filepath.Base(path)
+	}
+	function := &Function{Name: name, File: path}
+	p.files[path] = function
+	return function
+}
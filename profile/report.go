@@ -0,0 +1,191 @@
+package profile
+
+import (
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sortSamples orders samples by decreasing time, and then by stack so the order is stable
+func sortSamples(samples []*Sample) {
+	keys := make(map[*Sample]string, len(samples))
+	for _, s := range samples {
+		parts := make([]string, 0, len(s.Stack))
+		for _, loc := range s.Stack {
+			parts = append(parts, loc.String())
+		}
+		keys[s] = strings.Join(parts, ";")
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		if samples[i].Time != samples[j].Time {
+			return samples[i].Time > samples[j].Time
+		}
+		return keys[samples[i]] < keys[samples[j]]
+	})
+}
+
+// String returns the name of a function followed by where it starts
+func (f *Function) String() string {
+	if f.Line == 0 {
+		return f.Name
+	}
+	return f.Name + " " + displayName(f.File) + ":" + strconv.Itoa(f.Line)
+}
+
+func (l Location) String() string {
+	if l.Line == 0 {
+		return l.Function.Name
+	}
+	return l.Function.Name + " " + displayName(l.Function.File) + ":" + strconv.Itoa(l.Line)
+}
+
+func displayName(file string) string {
+	if file == "" {
+		return "<input>"
+	}
+	return filepath.Base(file)
+}
+
+// entry is what was spent in a function or at a line
+type entry struct {
+	name       string
+	flat, cum  time.Duration
+	flatAllocs int64
+}
+
+// Total returns the time and allocations of all the samples
+func (p *Profile) Total() (time.Duration, int64) {
+	var total time.Duration
+	var allocs int64
+	for _, s := range p.Samples {
+		total += s.Time
+		allocs += s.Allocs
+	}
+	return total, allocs
+}
+
+// functions returns the time spent in each function, flat when it is the innermost one and
+// cumulative when it is anywhere on the stack
+func (p *Profile) functions() []*entry {
+	byFunction := make(map[*Function]*entry)
+	get := func(f *Function) *entry {
+		e, ok := byFunction[f]
+		if !ok {
+			e = &entry{name: f.String()}
+			byFunction[f] = e
+		}
+		return e
+	}
+	for _, s := range p.Samples {
+		leaf := get(s.Stack[0].Function)
+		leaf.flat += s.Time
+		leaf.flatAllocs += s.Allocs
+		// Recursive functions are counted once per stack
+		seen := make(map[*Function]bool)
+		for _, loc := range s.Stack {
+			if !seen[loc.Function] {
+				seen[loc.Function] = true
+				get(loc.Function).cum += s.Time
+			}
+		}
+	}
+	entries := make([]*entry, 0, len(byFunction))
+	for _, e := range byFunction {
+		entries = append(entries, e)
+	}
+	sortEntries(entries)
+	return entries
+}
+
+// lines returns the time spent at each source line. The time of builtins is spent at the line
+// calling them
+func (p *Profile) lines() []*entry {
+	type line struct {
+		file string
+		line int
+	}
+	byLine := make(map[line]*entry)
+	for _, s := range p.Samples {
+		for _, loc := range s.Stack {
+			if loc.Line == 0 {
+				continue
+			}
+			key := line{loc.Function.File, loc.Line}
+			e, ok := byLine[key]
+			if !ok {
+				e = &entry{name: displayName(key.file) + ":" + strconv.Itoa(key.line)}
+				byLine[key] = e
+			}
+			e.flat += s.Time
+			e.flatAllocs += s.Allocs
+			break
+		}
+	}
+	entries := make([]*entry, 0, len(byLine))
+	for _, e := range byLine {
+		entries = append(entries, e)
+	}
+	sortEntries(entries)
+	return entries
+}
+
+// sortEntries orders entries by decreasing flat and then cumulative time
+func sortEntries(entries []*entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].flat != entries[j].flat {
+			return entries[i].flat > entries[j].flat
+		}
+		if entries[i].cum != entries[j].cum {
+			return entries[i].cum > entries[j].cum
+		}
+		return entries[i].name < entries[j].name
+	})
+}
+
+// WriteReport writes the functions and the lines the most time was spent in, at most n of each
+// or all of them if n is not positive
+func (p *Profile) WriteReport(w io.Writer, n int) error {
+	total, allocs := p.Total()
+	var b strings.Builder
+	fmt.Fprintf(&b, "Duration: %s, attributed %s, %d allocations\n", milliseconds(p.Duration), milliseconds(total), allocs)
+
+	fmt.Fprintf(&b, "\n%10s %7s %7s %10s %7s %8s  %s\n", "flat", "flat%", "sum%", "cum", "cum%", "allocs", "function")
+	var sum time.Duration
+	for _, e := range limit(p.functions(), n) {
+		sum += e.flat
+		fmt.Fprintf(&b, "%10s %7s %7s %10s %7s %8d  %s\n",
+			milliseconds(e.flat), percent(e.flat, total), percent(sum, total), milliseconds(e.cum), percent(e.cum, total), e.flatAllocs, e.name)
+	}
+
+	fmt.Fprintf(&b, "\n%10s %7s %7s %8s  %s\n", "flat", "flat%", "sum%", "allocs", "line")
+	sum = 0
+	for _, e := range limit(p.lines(), n) {
+		sum += e.flat
+		fmt.Fprintf(&b, "%10s %7s %7s %8d  %s\n", milliseconds(e.flat), percent(e.flat, total), percent(sum, total), e.flatAllocs, e.name)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func limit(entries []*entry, n int) []*entry {
+	if n > 0 && len(entries) > n {
+		return entries[:n]
+	}
+	return entries
+}
+
+func milliseconds(d time.Duration) string {
+	return fmt.Sprintf("%.2fms", float64(d)/float64(time.Millisecond))
+}
+
+func percent(d, total time.Duration) string {
+	if total == 0 {
+		return "0.00%"
+	}
+	return fmt.Sprintf("%.2f%%", float64(d)*100/float64(total))
+}
@@ -4,33 +4,50 @@ import (
 	"bufio"
 	"fmt"
 	"io"
+	"monkey_interpreter/ast"
 	"monkey_interpreter/evaluator"
 	"monkey_interpreter/lexer"
 	"monkey_interpreter/object"
 	"monkey_interpreter/parser"
+	"strings"
 )
 
 const Prompt = ">> "
 
 func Start(in io.Reader, out io.Writer) {
-	scanner := bufio.NewScanner(in)
+	// The reader is shared with the evaluation context so readline and input see the same stream
+	reader := bufio.NewReader(in)
+	ctx := evaluator.NewContext(reader, out, out)
+	env := object.NewEnvironment()
+	macroEnv := object.NewEnvironment()
 	for {
-		fmt.Print(Prompt)
-		scanned := scanner.Scan()
-		if !scanned {
+		_, _ = io.WriteString(out, Prompt)
+		code, err := reader.ReadString('\n')
+		if err != nil && code == "" {
 			return
 		}
-		code := scanner.Text()
+		code = strings.TrimRight(code, "\r\n")
 		l := lexer.New(code)
 		p := parser.New(l)
 		program := p.ParseProgram()
-		env := object.NewEnvironment()
-
 		if len(p.Error()) > 0 {
 			printParseError(out, p.Error())
+			continue
 		}
 
-		evaluated := evaluator.Eval(program, env)
+		evaluator.DefineMacros(program, macroEnv)
+		expanded, err := ctx.ExpandMacros(program, macroEnv)
+		if err != nil {
+			_, _ = io.WriteString(out, fmt.Sprintf("Error: %s\n", err))
+			continue
+		}
+		if errs := ctx.Resolve(expanded.(*ast.Program), env); len(errs) > 0 {
+			for _, err := range errs {
+				_, _ = io.WriteString(out, fmt.Sprintf("Error: %s\n", err))
+			}
+			continue
+		}
+		evaluated := ctx.Eval(expanded, env)
 		if evaluated != nil {
 			_, _ = io.WriteString(out, evaluated.Inspect())
 			_, _ = io.WriteString(out, "\n")
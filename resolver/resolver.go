@@ -0,0 +1,204 @@
+// Package resolver binds the variable references of a program to their declarations before it
+// runs, so the evaluator can address local variables by frame depth and slot instead of looking
+// names up in a chain of maps, and undefined variables are reported without executing anything.
+//
+// Scopes are the top level of a file and function bodies - if blocks share the scope of the code
+// around them, just like at runtime. A let declares its slot where it is, so the code of a body
+// before it refers to the variables of the outer scopes. Nested function bodies run later and are
+// resolved once the whole body around them is, so they can refer to any of its locals. A local
+// whose let has not run yet is looked up further out at runtime, as it was by name.
+package resolver
+
+import (
+	"fmt"
+	"monkey_interpreter/ast"
+)
+
+// Error is a reference to an undefined variable
+type Error struct {
+	Line    int
+	Column  int
+	Message string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Message)
+}
+
+// scope is the frame of a function body
+type scope struct {
+	slots map[string]int
+	names []string
+	outer *scope
+}
+
+func (s *scope) declare(name string) int {
+	if slot, ok := s.slots[name]; ok {
+		return slot
+	}
+	s.slots[name] = len(s.names)
+	s.names = append(s.names, name)
+	return s.slots[name]
+}
+
+type resolver struct {
+	globals map[string]bool
+	scope   *scope                 // innermost function scope, nil at the top level
+	nested  []*ast.FunctionLiteral // functions of the current scope left to resolve
+	errors  []*Error
+}
+
+// Resolve fills in the Resolution of the identifiers and the Locals of the function literals of
+// program. globals are the names defined before the program runs, such as builtins or the
+// variables of a REPL session
+func Resolve(program *ast.Program, globals []string) []*Error {
+	r := &resolver{globals: make(map[string]bool)}
+	for _, name := range globals {
+		r.globals[name] = true
+	}
+	for _, stmt := range program.Statements {
+		for _, decl := range Declarations(stmt) {
+			r.globals[decl.Value] = true
+		}
+	}
+	for _, stmt := range program.Statements {
+		r.resolve(stmt)
+	}
+	return r.errors
+}
+
+// Declarations returns the names a node binds in the scope it is evaluated in, looking into if
+// blocks but not into function bodies
+func Declarations(node ast.Node) []*ast.Identifier {
+	var decls []*ast.Identifier
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.LetStatement:
+			decls = append(decls, node.Name)
+		case *ast.ImportStatement:
+			decls = append(decls, node.Name)
+		case *ast.FunctionLiteral, *ast.MacroLiteral:
+			return false
+		case *ast.CallExpression:
+			if isCallTo(node, "quote") {
+				for _, arg := range UnquotedArguments(node) {
+					decls = append(decls, Declarations(arg)...)
+				}
+				return false
+			}
+		}
+		return true
+	})
+	return decls
+}
+
+func (r *resolver) resolve(node ast.Node) {
+	ast.Inspect(node, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Identifier:
+			r.reference(node)
+		case *ast.LetStatement:
+			r.resolve(node.Value)
+			r.bind(node.Name)
+			return false
+		case *ast.ImportStatement:
+			r.bind(node.Name)
+			return false
+		case *ast.MemberExpression:
+			// The member is looked up in the module, not in scope
+			r.resolve(node.Object)
+			return false
+		case *ast.FunctionLiteral:
+			// Top level variables are looked up by name, the body can be resolved right away
+			if r.scope == nil {
+				r.function(node)
+			} else {
+				r.nested = append(r.nested, node)
+			}
+			return false
+		case *ast.MacroLiteral:
+			// Macros left in the program are never evaluated
+			return false
+		case *ast.CallExpression:
+			if isCallTo(node, "quote") {
+				for _, arg := range UnquotedArguments(node) {
+					r.resolve(arg)
+				}
+				return false
+			}
+		}
+		return true
+	})
+}
+
+func (r *resolver) function(fn *ast.FunctionLiteral) {
+	s := &scope{slots: make(map[string]int), outer: r.scope}
+	for _, param := range fn.Parameters {
+		param.Resolution = ast.Resolution{Kind: ast.Local, Slot: s.declare(param.Value)}
+	}
+
+	r.scope = s
+	outerNested := r.nested
+	r.nested = nil
+	r.resolve(fn.Body)
+	// All the locals of the body are declared now
+	for len(r.nested) > 0 {
+		nested := r.nested[0]
+		r.nested = r.nested[1:]
+		r.function(nested)
+	}
+	r.nested = outerNested
+	r.scope = s.outer
+	fn.Locals = s.names
+}
+
+// bind resolves the name a let or import assigns to, which is always in the current scope
+func (r *resolver) bind(ident *ast.Identifier) {
+	if r.scope == nil {
+		ident.Resolution = ast.Resolution{Kind: ast.Global}
+		return
+	}
+	ident.Resolution = ast.Resolution{Kind: ast.Local, Slot: r.scope.declare(ident.Value)}
+}
+
+func (r *resolver) reference(ident *ast.Identifier) {
+	depth := 0
+	for s := r.scope; s != nil; s = s.outer {
+		if slot, ok := s.slots[ident.Value]; ok {
+			ident.Resolution = ast.Resolution{Kind: ast.Local, Depth: depth, Slot: slot}
+			return
+		}
+		depth++
+	}
+	if !r.globals[ident.Value] {
+		r.errors = append(r.errors, &Error{
+			Line:    ident.Token.Line,
+			Column:  ident.Token.Column,
+			Message: "identifier not found: " + ident.Value,
+		})
+		return
+	}
+	ident.Resolution = ast.Resolution{Kind: ast.Global, Depth: depth}
+}
+
+// UnquotedArguments returns the arguments of the unquote calls inside a quote call, the only
+// parts of the quoted code that are evaluated
+func UnquotedArguments(quote *ast.CallExpression) []ast.Expression {
+	var args []ast.Expression
+	for _, arg := range quote.Arguments {
+		ast.Inspect(arg, func(node ast.Node) bool {
+			call, ok := node.(*ast.CallExpression)
+			if ok && isCallTo(call, "unquote") {
+				args = append(args, call.Arguments...)
+				return false
+			}
+			return true
+		})
+	}
+	return args
+}
+
+func isCallTo(call *ast.CallExpression, name string) bool {
+	ident, ok := call.Function.(*ast.Identifier)
+	return ok && ident.Value == name
+}
@@ -0,0 +1,109 @@
+package resolver
+
+import (
+	"github.com/stretchr/testify/assert"
+	"monkey_interpreter/ast"
+	"monkey_interpreter/lexer"
+	"monkey_interpreter/parser"
+	"testing"
+)
+
+func parse(t *testing.T, input string) *ast.Program {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	assert.Empty(t, p.Error())
+	return program
+}
+
+// resolutions collects the resolution of every identifier named name, in source order
+func resolutions(program *ast.Program, name string) []ast.Resolution {
+	var res []ast.Resolution
+	ast.Inspect(program, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok && ident.Value == name {
+			res = append(res, ident.Resolution)
+		}
+		return true
+	})
+	return res
+}
+
+func local(depth, slot int) ast.Resolution {
+	return ast.Resolution{Kind: ast.Local, Depth: depth, Slot: slot}
+}
+
+func global(depth int) ast.Resolution {
+	return ast.Resolution{Kind: ast.Global, Depth: depth}
+}
+
+func TestResolve(t *testing.T) {
+	tests := []struct {
+		input string
+		name  string
+		exp   []ast.Resolution
+	}{
+		{`let x = 1; x`, "x", []ast.Resolution{global(0), global(0)}},
+		{`let f = fn(a, b) { b }`, "b", []ast.Resolution{local(0, 1), local(0, 1)}},
+		{`let f = fn(a) { let x = a; x }`, "x", []ast.Resolution{local(0, 1), local(0, 1)}},
+		{`let f = fn(a) { fn(b) { fn() { a } } }`, "a", []ast.Resolution{local(0, 0), local(2, 0)}},
+		{`let f = fn() { if (true) { let y = 1 } y }`, "y", []ast.Resolution{local(0, 0), local(0, 0)}},
+		{`let f = fn() { f() }`, "f", []ast.Resolution{global(0), global(1)}},
+		{`let f = fn() { g() }; let g = fn() { 1 }`, "g", []ast.Resolution{global(1), global(0)}},
+		{`let x = 1; let f = fn(x) { x }`, "x", []ast.Resolution{global(0), local(0, 0), local(0, 0)}},
+		{`let f = fn() { let x = 1; let x = 2; x }`, "x", []ast.Resolution{local(0, 0), local(0, 0), local(0, 0)}},
+		{`let x = 1; let f = fn() { let y = x; let x = 2; x }`, "x", []ast.Resolution{global(0), global(1), local(0, 1), local(0, 1)}},
+		{`let f = fn(a) { let y = a; let a = 2 }`, "a", []ast.Resolution{local(0, 0), local(0, 0), local(0, 0)}},
+		{`let f = fn() { let g = fn() { h }; let h = 1 }`, "h", []ast.Resolution{local(1, 1), local(0, 1)}},
+		{`let f = fn(x) { quote(x + unquote(x)) }`, "x", []ast.Resolution{local(0, 0), {}, local(0, 0)}},
+		{`fn() { len }`, "len", []ast.Resolution{global(1)}},
+		{`import "lib.mk" as lib; lib.lib`, "lib", []ast.Resolution{global(0), global(0), {}}},
+	}
+
+	for _, test := range tests {
+		program := parse(t, test.input)
+		errs := Resolve(program, []string{"len"})
+		assert.Empty(t, errs, test.input)
+		assert.Equal(t, test.exp, resolutions(program, test.name), test.input)
+	}
+}
+
+func TestResolveLocals(t *testing.T) {
+	program := parse(t, `fn(a, b) { let c = 1; if (a) { let d = 2; fn(e) { let f = 3 } }; let c = 4 }`)
+	assert.Empty(t, Resolve(program, nil))
+
+	var locals [][]string
+	ast.Inspect(program, func(node ast.Node) bool {
+		if fn, ok := node.(*ast.FunctionLiteral); ok {
+			locals = append(locals, fn.Locals)
+		}
+		return true
+	})
+	assert.Equal(t, [][]string{{"a", "b", "c", "d"}, {"e", "f"}}, locals)
+}
+
+func TestResolveErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   []string
+	}{
+		{`x`, []string{"1:1: identifier not found: x"}},
+		{"let a = 1;\nlet f = fn(b) {\n  a + b + c + d\n}; d", []string{
+			"3:11: identifier not found: c",
+			"3:15: identifier not found: d",
+			"4:4: identifier not found: d",
+		}},
+		{`let f = fn() { let x = 1 }; x`, []string{"1:29: identifier not found: x"}},
+		{`let f = fn() { let y = z; let z = 1 }`, []string{"1:24: identifier not found: z"}},
+		{`unquote(x)`, []string{"1:1: identifier not found: unquote", "1:9: identifier not found: x"}},
+		{`quote(x + unquote(y))`, []string{"1:19: identifier not found: y"}},
+		{`undefined.member`, []string{"1:1: identifier not found: undefined"}},
+	}
+
+	for _, test := range tests {
+		errs := Resolve(parse(t, test.input), nil)
+		msgs := make([]string, 0, len(errs))
+		for _, err := range errs {
+			msgs = append(msgs, err.Error())
+		}
+		assert.Equal(t, test.exp, msgs, test.input)
+	}
+}
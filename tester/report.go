@@ -0,0 +1,231 @@
+package tester
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Summary counts the outcomes of the tests of files
+type Summary struct {
+	Files, Tests, Passed, Failed, Errors int
+}
+
+// Summarize counts the tests of files. Files that could not be evaluated count as one erroring test
+func Summarize(files []*File) Summary {
+	s := Summary{Files: len(files)}
+	for _, f := range files {
+		if f.Err != "" {
+			s.Tests++
+			s.Errors++
+			continue
+		}
+		for _, t := range f.Tests {
+			s.Tests++
+			switch t.Status {
+			case Pass:
+				s.Passed++
+			case Fail:
+				s.Failed++
+			default:
+				s.Errors++
+			}
+		}
+	}
+	return s
+}
+
+// WriteText writes the failed tests with their output and a line per file. Verbose writes the
+// tests that passed too
+func WriteText(w io.Writer, files []*File, verbose bool) error {
+	var b strings.Builder
+	for _, f := range files {
+		if f.Err != "" {
+			fmt.Fprintf(&b, "--- FAIL: %s\n", f.Path)
+			writeIndented(&b, f.Err)
+			writeIndented(&b, f.Output)
+		}
+		for _, t := range f.Tests {
+			if t.Status == Pass && !verbose {
+				continue
+			}
+			fmt.Fprintf(&b, "--- %s: %s (%s:%d, %s)\n", t.Status, t.Name, f.Path, t.Line, seconds(t.Duration))
+			writeIndented(&b, t.Message)
+			writeIndented(&b, t.Output)
+		}
+
+		s := Summarize([]*File{f})
+		switch {
+		case f.Err != "":
+			fmt.Fprintf(&b, "FAIL\t%s\t%s\n", f.Path, seconds(f.Duration))
+		case s.Failed+s.Errors > 0:
+			fmt.Fprintf(&b, "FAIL\t%s\t%d passed, %d failed\t%s\n", f.Path, s.Passed, s.Failed+s.Errors, seconds(f.Duration))
+		default:
+			fmt.Fprintf(&b, "ok\t%s\t%d passed\t%s\n", f.Path, s.Passed, seconds(f.Duration))
+		}
+	}
+
+	s := Summarize(files)
+	switch {
+	case s.Files == 0:
+		b.WriteString("no test files\n")
+	case s.Failed+s.Errors > 0:
+		fmt.Fprintf(&b, "FAIL: %d of %d tests failed in %d files\n", s.Failed+s.Errors, s.Tests, s.Files)
+	default:
+		fmt.Fprintf(&b, "PASS: %d tests in %d files\n", s.Tests, s.Files)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeIndented(b *strings.Builder, text string) {
+	text = strings.TrimRight(text, "\n")
+	if text == "" {
+		return
+	}
+	for _, line := range strings.Split(text, "\n") {
+		fmt.Fprintf(b, "    %s\n", line)
+	}
+}
+
+func seconds(d time.Duration) string {
+	return fmt.Sprintf("%.3fs", d.Seconds())
+}
+
+// WriteTAP writes the results in the Test Anything Protocol version 13, with the details of the
+// failures as YAML blocks
+func WriteTAP(w io.Writer, files []*File) error {
+	var b strings.Builder
+	s := Summarize(files)
+	fmt.Fprintf(&b, "TAP version 13\n1..%d\n", s.Tests)
+	n := 0
+	for _, f := range files {
+		if f.Err != "" {
+			n++
+			fmt.Fprintf(&b, "not ok %d - %s\n", n, f.Path)
+			writeYAML(&b, f.Err, f.Path, 0, f.Output)
+			continue
+		}
+		for _, t := range f.Tests {
+			n++
+			if t.Status == Pass {
+				fmt.Fprintf(&b, "ok %d - %s: %s\n", n, f.Path, t.Name)
+				continue
+			}
+			fmt.Fprintf(&b, "not ok %d - %s: %s\n", n, f.Path, t.Name)
+			writeYAML(&b, t.Message, f.Path, t.Line, t.Output)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func writeYAML(b *strings.Builder, message, file string, line int, output string) {
+	b.WriteString("  ---\n")
+	fmt.Fprintf(b, "  message: %s\n", strconv.Quote(message))
+	fmt.Fprintf(b, "  file: %s\n", strconv.Quote(file))
+	if line > 0 {
+		fmt.Fprintf(b, "  line: %d\n", line)
+	}
+	if output = strings.TrimRight(output, "\n"); output != "" {
+		b.WriteString("  output: |\n")
+		for _, l := range strings.Split(output, "\n") {
+			fmt.Fprintf(b, "    %s\n", l)
+		}
+	}
+	b.WriteString("  ...\n")
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Time     string      `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	File      string        `xml:"file,attr,omitempty"`
+	Line      int           `xml:"line,attr,omitempty"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitProblem `xml:"failure"`
+	Error     *junitProblem `xml:"error"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitProblem struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// WriteJUnit writes the results as JUnit XML with a test suite per file. A file that could not be
+// evaluated is a suite with a single erroring test case named after it
+func WriteJUnit(w io.Writer, files []*File) error {
+	var total time.Duration
+	s := Summarize(files)
+	suites := junitSuites{Tests: s.Tests, Failures: s.Failed, Errors: s.Errors}
+	for _, f := range files {
+		total += f.Duration
+		fs := Summarize([]*File{f})
+		suite := junitSuite{Name: f.Path, Tests: fs.Tests, Failures: fs.Failed, Errors: fs.Errors, Time: junitTime(f.Duration)}
+		if f.Err != "" {
+			suite.Cases = append(suite.Cases, junitCase{
+				Name:      f.Path,
+				ClassName: f.Path,
+				File:      f.Path,
+				Time:      junitTime(f.Duration),
+				Error:     &junitProblem{Message: f.Err, Text: f.Err},
+				SystemOut: f.Output,
+			})
+		}
+		for _, t := range f.Tests {
+			c := junitCase{
+				Name:      t.Name,
+				ClassName: f.Path,
+				File:      f.Path,
+				Line:      t.Line,
+				Time:      junitTime(t.Duration),
+				SystemOut: t.Output,
+			}
+			switch t.Status {
+			case Fail:
+				c.Failure = &junitProblem{Message: t.Message, Text: t.Message}
+			case Error:
+				c.Error = &junitProblem{Message: t.Message, Text: t.Message}
+			}
+			suite.Cases = append(suite.Cases, c)
+		}
+		suites.Suites = append(suites.Suites, suite)
+	}
+	suites.Time = junitTime(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+func junitTime(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
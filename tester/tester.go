@@ -0,0 +1,262 @@
+// Package tester runs the tests written in Monkey. A test is a function without parameters bound
+// by a top level let whose name starts with test_, in a file whose name ends with _test.mk. Every
+// test runs in a fresh evaluation of its file, so tests cannot see what other tests did, and fails
+// when it returns an error, like the ones of the assert builtins.
+package tester
+
+import (
+	"bytes"
+	"fmt"
+	"monkey_interpreter/ast"
+	"monkey_interpreter/evaluator"
+	"monkey_interpreter/lexer"
+	"monkey_interpreter/object"
+	"monkey_interpreter/parser"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	fileSuffix = "_test.mk"
+	testPrefix = "test_"
+)
+
+type Status int
+
+const (
+	Pass  Status = iota
+	Fail         // an assertion failed
+	Error        // the test failed with any other error
+)
+
+func (s Status) String() string {
+	switch s {
+	case Pass:
+		return "PASS"
+	case Fail:
+		return "FAIL"
+	default:
+		return "ERROR"
+	}
+}
+
+// Result is the outcome of a test
+type Result struct {
+	Name     string
+	Line     int
+	Status   Status
+	Message  string // the error of a failed test
+	Output   string // what the test printed on standard output and error
+	Duration time.Duration
+}
+
+// File is the outcome of the tests of a file
+type File struct {
+	Path     string
+	Err      string // why the file could not be evaluated, its tests did not run then
+	Output   string // what evaluating the file printed when it failed
+	Tests    []*Result
+	Duration time.Duration
+}
+
+// Failed reports whether the file could not be evaluated or one of its tests failed
+func (f *File) Failed() bool {
+	if f.Err != "" {
+		return true
+	}
+	for _, t := range f.Tests {
+		if t.Status != Pass {
+			return true
+		}
+	}
+	return false
+}
+
+// Find returns the test files matched by patterns in sorted order. A pattern is a file, a
+// directory whose test files are used, or a directory followed by /... to include all of the
+// directories below it, skipping the ones starting with a dot or an underscore
+func Find(patterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var files []string
+	add := func(path string) {
+		if !seen[path] {
+			seen[path] = true
+			files = append(files, path)
+		}
+	}
+	for _, pattern := range patterns {
+		recursive := pattern == "..." || strings.HasSuffix(pattern, "/...")
+		root := pattern
+		if recursive {
+			root = filepath.Clean(strings.TrimSuffix(strings.TrimSuffix(pattern, "..."), "/"))
+		}
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			if recursive {
+				return nil, fmt.Errorf("%s is not a directory", root)
+			}
+			add(root)
+			continue
+		}
+
+		err = filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				if path == root {
+					return nil
+				}
+				if !recursive || strings.HasPrefix(info.Name(), ".") || strings.HasPrefix(info.Name(), "_") {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if strings.HasSuffix(info.Name(), fileSuffix) {
+				add(path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Runner runs the tests of files
+type Runner struct {
+	// SearchPath lists the directories imports are looked up in
+	SearchPath []string
+
+	// Run selects the tests to run by name when set
+	Run *regexp.Regexp
+}
+
+// RunFile runs the tests of a file in the order they are defined
+func (r *Runner) RunFile(path string) *File {
+	start := time.Now()
+	file := &File{Path: path}
+	defer func() {
+		file.Duration = time.Since(start)
+	}()
+
+	var output bytes.Buffer
+	if res := r.eval(path, &output).res; isError(res) {
+		file.Err = res.(*object.Error).Message
+		file.Output = output.String()
+		return file
+	}
+	tests, err := testFunctions(path)
+	if err != nil {
+		file.Err = err.Error()
+		return file
+	}
+	for _, test := range tests {
+		if r.Run != nil && !r.Run.MatchString(test.Name.Value) {
+			continue
+		}
+		file.Tests = append(file.Tests, r.runTest(path, test))
+	}
+	return file
+}
+
+type evaluation struct {
+	ctx *evaluator.Context
+	env *object.Environment
+	res object.Object
+}
+
+// eval evaluates the file in a new context writing to output
+func (r *Runner) eval(path string, output *bytes.Buffer) evaluation {
+	ctx := evaluator.NewContext(strings.NewReader(""), output, output)
+	ctx.SearchPath = r.SearchPath
+	env := object.NewEnvironment()
+	res := recovered(func() object.Object {
+		return ctx.EvalFile(path, env)
+	})
+	return evaluation{ctx: ctx, env: env, res: res}
+}
+
+// recovered returns the result of f, or an error if the evaluator panics so a bug in it fails a
+// single test instead of the whole run
+func recovered(f func() object.Object) (res object.Object) {
+	defer func() {
+		if r := recover(); r != nil {
+			res = &object.Error{Message: fmt.Sprintf("panic: %v", r)}
+		}
+	}()
+	return f()
+}
+
+func (r *Runner) runTest(path string, test *ast.LetStatement) *Result {
+	result := &Result{Name: test.Name.Value, Line: test.Token.Line}
+	var output bytes.Buffer
+	defer func() {
+		result.Output = output.String()
+	}()
+
+	e := r.eval(path, &output)
+	if isError(e.res) {
+		result.Status, result.Message = Error, e.res.(*object.Error).Message
+		return result
+	}
+	fn, ok := e.env.Get(test.Name.Value)
+	if !ok {
+		result.Status, result.Message = Error, "test function not found: "+test.Name.Value
+		return result
+	}
+	if fn, ok := fn.(*object.Function); !ok || len(fn.Parameters) > 0 {
+		result.Status, result.Message = Error, "a test must be a function without parameters"
+		return result
+	}
+
+	start := time.Now()
+	res := recovered(func() object.Object {
+		return e.ctx.Call(fn)
+	})
+	result.Duration = time.Since(start)
+	if err, ok := res.(*object.Error); ok {
+		result.Status, result.Message = Error, err.Message
+		if err.Assertion {
+			result.Status = Fail
+		}
+	}
+	return result
+}
+
+// testFunctions returns the top level lets of a file binding a function to a test name
+func testFunctions(path string) ([]*ast.LetStatement, error) {
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	p := parser.New(lexer.New(string(src)))
+	program := p.ParseProgram()
+	if len(p.Error()) > 0 {
+		return nil, fmt.Errorf("%s", strings.Join(p.Error(), "; "))
+	}
+	var tests []*ast.LetStatement
+	for _, stmt := range program.Statements {
+		let, ok := stmt.(*ast.LetStatement)
+		if !ok || !strings.HasPrefix(let.Name.Value, testPrefix) {
+			continue
+		}
+		if _, ok := let.Value.(*ast.FunctionLiteral); ok {
+			tests = append(tests, let)
+		}
+	}
+	return tests, nil
+}
+
+func isError(obj object.Object) bool {
+	return obj != nil && obj.Type() == object.ErrorObj
+}
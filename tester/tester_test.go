@@ -0,0 +1,223 @@
+package tester
+
+import (
+	"bytes"
+	"encoding/xml"
+	"github.com/stretchr/testify/assert"
+	"monkey_interpreter/object"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.NoError(t, os.WriteFile(path, []byte(src), 0644))
+	}
+	return dir
+}
+
+const mathTest = `import "./math.mk" as math;
+let test_add = fn() {
+  assert_eq(math.add(1, 2), 3);
+};
+let test_sub = fn() {
+  puts("subtracting");
+  assert_eq(math.sub(3, 1), 1, "sub");
+};
+let helper = fn() { assert(false) };
+let test_error = fn() {
+  1 + true
+};
+`
+
+// runFiles runs the tests of dir with the durations zeroed
+func runFiles(t *testing.T, r *Runner, dir string) []*File {
+	paths, err := Find([]string{dir + "/..."})
+	assert.NoError(t, err)
+	var files []*File
+	for _, path := range paths {
+		f := r.RunFile(path)
+		f.Duration = 0
+		for _, test := range f.Tests {
+			test.Duration = 0
+		}
+		rel, err := filepath.Rel(dir, f.Path)
+		assert.NoError(t, err)
+		f.Path = rel
+		files = append(files, f)
+	}
+	return files
+}
+
+func TestFind(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"a_test.mk":          "",
+		"a.mk":               "",
+		"sub/b_test.mk":      "",
+		"sub/deep/c_test.mk": "",
+		".hidden/d_test.mk":  "",
+		"_skip/e_test.mk":    "",
+	})
+
+	files, err := Find([]string{dir})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{filepath.Join(dir, "a_test.mk")}, files)
+
+	files, err = Find([]string{dir + "/...", filepath.Join(dir, "sub", "b_test.mk")})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		filepath.Join(dir, "a_test.mk"),
+		filepath.Join(dir, "sub", "b_test.mk"),
+		filepath.Join(dir, "sub", "deep", "c_test.mk"),
+	}, files)
+
+	_, err = Find([]string{filepath.Join(dir, "missing")})
+	assert.Error(t, err)
+}
+
+func TestRunFile(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"math.mk":      "let add = fn(a, b) { a + b };\nlet sub = fn(a, b) { a - b };\n",
+		"math_test.mk": mathTest,
+	})
+	files := runFiles(t, &Runner{}, dir)
+	assert.Len(t, files, 1)
+	assert.Equal(t, "", files[0].Err)
+	assert.Equal(t, []*Result{
+		{Name: "test_add", Line: 2, Status: Pass},
+		{Name: "test_sub", Line: 5, Status: Fail, Message: "assertion failed: sub: expected 1, got 2", Output: "subtracting\n"},
+		{Name: "test_error", Line: 10, Status: Error, Message: "type mismatch: INTEGER + BOOLEAN"},
+	}, files[0].Tests)
+
+	files = runFiles(t, &Runner{Run: regexp.MustCompile("sub|add")}, dir)
+	assert.Len(t, files[0].Tests, 2)
+}
+
+func TestRunFileError(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"parse_test.mk":   "let test_a = fn() { ;\n",
+		"runtime_test.mk": "puts(\"loading\");\nlet x = 1 + true;\nlet test_a = fn() { 1 };\n",
+	})
+	files := runFiles(t, &Runner{}, dir)
+	assert.Len(t, files, 2)
+	assert.Contains(t, files[0].Err, "parse_test.mk: ")
+	assert.Empty(t, files[0].Tests)
+	assert.Equal(t, "type mismatch: INTEGER + BOOLEAN", files[1].Err)
+	assert.Equal(t, "loading\n", files[1].Output)
+	assert.True(t, files[1].Failed())
+}
+
+func TestRunFileStatus(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"status_test.mk": `let test_assert = fn() {
+  assert(false);
+};
+let test_error = fn() {
+  assert_throws(fn() { 1 + true }, "assertion failed")
+};
+let test_let = fn() {
+  let x = 1;
+};
+`,
+	})
+	files := runFiles(t, &Runner{}, dir)
+	assert.Len(t, files, 1)
+	tests := files[0].Tests
+	assert.Len(t, tests, 3)
+	assert.Equal(t, Fail, tests[0].Status)
+	// assert_throws fails, the message it checks for does not matter
+	assert.Equal(t, Fail, tests[1].Status)
+	assert.Equal(t, Pass, tests[2].Status)
+}
+
+func TestRecovered(t *testing.T) {
+	res := recovered(func() object.Object { panic("boom") })
+	assert.Equal(t, "Error: panic: boom", res.Inspect())
+	assert.Equal(t, "1", recovered(func() object.Object { return &object.Integer{Value: 1} }).Inspect())
+}
+
+func testFiles() []*File {
+	return []*File{
+		{Path: "broken_test.mk", Err: "identifier not found: x"},
+		{Path: "math_test.mk", Tests: []*Result{
+			{Name: "test_add", Line: 3, Status: Pass},
+			{Name: "test_sub", Line: 6, Status: Fail, Message: "assertion failed: expected 1, got 2", Output: "subtracting\n"},
+		}},
+		{Path: "ok_test.mk", Tests: []*Result{{Name: "test_ok", Line: 1, Status: Pass}}},
+	}
+}
+
+func TestWriteText(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, WriteText(&b, testFiles(), false))
+	assert.Equal(t, `--- FAIL: broken_test.mk
+    identifier not found: x
+FAIL	broken_test.mk	0.000s
+--- FAIL: test_sub (math_test.mk:6, 0.000s)
+    assertion failed: expected 1, got 2
+    subtracting
+FAIL	math_test.mk	1 passed, 1 failed	0.000s
+ok	ok_test.mk	1 passed	0.000s
+FAIL: 2 of 4 tests failed in 3 files
+`, b.String())
+
+	b.Reset()
+	assert.NoError(t, WriteText(&b, testFiles()[2:], true))
+	assert.Equal(t, `--- PASS: test_ok (ok_test.mk:1, 0.000s)
+ok	ok_test.mk	1 passed	0.000s
+PASS: 1 tests in 1 files
+`, b.String())
+
+	b.Reset()
+	assert.NoError(t, WriteText(&b, nil, false))
+	assert.Equal(t, "no test files\n", b.String())
+}
+
+func TestWriteTAP(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, WriteTAP(&b, testFiles()))
+	assert.Equal(t, `TAP version 13
+1..4
+not ok 1 - broken_test.mk
+  ---
+  message: "identifier not found: x"
+  file: "broken_test.mk"
+  ...
+ok 2 - math_test.mk: test_add
+not ok 3 - math_test.mk: test_sub
+  ---
+  message: "assertion failed: expected 1, got 2"
+  file: "math_test.mk"
+  line: 6
+  output: |
+    subtracting
+  ...
+ok 4 - ok_test.mk: test_ok
+`, b.String())
+}
+
+func TestWriteJUnit(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, WriteJUnit(&b, testFiles()))
+
+	var suites junitSuites
+	assert.NoError(t, xml.Unmarshal(b.Bytes(), &suites))
+	assert.Equal(t, 4, suites.Tests)
+	assert.Equal(t, 1, suites.Failures)
+	assert.Equal(t, 1, suites.Errors)
+	assert.Len(t, suites.Suites, 3)
+	assert.Equal(t, "identifier not found: x", suites.Suites[0].Cases[0].Error.Message)
+	sub := suites.Suites[1].Cases[1]
+	assert.Equal(t, "test_sub", sub.Name)
+	assert.Equal(t, "math_test.mk", sub.ClassName)
+	assert.Equal(t, 6, sub.Line)
+	assert.Equal(t, "assertion failed: expected 1, got 2", sub.Failure.Message)
+	assert.Equal(t, "subtracting\n", sub.SystemOut)
+	assert.Nil(t, suites.Suites[1].Cases[0].Failure)
+	assert.Contains(t, b.String(), `<testcase name="test_add" classname="math_test.mk" file="math_test.mk" line="3" time="0.000"></testcase>`)
+}
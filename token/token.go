@@ -5,6 +5,8 @@ type Type string
 type Token struct {
 	Type    Type
 	Literal string
+	Line    int // 1-based line of the first character of the token
+	Column  int // 1-based column of the first character of the token
 }
 
 const (
@@ -12,6 +14,8 @@ const (
 	ILLEGAL = "ILLEGAL"
 	// EOF End of file - final, ending token
 	EOF = "EOF"
+	// COMMENT Line comment starting with // - skipped by the parser
+	COMMENT = "COMMENT"
 
 	/*
 		Identifiers and Literals
@@ -47,6 +51,8 @@ const (
 	LT       = "<"
 	GT       = ">"
 	COLON    = ":"
+	DOT      = "."
+	ARROW    = "->"
 
 	/*
 		KEYWORDS
@@ -59,6 +65,9 @@ const (
 	TRUE     = "TRUE"
 	FALSE    = "FALSE"
 	STRING   = "STRING"
+	MACRO    = "MACRO"
+	IMPORT   = "IMPORT"
+	AS       = "AS"
 
 	/*
 		LOGIC OPS
@@ -0,0 +1,103 @@
+// Package trace implements evaluator tracers. Chrome writes the calls of user functions and
+// builtins in the Trace Event Format, which chrome://tracing, Perfetto and speedscope display as a
+// timeline of nested calls.
+package trace
+
+import (
+	"encoding/json"
+	"io"
+	"monkey_interpreter/ast"
+	"monkey_interpreter/object"
+	"time"
+)
+
+// Chrome streams a duration event per function call to a writer. The events are only a valid
+// document once the tracer is closed
+type Chrome struct {
+	w     io.Writer
+	start time.Time
+	now   func() time.Time
+	count int   // events written
+	err   error // the first write error, later events are dropped
+}
+
+// chromeEvent is a begin (B) or end (E) event, timestamps are in microseconds
+type chromeEvent struct {
+	Name      string  `json:"name"`
+	Category  string  `json:"cat"`
+	Phase     string  `json:"ph"`
+	Timestamp float64 `json:"ts"`
+	PID       int     `json:"pid"`
+	TID       int     `json:"tid"`
+}
+
+// Event categories
+const (
+	categoryFunction = "function"
+	categoryBuiltin  = "builtin"
+)
+
+func NewChrome(w io.Writer) *Chrome {
+	return &Chrome{w: w, start: time.Now(), now: time.Now}
+}
+
+// Enter does nothing, the trace only has function calls
+func (t *Chrome) Enter(ast.Node) {}
+
+// Exit does nothing, the trace only has function calls
+func (t *Chrome) Exit(ast.Node, object.Object) {}
+
+func (t *Chrome) Call(name string, _ *object.Function, _ []object.Object) {
+	t.write(name, categoryFunction, "B")
+}
+
+func (t *Chrome) Return(name string, _ *object.Function, _ object.Object) {
+	t.write(name, categoryFunction, "E")
+}
+
+func (t *Chrome) BuiltinCall(name string, _ []object.Object) {
+	t.write(name, categoryBuiltin, "B")
+}
+
+func (t *Chrome) BuiltinReturn(name string, _ object.Object) {
+	t.write(name, categoryBuiltin, "E")
+}
+
+func (t *Chrome) write(name, category, phase string) {
+	if t.err != nil {
+		return
+	}
+	elapsed := t.now().Sub(t.start)
+	event, err := json.Marshal(chromeEvent{
+		Name:      name,
+		Category:  category,
+		Phase:     phase,
+		Timestamp: float64(elapsed.Nanoseconds()) / 1e3,
+		PID:       1,
+		TID:       1,
+	})
+	if err != nil {
+		t.err = err
+		return
+	}
+
+	prefix := ",\n"
+	if t.count == 0 {
+		prefix = `{"traceEvents":[` + "\n"
+	}
+	t.count++
+	_, t.err = t.w.Write(append([]byte(prefix), event...))
+}
+
+// Close ends the document and returns the first error writing the trace
+func (t *Chrome) Close() error {
+	if t.err != nil {
+		return t.err
+	}
+	end := "\n]}\n"
+	if t.count == 0 {
+		end = `{"traceEvents":[]}` + "\n"
+	}
+	_, err := io.WriteString(t.w, end)
+	return err
+}
@@ -0,0 +1,63 @@
+package trace
+
+import (
+	"bytes"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"monkey_interpreter/evaluator"
+	"monkey_interpreter/lexer"
+	"monkey_interpreter/object"
+	"monkey_interpreter/parser"
+	"strings"
+	"testing"
+	"time"
+)
+
+// tick returns a clock advancing by a millisecond on every reading
+func tick(start time.Time) func() time.Time {
+	now := start
+	return func() time.Time {
+		now = now.Add(time.Millisecond)
+		return now
+	}
+}
+
+func TestChrome(t *testing.T) {
+	var out bytes.Buffer
+	tracer := NewChrome(&out)
+	tracer.now = tick(tracer.start)
+
+	ctx := evaluator.NewContext(strings.NewReader(""), &bytes.Buffer{}, &bytes.Buffer{})
+	ctx.Tracer = tracer
+	input := `let double = fn(x) { x * 2 }; puts(double(len("ab")));`
+	ctx.Eval(parser.New(lexer.New(input)).ParseProgram(), object.NewEnvironment())
+	assert.NoError(t, tracer.Close())
+
+	assert.JSONEq(t, `{"traceEvents": [
+		{"name": "len", "cat": "builtin", "ph": "B", "ts": 1000, "pid": 1, "tid": 1},
+		{"name": "len", "cat": "builtin", "ph": "E", "ts": 2000, "pid": 1, "tid": 1},
+		{"name": "double", "cat": "function", "ph": "B", "ts": 3000, "pid": 1, "tid": 1},
+		{"name": "double", "cat": "function", "ph": "E", "ts": 4000, "pid": 1, "tid": 1},
+		{"name": "puts", "cat": "builtin", "ph": "B", "ts": 5000, "pid": 1, "tid": 1},
+		{"name": "puts", "cat": "builtin", "ph": "E", "ts": 6000, "pid": 1, "tid": 1}
+	]}`, out.String())
+}
+
+func TestChromeEmpty(t *testing.T) {
+	var out bytes.Buffer
+	assert.NoError(t, NewChrome(&out).Close())
+	assert.JSONEq(t, `{"traceEvents": []}`, out.String())
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestChromeWriteError(t *testing.T) {
+	tracer := NewChrome(failingWriter{})
+	tracer.Call("f", nil, nil)
+	tracer.Return("f", nil, nil)
+	assert.EqualError(t, tracer.Close(), "disk full")
+}
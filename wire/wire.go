@@ -0,0 +1,48 @@
+// Package wire reads and writes the messages of the language server and debug adapter protocols,
+// JSON bodies framed by a Content-Length header
+package wire
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+)
+
+// MaxMessageLength bounds the body of a message, which is read whole into memory
+const MaxMessageLength = 64 << 20
+
+// ReadMessage reads a message framed by a Content-Length header
+func ReadMessage(r *bufio.Reader) ([]byte, error) {
+	header, err := textproto.NewReader(r).ReadMIMEHeader()
+	if err != nil {
+		return nil, err
+	}
+	length, err := strconv.Atoi(header.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid Content-Length header %q", header.Get("Content-Length"))
+	}
+	if length < 0 || length > MaxMessageLength {
+		return nil, fmt.Errorf("invalid Content-Length %d, must be between 0 and %d", length, MaxMessageLength)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// WriteMessage writes msg encoded as JSON with its Content-Length header
+func WriteMessage(w io.Writer, msg interface{}) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(w, "Content-Length: %d\r\n\r\n", len(body)); err != nil {
+		return err
+	}
+	_, err = w.Write(body)
+	return err
+}
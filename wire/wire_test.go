@@ -0,0 +1,39 @@
+package wire
+
+import (
+	"bufio"
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestReadMessageLength(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"Content-Length: 2\r\n\r\n{}", ""},
+		{"Content-Length: x\r\n\r\n{}", `invalid Content-Length header "x"`},
+		{"Content-Length: -1\r\n\r\n{}", "invalid Content-Length -1, must be between 0 and 67108864"},
+		{"Content-Length: 9223372036854775807\r\n\r\n{}", "invalid Content-Length 9223372036854775807, must be between 0 and 67108864"},
+	}
+
+	for _, tt := range tests {
+		_, err := ReadMessage(bufio.NewReader(bytes.NewBufferString(tt.input)))
+		if tt.expected == "" {
+			assert.NoError(t, err)
+		} else {
+			assert.EqualError(t, err, tt.expected)
+		}
+	}
+}
+
+func TestWriteMessage(t *testing.T) {
+	var b bytes.Buffer
+	assert.NoError(t, WriteMessage(&b, map[string]int{"a": 1}))
+	assert.Equal(t, "Content-Length: 7\r\n\r\n{\"a\":1}", b.String())
+
+	body, err := ReadMessage(bufio.NewReader(&b))
+	assert.NoError(t, err)
+	assert.Equal(t, `{"a":1}`, string(body))
+}